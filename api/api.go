@@ -0,0 +1,11 @@
+// Package api holds the hello-api interface definitions: the OpenAPI
+// document for the HTTP API, embedded here, and the gRPC service in hello/v1.
+package api
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3.1 document describing the HTTP API, served at
+// /openapi.json.
+//
+//go:embed openapi.json
+var OpenAPI []byte
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"hello-api/internal/logging"
+	"hello-api/internal/metrics"
+	"hello-api/internal/openapi"
+	"hello-api/internal/render"
+	"hello-api/internal/requestid"
+	"hello-api/internal/validation"
+)
+
+// ndjsonType is the media type of newline-delimited JSON batches.
+const ndjsonType = "application/x-ndjson"
+
+// batchFlushEvery is how many results are written between flushes, so large
+// batches reach the client while later items are still being processed.
+const batchFlushEvery = 100
+
+// BatchResult is the outcome of one item of a POST /hello/batch request.
+// Index is the item's zero-based position in the request. Exactly one of
+// Message and Error is set.
+type BatchResult struct {
+	Index   int            `json:"index"`
+	Message string         `json:"message,omitempty"`
+	Locale  string         `json:"locale,omitempty"`
+	Error   *ErrorResponse `json:"error,omitempty"`
+}
+
+// BatchResponse is the JSON body of POST /hello/batch. It is streamed one
+// result at a time; NDJSON responses send the results alone, one per line.
+type BatchResponse struct {
+	Results   []BatchResult `json:"results"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+}
+
+// batchItem is a decoded request item, or the error that prevented decoding
+// it.
+type batchItem struct {
+	req Request
+	err *ErrorResponse
+}
+
+// batchMetrics record the size and outcome of POST /hello/batch requests.
+type batchMetrics struct {
+	size  *metrics.HistogramVec
+	items *metrics.CounterVec
+}
+
+func newBatchMetrics(reg *metrics.Registry) *batchMetrics {
+	return &batchMetrics{
+		size: metrics.NewHistogramVec(reg, "hello_batch_size",
+			"Number of items in POST /hello/batch requests.", []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000}),
+		items: metrics.NewCounterVec(reg, "hello_batch_items_total",
+			"Total number of batch items processed, by result.", "result"),
+	}
+}
+
+// helloBatchHandler greets every item of a JSON array or NDJSON stream of
+// Request objects. Items that fail to decode or validate are reported in
+// place without failing the rest of the batch.
+func (a *app) helloBatchHandler(w http.ResponseWriter, r *http.Request) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+	ndjsonInput := mediaType == ndjsonType || mediaType == "application/ndjson"
+	if mediaType != "application/json" && !ndjsonInput {
+		respondWithError(w, r, http.StatusUnsupportedMediaType,
+			"Content-Type must be application/json or application/x-ndjson", "INVALID_CONTENT_TYPE")
+		return
+	}
+	if charset, set := params["charset"]; set && !strings.EqualFold(charset, "utf-8") {
+		respondWithError(w, r, http.StatusUnsupportedMediaType, "JSON bodies must be UTF-8 encoded", "INVALID_CONTENT_TYPE")
+		return
+	}
+
+	if !decodeRequestBody(w, r, a.cfg.Hello.Batch.MaxBodyBytes) {
+		return
+	}
+
+	var items []batchItem
+	if ndjsonInput {
+		items, err = readNDJSONBatch(r.Body, a.cfg.Hello.Batch.MaxItems)
+	} else {
+		items, err = readJSONBatch(r.Body, a.cfg.Hello.Batch.MaxItems)
+	}
+	if err != nil {
+		switch {
+		case respondBodyTooLarge(w, r, err):
+		case errors.Is(err, errBatchNotArray):
+			respondWithErrorDetails(w, r, http.StatusBadRequest, "Invalid request", "VALIDATION_FAILED",
+				validation.Errors{{Field: "body", Code: openapi.CodeInvalidType, Message: "must be an array"}})
+		case errors.Is(err, errBatchTooLarge):
+			respondWithError(w, r, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("Batch exceeds %d items", a.cfg.Hello.Batch.MaxItems), "BATCH_TOO_LARGE")
+		default:
+			respondWithError(w, r, http.StatusBadRequest, "Request body must be a JSON array of requests", "INVALID_JSON")
+		}
+		return
+	}
+	if len(items) == 0 {
+		respondWithError(w, r, http.StatusBadRequest, "Batch must contain at least one item", "EMPTY_BATCH")
+		return
+	}
+	a.batch.size.Observe(float64(len(items)))
+
+	var out batchWriter = &jsonBatchWriter{w: w}
+	if wantsNDJSON(r.Header.Get("Accept"), ndjsonInput) {
+		out = &ndjsonBatchWriter{enc: json.NewEncoder(w)}
+	}
+
+	w.Header().Set("Content-Type", out.contentType())
+	w.Header().Add("Vary", "Accept, Accept-Language")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	var succeeded, failed int
+	for i, item := range items {
+		result := BatchResult{Index: i, Error: item.err}
+		if result.Error == nil {
+			resp, err := a.greet(r.Context(), r.Header.Get("Accept-Language"), []string{item.req.Name}, item.req.Lang, item.req.Formality)
+			if err != nil {
+				var fieldErrs validation.Errors
+				errors.As(err, &fieldErrs)
+				result.Error = &ErrorResponse{Error: "Invalid name", Code: "VALIDATION_FAILED", Details: fieldErrs}
+			} else {
+				result.Message, result.Locale = resp.Message, resp.Locale
+			}
+		}
+
+		if result.Error != nil {
+			// Like every other error body, each item's error carries the
+			// request ID, so a failed item can be found in the logs.
+			result.Error.RequestID = requestid.FromContext(r.Context())
+			failed++
+		} else {
+			succeeded++
+		}
+
+		if err := out.write(result); err != nil {
+			// The client has gone away; nothing more can be delivered.
+			logging.FromContext(r.Context()).Error("failed to write batch result", slog.Any("error", err))
+			break
+		}
+		if (i+1)%batchFlushEvery == 0 {
+			rc.Flush()
+		}
+	}
+	out.close(succeeded, failed)
+
+	a.batch.items.Add(float64(succeeded), "succeeded")
+	a.batch.items.Add(float64(failed), "failed")
+}
+
+var (
+	// errBatchTooLarge is returned when a batch has more items than allowed.
+	errBatchTooLarge = errors.New("batch too large")
+	// errBatchNotArray is returned when a JSON batch is not an array.
+	errBatchNotArray = errors.New("batch is not a JSON array")
+)
+
+// readJSONBatch decodes a JSON array of requests. A malformed array fails
+// the whole batch; an element that is not a valid Request fails only that
+// item.
+func readJSONBatch(body io.Reader, maxItems int) ([]batchItem, error) {
+	dec := json.NewDecoder(body)
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('[') {
+		return nil, errBatchNotArray
+	}
+
+	var items []batchItem
+	for dec.More() {
+		if len(items) == maxItems {
+			return nil, errBatchTooLarge
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		items = append(items, decodeBatchItem(raw))
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after JSON array")
+	}
+	return items, nil
+}
+
+// readNDJSONBatch decodes one request per line, skipping blank lines. A line
+// that is not a valid Request fails only that item.
+func readNDJSONBatch(body io.Reader, maxItems int) ([]batchItem, error) {
+	br := bufio.NewReader(body)
+	var items []batchItem
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			if len(items) == maxItems {
+				return nil, errBatchTooLarge
+			}
+			items = append(items, decodeBatchItem(line))
+		}
+		if err == io.EOF {
+			return items, nil
+		}
+	}
+}
+
+// batchItemType validates batch items against the document's Request
+// schema, as validationMiddleware does for POST /hello bodies.
+var batchItemType = &openapi.MediaType{Schema: spec.Components.Schemas["Request"]}
+
+// decodeBatchItem validates and decodes one item as it is read, so only the
+// item, not the whole batch, is held twice.
+func decodeBatchItem(data []byte) batchItem {
+	if errs, err := batchItemType.ValidateJSON("item", data); err != nil {
+		return batchItem{err: &ErrorResponse{Error: "Invalid JSON", Code: "INVALID_JSON"}}
+	} else if len(errs) > 0 {
+		return batchItem{err: &ErrorResponse{Error: "Invalid request", Code: "VALIDATION_FAILED", Details: errs}}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var req Request
+	if err := dec.Decode(&req); err != nil || dec.More() {
+		return batchItem{err: &ErrorResponse{Error: "Invalid JSON", Code: "INVALID_JSON"}}
+	}
+	return batchItem{req: req}
+}
+
+// wantsNDJSON reports whether results should be streamed as NDJSON: when
+// the client asks for it, or when it sent NDJSON without asking for JSON.
+func wantsNDJSON(accept string, ndjsonInput bool) bool {
+	if render.Mentions(accept, ndjsonType) || render.Mentions(accept, "application/ndjson") {
+		return true
+	}
+	return ndjsonInput && !render.Mentions(accept, "application/json")
+}
+
+// batchWriter streams batch results in one response format.
+type batchWriter interface {
+	contentType() string
+	write(result BatchResult) error
+	close(succeeded, failed int)
+}
+
+// jsonBatchWriter writes a BatchResponse object, one result at a time.
+type jsonBatchWriter struct {
+	w       io.Writer
+	written int
+}
+
+func (jw *jsonBatchWriter) contentType() string { return "application/json" }
+
+func (jw *jsonBatchWriter) write(result BatchResult) error {
+	b, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	prefix := ","
+	if jw.written == 0 {
+		prefix = `{"results":[`
+	}
+	if _, err := io.WriteString(jw.w, prefix); err != nil {
+		return err
+	}
+	jw.written++
+	_, err = jw.w.Write(b)
+	return err
+}
+
+func (jw *jsonBatchWriter) close(succeeded, failed int) {
+	if jw.written == 0 {
+		io.WriteString(jw.w, `{"results":[`)
+	}
+	fmt.Fprintf(jw.w, `],"succeeded":%d,"failed":%d}`+"\n", succeeded, failed)
+}
+
+// ndjsonBatchWriter writes one result per line.
+type ndjsonBatchWriter struct {
+	enc *json.Encoder
+}
+
+func (nw *ndjsonBatchWriter) contentType() string { return ndjsonType }
+
+func (nw *ndjsonBatchWriter) write(result BatchResult) error { return nw.enc.Encode(result) }
+
+func (nw *ndjsonBatchWriter) close(int, int) {}
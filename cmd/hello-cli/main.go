@@ -0,0 +1,347 @@
+// Command hello-cli calls the hello-api HTTP API from the command line:
+//
+//	hello-cli [flags] hello [-lang code] [-formality level] [-f file]... [name...]
+//	hello-cli [flags] health
+//	hello-cli [flags] ping
+//	hello-cli [flags] info
+//
+// The hello command greets each name given as an argument or read from the
+// files named by -f, one per line ("-" reads standard input). Results are
+// printed as JSON lines, an aligned table or tab-separated raw values.
+//
+// hello-cli exits with status 1 when any call fails, for example with an
+// error response from the API, and 2 when it is used incorrectly.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"hello-api/pkg/client"
+)
+
+// Exit statuses.
+const (
+	exitOK     = 0
+	exitFailed = 1
+	exitUsage  = 2
+)
+
+const (
+	programName = "hello-cli"
+	defaultURL  = "http://localhost:8080"
+)
+
+const usage = `Usage: hello-cli [flags] <command> [arguments]
+
+Commands:
+  hello   greet names given as arguments or read with -f
+  health  check the server's health
+  ping    ping the server
+  info    show the request as the server received it
+
+Flags:
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.LookupEnv, os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// cli holds what a command needs to call the API and print its results.
+type cli struct {
+	client *client.Client
+	out    *printer
+	stdin  io.Reader
+	stderr io.Writer
+}
+
+// run parses args and runs the command they name, returning the exit
+// status. The base URL and credentials default to the HELLO_API_URL,
+// HELLO_API_KEY and HELLO_API_TOKEN environment variables.
+func run(ctx context.Context, args []string, lookupEnv func(string) (string, bool), stdin io.Reader, stdout, stderr io.Writer) int {
+	env := func(key, fallback string) string {
+		if v, ok := lookupEnv(key); ok {
+			return v
+		}
+		return fallback
+	}
+
+	fs := flag.NewFlagSet(programName, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	baseURL := fs.String("url", env("HELLO_API_URL", defaultURL), "base URL of the API (env HELLO_API_URL)")
+	output := fs.String("o", formatTable, "output format: json, table or raw")
+	timeout := fs.Duration("timeout", client.DefaultTimeout, "timeout of each attempt")
+	retries := fs.Int("retries", client.DefaultRetries, "retries of calls that fail transiently")
+	apiKey := fs.String("api-key", env("HELLO_API_KEY", ""), "API key to authenticate with (env HELLO_API_KEY)")
+	token := fs.String("token", env("HELLO_API_TOKEN", ""), "bearer token to authenticate with (env HELLO_API_TOKEN)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	out, err := newPrinter(stdout, *output)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+
+	opts := []client.Option{
+		client.WithTimeout(*timeout),
+		client.WithRetries(*retries),
+		client.WithUserAgent(programName),
+	}
+	if *apiKey != "" {
+		opts = append(opts, client.WithAPIKey(*apiKey))
+	}
+	if *token != "" {
+		opts = append(opts, client.WithBearerToken(*token))
+	}
+	c, err := client.New(*baseURL, opts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitUsage
+	}
+
+	app := &cli{client: c, out: out, stdin: stdin, stderr: stderr}
+	commands := map[string]func(ctx context.Context, args []string) int{
+		"hello":  app.hello,
+		"health": app.health,
+		"ping":   app.ping,
+		"info":   app.info,
+	}
+	name, cmdArgs := fs.Arg(0), fs.Args()[1:]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "%s: unknown command %q\n", programName, name)
+		fs.Usage()
+		return exitUsage
+	}
+
+	code := cmd(ctx, cmdArgs)
+	if err := out.Flush(); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", programName, err)
+		return exitFailed
+	}
+	return code
+}
+
+// stringList is a flag that may be repeated.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// hello greets each name and keeps going when a call fails, so one bad name
+// in a bulk run does not hide the results of the others.
+func (app *cli) hello(ctx context.Context, args []string) int {
+	fs := flag.NewFlagSet(programName+" hello", flag.ContinueOnError)
+	fs.SetOutput(app.stderr)
+	lang := fs.String("lang", "", "language of the greetings, such as fr")
+	formality := fs.String("formality", "", "formal or informal")
+	var files stringList
+	fs.Var(&files, "f", "read names from a file, one per line; - reads standard input (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	names := fs.Args()
+	for _, path := range files {
+		read, err := app.readNames(path)
+		if err != nil {
+			fmt.Fprintf(app.stderr, "%s: %v\n", programName, err)
+			return exitUsage
+		}
+		names = append(names, read...)
+	}
+	if len(names) == 0 && len(files) == 0 {
+		// No names greets the server's default.
+		names = []string{""}
+	}
+
+	app.out.Header("NAME", "MESSAGE", "LOCALE")
+	code := exitOK
+	for _, name := range names {
+		var resp *client.Response
+		var err error
+		if *lang != "" || *formality != "" {
+			resp, err = app.client.HelloPOST(ctx, client.Request{Name: name, Lang: *lang, Formality: *formality})
+		} else {
+			resp, err = app.client.Hello(ctx, name)
+		}
+		if err != nil {
+			app.reportError(name, err)
+			code = exitFailed
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		app.out.Row(greeting{Name: name, Message: resp.Message, Locale: resp.Locale}, name, resp.Message, resp.Locale)
+	}
+	return code
+}
+
+// greeting is a hello result as printed in JSON.
+type greeting struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	Locale  string `json:"locale,omitempty"`
+}
+
+// readNames reads one name per line, skipping blank lines and lines
+// starting with #.
+func (app *cli) readNames(path string) ([]string, error) {
+	r := app.stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return names, nil
+}
+
+func (app *cli) health(ctx context.Context, args []string) int {
+	if !app.noArgs("health", args) {
+		return exitUsage
+	}
+	resp, err := app.client.Health(ctx)
+	if err != nil {
+		app.reportError("", err)
+		return exitFailed
+	}
+	app.out.Header("STATUS")
+	app.out.Row(resp, resp.Status)
+	return exitOK
+}
+
+func (app *cli) ping(ctx context.Context, args []string) int {
+	if !app.noArgs("ping", args) {
+		return exitUsage
+	}
+	resp, err := app.client.Ping(ctx)
+	if err != nil {
+		app.reportError("", err)
+		return exitFailed
+	}
+	app.out.Header("PONG")
+	app.out.Row(resp, resp.Pong)
+	return exitOK
+}
+
+// info prints one row per field, with headers and query parameters named
+// like "header.Accept" and "query.name", in table and raw output.
+func (app *cli) info(ctx context.Context, args []string) int {
+	if !app.noArgs("info", args) {
+		return exitUsage
+	}
+	resp, err := app.client.Info(ctx)
+	if err != nil {
+		app.reportError("", err)
+		return exitFailed
+	}
+	app.out.Object(resp, infoRows(resp))
+	return exitOK
+}
+
+func infoRows(resp *client.InfoResponse) [][]string {
+	rows := [][]string{
+		{"method", resp.Method},
+		{"url", resp.URL},
+		{"host", resp.Host},
+		{"remote_addr", resp.RemoteAddr},
+		{"user_agent", resp.UserAgent},
+	}
+	for _, key := range sortedKeys(resp.Headers) {
+		rows = append(rows, []string{"header." + key, resp.Headers[key]})
+	}
+	for _, key := range sortedKeys(resp.QueryParams) {
+		rows = append(rows, []string{"query." + key, resp.QueryParams[key]})
+	}
+	return rows
+}
+
+func (app *cli) noArgs(command string, args []string) bool {
+	if len(args) > 0 {
+		fmt.Fprintf(app.stderr, "%s: %s takes no arguments\n", programName, command)
+		return false
+	}
+	return true
+}
+
+// reportError prints a failed call to standard error. API errors include
+// their code, request ID and field details so they can be traced in the
+// server's logs.
+func (app *cli) reportError(name string, err error) {
+	prefix := programName + ": "
+	if name != "" {
+		prefix += fmt.Sprintf("%q: ", name)
+	}
+
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		fmt.Fprintf(app.stderr, "%s%v\n", prefix, err)
+		return
+	}
+
+	msg := fmt.Sprintf("%s%d", prefix, apiErr.StatusCode)
+	if apiErr.Code != "" {
+		msg += " " + apiErr.Code
+	}
+	if apiErr.Message != "" {
+		msg += ": " + apiErr.Message
+	}
+	if apiErr.RequestID != "" {
+		msg += " (request_id " + apiErr.RequestID + ")"
+	}
+	if apiErr.RetryAfter > 0 {
+		msg += fmt.Sprintf(" (retry after %s)", apiErr.RetryAfter.Round(time.Second))
+	}
+	fmt.Fprintln(app.stderr, msg)
+	for _, d := range apiErr.Details {
+		fmt.Fprintf(app.stderr, "  %s: %s (%s)\n", d.Field, d.Message, d.Code)
+	}
+}
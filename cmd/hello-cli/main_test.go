@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeAPI answers like hello-api: it greets names, rejecting "<b>" and
+// names sent without the API key "secret" when requireKey is set.
+func fakeAPI(requireKey bool) http.Handler {
+	mux := http.NewServeMux()
+	writeJSON := func(w http.ResponseWriter, status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		if requireKey && r.Header.Get("X-API-Key") != "secret" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid API key", "code": "INVALID_API_KEY", "request_id": "req-1"})
+			return
+		}
+		name, lang := r.URL.Query().Get("name"), "en"
+		if r.Method == http.MethodPost {
+			var body struct{ Name, Lang string }
+			json.NewDecoder(r.Body).Decode(&body)
+			name, lang = body.Name, body.Lang
+		}
+		if name == "<b>" {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error":      "Invalid name",
+				"code":       "VALIDATION_FAILED",
+				"request_id": "req-2",
+				"details":    []map[string]string{{"field": "name", "code": "invalid_characters", "message": "contains disallowed character '<'"}},
+			})
+			return
+		}
+		if name == "" {
+			name = "World"
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"message": "Hello, " + name + "!", "locale": lang})
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
+	})
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"pong": "pong"})
+	})
+	mux.HandleFunc("/info", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"method":       r.Method,
+			"url":          r.URL.String(),
+			"host":         r.Host,
+			"user_agent":   r.UserAgent(),
+			"headers":      map[string]string{"Accept": r.Header.Get("Accept")},
+			"query_params": map[string]string{},
+		})
+	})
+	return mux
+}
+
+func TestRun(t *testing.T) {
+	srv := httptest.NewServer(fakeAPI(false))
+	defer srv.Close()
+
+	names := filepath.Join(t.TempDir(), "names.txt")
+	if err := os.WriteFile(names, []byte("Ada\n\n# skipped\n  Bo  \n"), 0o600); err != nil {
+		t.Fatalf("failed to write names: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		args           []string
+		stdin          string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string
+	}{
+		{
+			name:           "hello table",
+			args:           []string{"hello", "Ada"},
+			expectedCode:   exitOK,
+			expectedStdout: "NAME  MESSAGE      LOCALE\nAda   Hello, Ada!  en\n",
+		},
+		{
+			name:           "hello default name",
+			args:           []string{"-o", "raw", "hello"},
+			expectedCode:   exitOK,
+			expectedStdout: "\tHello, World!\ten\n",
+		},
+		{
+			name:           "hello POST with language",
+			args:           []string{"-o", "json", "hello", "-lang", "fr", "Ada"},
+			expectedCode:   exitOK,
+			expectedStdout: `{"name":"Ada","message":"Hello, Ada!","locale":"fr"}` + "\n",
+		},
+		{
+			name:         "bulk from file and stdin",
+			args:         []string{"-o", "json", "hello", "-f", names, "-f", "-", "Cy"},
+			stdin:        "Di\n",
+			expectedCode: exitOK,
+			expectedStdout: `{"name":"Cy","message":"Hello, Cy!","locale":"en"}` + "\n" +
+				`{"name":"Ada","message":"Hello, Ada!","locale":"en"}` + "\n" +
+				`{"name":"Bo","message":"Hello, Bo!","locale":"en"}` + "\n" +
+				`{"name":"Di","message":"Hello, Di!","locale":"en"}` + "\n",
+		},
+		{
+			name:           "empty bulk input",
+			args:           []string{"-o", "raw", "hello", "-f", "-"},
+			expectedCode:   exitOK,
+			expectedStdout: "",
+		},
+		{
+			name:           "bulk continues past errors",
+			args:           []string{"-o", "raw", "hello", "-f", "-"},
+			stdin:          "Ada\n<b>\nBo\n",
+			expectedCode:   exitFailed,
+			expectedStdout: "Ada\tHello, Ada!\ten\nBo\tHello, Bo!\ten\n",
+			expectedStderr: `hello-cli: "<b>": 400 VALIDATION_FAILED: Invalid name (request_id req-2)` + "\n" +
+				"  name: contains disallowed character '<' (invalid_characters)\n",
+		},
+		{
+			name:           "missing names file",
+			args:           []string{"hello", "-f", filepath.Join(t.TempDir(), "missing.txt")},
+			expectedCode:   exitUsage,
+			expectedStderr: "no such file or directory",
+		},
+		{
+			name:           "health",
+			args:           []string{"health"},
+			expectedCode:   exitOK,
+			expectedStdout: "STATUS\nhealthy\n",
+		},
+		{
+			name:           "ping raw",
+			args:           []string{"-o", "raw", "ping"},
+			expectedCode:   exitOK,
+			expectedStdout: "pong\n",
+		},
+		{
+			name:           "info raw",
+			args:           []string{"-o", "raw", "info"},
+			expectedCode:   exitOK,
+			expectedStdout: "method\tGET\nurl\t/info\nhost\t" + strings.TrimPrefix(srv.URL, "http://") + "\nremote_addr\t\nuser_agent\thello-cli\nheader.Accept\tapplication/json\n",
+		},
+		{
+			name:           "info json",
+			args:           []string{"-o", "json", "info"},
+			expectedCode:   exitOK,
+			expectedStdout: `{"method":"GET","url":"/info","host":"` + strings.TrimPrefix(srv.URL, "http://") + `","remote_addr":"","user_agent":"hello-cli","headers":{"Accept":"application/json"},"query_params":{}}` + "\n",
+		},
+		{
+			name:           "no command",
+			args:           nil,
+			expectedCode:   exitUsage,
+			expectedStderr: "Usage: hello-cli",
+		},
+		{
+			name:           "unknown command",
+			args:           []string{"greet"},
+			expectedCode:   exitUsage,
+			expectedStderr: `unknown command "greet"`,
+		},
+		{
+			name:           "unknown format",
+			args:           []string{"-o", "yaml", "ping"},
+			expectedCode:   exitUsage,
+			expectedStderr: `unknown output format "yaml"`,
+		},
+		{
+			name:           "unexpected arguments",
+			args:           []string{"ping", "extra"},
+			expectedCode:   exitUsage,
+			expectedStderr: "ping takes no arguments",
+		},
+		{
+			name:           "invalid URL",
+			args:           []string{"-url", "localhost:8080", "ping"},
+			expectedCode:   exitUsage,
+			expectedStderr: "invalid base URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := func(key string) (string, bool) {
+				if key == "HELLO_API_URL" {
+					return srv.URL, true
+				}
+				return "", false
+			}
+			var stdout, stderr bytes.Buffer
+			code := run(context.Background(), tt.args, env, strings.NewReader(tt.stdin), &stdout, &stderr)
+
+			if code != tt.expectedCode {
+				t.Errorf("expected exit code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			if stdout.String() != tt.expectedStdout {
+				t.Errorf("expected stdout %q, got %q", tt.expectedStdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tt.expectedStderr) {
+				t.Errorf("expected stderr containing %q, got %q", tt.expectedStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunCredentials(t *testing.T) {
+	srv := httptest.NewServer(fakeAPI(true))
+	defer srv.Close()
+
+	tests := []struct {
+		name           string
+		args           []string
+		env            map[string]string
+		expectedCode   int
+		expectedStderr string
+	}{
+		{
+			name:           "missing key",
+			args:           []string{"-url", srv.URL, "hello", "Ada"},
+			expectedCode:   exitFailed,
+			expectedStderr: `hello-cli: "Ada": 401 INVALID_API_KEY: Invalid API key (request_id req-1)`,
+		},
+		{
+			name:         "key flag",
+			args:         []string{"-url", srv.URL, "-api-key", "secret", "hello", "Ada"},
+			expectedCode: exitOK,
+		},
+		{
+			name:         "key from environment",
+			args:         []string{"hello", "Ada"},
+			env:          map[string]string{"HELLO_API_URL": srv.URL, "HELLO_API_KEY": "secret"},
+			expectedCode: exitOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			}
+			var stdout, stderr bytes.Buffer
+			code := run(context.Background(), tt.args, env, strings.NewReader(""), &stdout, &stderr)
+
+			if code != tt.expectedCode {
+				t.Errorf("expected exit code %d, got %d (stderr: %s)", tt.expectedCode, code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.expectedStderr) {
+				t.Errorf("expected stderr containing %q, got %q", tt.expectedStderr, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats selected with -o.
+const (
+	formatJSON  = "json"
+	formatTable = "table"
+	formatRaw   = "raw"
+)
+
+// printer writes results in one of the output formats. JSON prints each
+// result as a line of JSON; table aligns rows under a header; raw prints
+// rows as tab-separated values without a header, for cut and awk.
+type printer struct {
+	format string
+	enc    *json.Encoder
+	tw     *tabwriter.Writer
+	w      io.Writer
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	p := &printer{format: format, w: w}
+	switch format {
+	case formatJSON:
+		p.enc = json.NewEncoder(w)
+		p.enc.SetEscapeHTML(false)
+	case formatTable:
+		p.tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		p.w = p.tw
+	case formatRaw:
+	default:
+		return nil, fmt.Errorf("unknown output format %q: must be %s, %s or %s", format, formatJSON, formatTable, formatRaw)
+	}
+	return p, nil
+}
+
+// Header names the columns of the rows that follow. Only tables print it.
+func (p *printer) Header(columns ...string) {
+	if p.format == formatTable {
+		p.line(columns)
+	}
+}
+
+// Row prints one result: v in JSON, cells otherwise.
+func (p *printer) Row(v interface{}, cells ...string) {
+	if p.format == formatJSON {
+		p.enc.Encode(v)
+		return
+	}
+	p.line(cells)
+}
+
+// Object prints a single result: v in JSON, otherwise its fields as rows of
+// a name and a value.
+func (p *printer) Object(v interface{}, fields [][]string) {
+	if p.format == formatJSON {
+		p.enc.Encode(v)
+		return
+	}
+	p.Header("FIELD", "VALUE")
+	for _, field := range fields {
+		p.line(field)
+	}
+}
+
+// Flush writes buffered table rows.
+func (p *printer) Flush() error {
+	if p.tw != nil {
+		return p.tw.Flush()
+	}
+	return nil
+}
+
+// line writes cells separated by tabs. Tabs and newlines inside a cell are
+// replaced by spaces so every row stays on one line.
+func (p *printer) line(cells []string) {
+	clean := make([]string, len(cells))
+	for i, cell := range cells {
+		clean[i] = strings.Map(func(r rune) rune {
+			if r == '\t' || r == '\n' || r == '\r' {
+				return ' '
+			}
+			return r
+		}, cell)
+	}
+	fmt.Fprintln(p.w, strings.Join(clean, "\t"))
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
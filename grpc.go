@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	hellov1 "hello-api/api/hello/v1"
+	"hello-api/internal/auth"
+	"hello-api/internal/grpcserver"
+	"hello-api/internal/validation"
+)
+
+// errorDomain identifies hello-api in google.rpc.ErrorInfo details.
+const errorDomain = "hello-api"
+
+// grpcRoutes maps each RPC to the HTTP route it mirrors, so auth.routes
+// protects both.
+var grpcRoutes = map[string]string{
+	hellov1.HelloService_Hello_FullMethodName:  "/hello",
+	hellov1.HelloService_Health_FullMethodName: "/health",
+	hellov1.HelloService_Ping_FullMethodName:   "/ping",
+	hellov1.HelloService_Info_FullMethodName:   "/info",
+}
+
+// newGRPCServer builds the gRPC server with the HelloService, the standard
+// health service and server reflection registered. The returned health
+// server reports SERVING until its Shutdown method is called.
+func (a *app) newGRPCServer() (*grpc.Server, *grpchealth.Server) {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(grpcserver.UnaryRequestID(), grpcserver.UnaryLogging(a.logger), a.grpcUnaryRateLimit, a.grpcAuth),
+		grpc.ChainStreamInterceptor(grpcserver.StreamRequestID(), grpcserver.StreamLogging(a.logger), a.grpcStreamRateLimit),
+	)
+
+	hellov1.RegisterHelloServiceServer(server, &helloService{app: a})
+
+	health := grpchealth.NewServer()
+	health.SetServingStatus(hellov1.HelloService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, health)
+
+	reflection.Register(server)
+	return server, health
+}
+
+// stopGRPC waits for in-flight RPCs to finish, then stops the server. When
+// ctx expires first, remaining RPCs are cancelled and ctx's error returned.
+func stopGRPC(ctx context.Context, server *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return ctx.Err()
+	}
+}
+
+// grpcAuth applies the HTTP authentication rules to the RPCs whose routes are
+// listed in auth.routes. Credentials are read from the x-api-key and
+// authorization metadata.
+func (a *app) grpcAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	route, ok := grpcRoutes[info.FullMethod]
+	if a.auth == nil || !ok || !slices.Contains(a.cfg.Auth.Routes, route) {
+		return handler(ctx, req)
+	}
+
+	// Authenticate reads credentials from HTTP headers.
+	p, authErr := a.auth.Authenticate(grpcRequest(ctx, auth.APIKeyHeader, "Authorization"))
+	if authErr != nil {
+		code := codes.Unauthenticated
+		if authErr.Status == http.StatusForbidden {
+			code = codes.PermissionDenied
+		}
+		return nil, grpcError(code, authErr.Message, authErr.Code, nil)
+	}
+	return handler(auth.WithPrincipal(ctx, p), req)
+}
+
+func (a *app) grpcUnaryRateLimit(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.grpcRateLimit(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *app) grpcStreamRateLimit(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.grpcRateLimit(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// grpcRateLimit applies the HTTP rate limits to the RPCs whose routes are
+// listed in ratelimit.routes. Calls take tokens from the same buckets as
+// HTTP requests to the route, so gRPC is not a way around the limits.
+func (a *app) grpcRateLimit(ctx context.Context, fullMethod string) error {
+	route, ok := grpcRoutes[fullMethod]
+	if a.limiter == nil || !ok || !slices.Contains(a.cfg.RateLimit.Routes, route) {
+		return nil
+	}
+
+	r := grpcRequest(ctx, auth.APIKeyHeader, "X-Forwarded-For")
+	if res := a.limiter.Allow(a.rateLimitKey(route)(r)); !res.Allowed {
+		return grpcError(codes.ResourceExhausted, "Too many requests", "RATE_LIMITED", nil)
+	}
+	return nil
+}
+
+// grpcRequest returns an HTTP request from the peer of ctx carrying the
+// incoming metadata keys as headers, for the HTTP auth and rate limiting.
+func grpcRequest(ctx context.Context, keys ...string) *http.Request {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r := &http.Request{Header: make(http.Header)}
+	for _, key := range keys {
+		for _, v := range md.Get(key) {
+			r.Header.Add(key, v)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+	}
+	return r
+}
+
+// grpcError builds a status carrying errorCode in a google.rpc.ErrorInfo
+// detail and any field errors in a google.rpc.BadRequest detail, the gRPC
+// equivalent of ErrorResponse.
+func grpcError(code codes.Code, message, errorCode string, fieldErrs []validation.FieldError) error {
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: errorCode, Domain: errorDomain}}
+	if len(fieldErrs) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, fe := range fieldErrs {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fe.Field,
+				Description: fe.Message,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	st, err := status.New(code, message).WithDetails(details...)
+	if err != nil {
+		return status.Error(code, message)
+	}
+	return st.Err()
+}
+
+// helloService implements hellov1.HelloServiceServer with the same logic as
+// the HTTP handlers.
+type helloService struct {
+	hellov1.UnimplementedHelloServiceServer
+	app *app
+}
+
+func (s *helloService) Hello(ctx context.Context, req *hellov1.HelloRequest) (*hellov1.HelloResponse, error) {
+	resp, err := s.app.greet(ctx, firstMetadata(ctx, "accept-language"), []string{req.GetName()}, req.GetLang(), req.GetFormality())
+	if err != nil {
+		var fieldErrs validation.Errors
+		errors.As(err, &fieldErrs)
+		return nil, grpcError(codes.InvalidArgument, "Invalid name", "VALIDATION_FAILED", fieldErrs)
+	}
+	return &hellov1.HelloResponse{Message: resp.Message, Locale: resp.Locale}, nil
+}
+
+func (s *helloService) Health(context.Context, *hellov1.HealthRequest) (*hellov1.HealthResponse, error) {
+	return &hellov1.HealthResponse{Status: "healthy"}, nil
+}
+
+func (s *helloService) Ping(context.Context, *hellov1.PingRequest) (*hellov1.PingResponse, error) {
+	return &hellov1.PingResponse{Pong: "pong"}, nil
+}
+
+func (s *helloService) Info(ctx context.Context, _ *hellov1.InfoRequest) (*hellov1.InfoResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := make(map[string]string, len(md))
+	for key, v := range md {
+		if len(v) > 0 {
+			values[key] = redactCredential(key, v[0])
+		}
+	}
+
+	resp := &hellov1.InfoResponse{
+		Authority: firstMetadata(ctx, ":authority"),
+		UserAgent: firstMetadata(ctx, "user-agent"),
+		Metadata:  values,
+	}
+	resp.Method, _ = grpc.Method(ctx)
+	if p, ok := peer.FromContext(ctx); ok {
+		resp.RemoteAddr = p.Addr.String()
+	}
+	return resp, nil
+}
+
+// firstMetadata returns the first value of the incoming metadata key, or "".
+func firstMetadata(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// APIKeys verifies static API keys against SHA-256 hashes, so the raw keys
+// never need to be stored in configuration.
+type APIKeys struct {
+	entries []apiKeyEntry
+}
+
+type apiKeyEntry struct {
+	name string
+	hash [sha256.Size]byte
+}
+
+// HashAPIKey returns the hex-encoded SHA-256 hash to configure for key.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseAPIKeys parses "name:sha256hex" entries.
+func ParseAPIKeys(entries []string) (*APIKeys, error) {
+	keys := &APIKeys{}
+	seen := make(map[string]bool)
+
+	for _, entry := range entries {
+		name, hash, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("API key entry %q must be name:sha256hex", entry)
+		}
+		raw, err := hex.DecodeString(hash)
+		if err != nil || len(raw) != sha256.Size {
+			return nil, fmt.Errorf("API key %q: hash must be 64 hex characters", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("API key %q is defined twice", name)
+		}
+		seen[name] = true
+
+		e := apiKeyEntry{name: name}
+		copy(e.hash[:], raw)
+		keys.entries = append(keys.entries, e)
+	}
+	return keys, nil
+}
+
+// ReadAPIKeysFile reads API key entries from path, one per line. Blank
+// lines and lines starting with # are ignored.
+func ReadAPIKeysFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, scanner.Err()
+}
+
+// Len returns the number of configured keys.
+func (k *APIKeys) Len() int { return len(k.entries) }
+
+// Verify returns the principal for key. Every entry is compared in constant
+// time so the response time does not reveal which keys exist.
+func (k *APIKeys) Verify(key string) (*Principal, bool) {
+	sum := sha256.Sum256([]byte(key))
+
+	var match string
+	for _, e := range k.entries {
+		if subtle.ConstantTimeCompare(sum[:], e.hash[:]) == 1 {
+			match = e.name
+		}
+	}
+	if match == "" {
+		return nil, false
+	}
+	return &Principal{Subject: match, Method: MethodAPIKey}, true
+}
@@ -0,0 +1,137 @@
+// Package auth authenticates requests with static API keys or signed JWT
+// bearer tokens and exposes the authenticated principal in the request
+// context.
+package auth
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// APIKeyHeader carries a static API key.
+const APIKeyHeader = "X-API-Key"
+
+// Authentication methods reported in Principal.Method.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal is the authenticated caller.
+type Principal struct {
+	// Subject uniquely identifies the caller: the API key name or the JWT
+	// "sub" claim.
+	Subject string
+	// Name is a display name, from the JWT "name" claim when present.
+	Name   string
+	Method string
+	Scopes []string
+}
+
+// DisplayName returns Name, falling back to Subject.
+func (p *Principal) DisplayName() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.Subject
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the authenticated principal, or nil for anonymous
+// requests.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(contextKey{}).(*Principal)
+	return p
+}
+
+// Error describes why a request was not authenticated or authorized.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Authentication and authorization failures.
+var (
+	ErrMissingCredentials = &Error{Status: http.StatusUnauthorized, Code: "UNAUTHORIZED", Message: "Authentication required"}
+	ErrInvalidAPIKey      = &Error{Status: http.StatusUnauthorized, Code: "INVALID_API_KEY", Message: "Invalid API key"}
+	ErrInvalidToken       = &Error{Status: http.StatusUnauthorized, Code: "INVALID_TOKEN", Message: "Invalid bearer token"}
+	ErrInsufficientScope  = &Error{Status: http.StatusForbidden, Code: "INSUFFICIENT_SCOPE", Message: "Insufficient scope"}
+)
+
+// Authenticator checks API keys and bearer tokens. Either source may be nil
+// to disable it.
+type Authenticator struct {
+	APIKeys *APIKeys
+	JWT     *JWTVerifier
+	// RequiredScope, when set, must be present in a JWT principal's scopes.
+	// API keys are service credentials and are not scope-restricted.
+	RequiredScope string
+}
+
+// Authenticate returns the principal for r or an *Error.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, *Error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" && a.APIKeys != nil {
+		p, ok := a.APIKeys.Verify(key)
+		if !ok {
+			return nil, ErrInvalidAPIKey
+		}
+		return p, nil
+	}
+
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if found && strings.EqualFold(scheme, "Bearer") && a.JWT != nil {
+		p, err := a.JWT.Verify(strings.TrimSpace(token))
+		if err != nil {
+			return nil, ErrInvalidToken
+		}
+		if a.RequiredScope != "" && !p.HasScope(a.RequiredScope) {
+			return nil, ErrInsufficientScope
+		}
+		return p, nil
+	}
+
+	return nil, ErrMissingCredentials
+}
+
+// Middleware rejects unauthenticated requests through onError and passes
+// authenticated ones to next with the principal in the context.
+func Middleware(a *Authenticator, onError func(http.ResponseWriter, *http.Request, *Error), next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, authErr := a.Authenticate(r)
+		if authErr != nil {
+			if a.JWT != nil {
+				w.Header().Set("WWW-Authenticate", challenge(authErr))
+			}
+			onError(w, r, authErr)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
+
+// challenge builds an RFC 6750 WWW-Authenticate header value.
+func challenge(err *Error) string {
+	switch err {
+	case ErrInvalidToken:
+		return `Bearer realm="hello-api", error="invalid_token"`
+	case ErrInsufficientScope:
+		return `Bearer realm="hello-api", error="insufficient_scope"`
+	}
+	return `Bearer realm="hello-api"`
+}
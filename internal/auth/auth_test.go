@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAPIKeys(t *testing.T) {
+	hash := HashAPIKey("secret")
+
+	tests := []struct {
+		name        string
+		entries     []string
+		expectedErr string
+	}{
+		{name: "valid", entries: []string{"ci:" + hash, " ops:" + HashAPIKey("other") + " "}},
+		{name: "missing separator", entries: []string{hash}, expectedErr: "must be name:sha256hex"},
+		{name: "empty name", entries: []string{":" + hash}, expectedErr: "must be name:sha256hex"},
+		{name: "short hash", entries: []string{"ci:abcd"}, expectedErr: "64 hex characters"},
+		{name: "not hex", entries: []string{"ci:" + strings.Repeat("z", 64)}, expectedErr: "64 hex characters"},
+		{name: "duplicate name", entries: []string{"ci:" + hash, "ci:" + hash}, expectedErr: "defined twice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseAPIKeys(tt.entries)
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("expected error containing '%s', got '%v'", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestAPIKeysVerify(t *testing.T) {
+	keys, err := ParseAPIKeys([]string{"ci:" + HashAPIKey("ci-secret"), "ops:" + HashAPIKey("ops-secret")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p, ok := keys.Verify("ops-secret")
+	if !ok {
+		t.Fatal("expected key to verify")
+	}
+	if p.Subject != "ops" || p.Method != MethodAPIKey {
+		t.Errorf("expected ops api_key principal, got %+v", p)
+	}
+
+	if _, ok := keys.Verify("wrong"); ok {
+		t.Error("expected unknown key to be rejected")
+	}
+}
+
+func TestReadAPIKeysFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	content := "# service keys\n\nci:" + HashAPIKey("a") + "\n  ops:" + HashAPIKey("b") + "  \n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write keys file: %v", err)
+	}
+
+	entries, err := ReadAPIKeysFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || !strings.HasPrefix(entries[1], "ops:") {
+		t.Errorf("expected two entries, got %v", entries)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	keys, _ := ParseAPIKeys([]string{"ci:" + HashAPIKey("ci-secret")})
+	verifier, _ := NewJWTVerifier(JWTOptions{HMACSecret: []byte("shh")})
+	a := &Authenticator{APIKeys: keys, JWT: verifier, RequiredScope: "hello:read"}
+
+	exp := time.Now().Add(time.Hour).Unix()
+	scoped := signHS256(t, "shh", map[string]interface{}{"sub": "u1", "name": "Ada", "exp": exp, "scope": "info:read hello:read"})
+	unscoped := signHS256(t, "shh", map[string]interface{}{"sub": "u1", "exp": exp})
+
+	tests := []struct {
+		name             string
+		header           string
+		value            string
+		expectedStatus   int
+		expectedCode     string
+		expectedSubject  string
+		expectedAuthHint string
+	}{
+		{name: "no credentials", expectedStatus: http.StatusUnauthorized, expectedCode: "UNAUTHORIZED", expectedAuthHint: `Bearer realm="hello-api"`},
+		{name: "valid API key", header: APIKeyHeader, value: "ci-secret", expectedStatus: http.StatusOK, expectedSubject: "ci"},
+		{name: "invalid API key", header: APIKeyHeader, value: "nope", expectedStatus: http.StatusUnauthorized, expectedCode: "INVALID_API_KEY"},
+		{name: "valid bearer token", header: "Authorization", value: "Bearer " + scoped, expectedStatus: http.StatusOK, expectedSubject: "u1"},
+		{name: "lowercase scheme", header: "Authorization", value: "bearer " + scoped, expectedStatus: http.StatusOK, expectedSubject: "u1"},
+		{name: "invalid bearer token", header: "Authorization", value: "Bearer x.y.z", expectedStatus: http.StatusUnauthorized, expectedCode: "INVALID_TOKEN", expectedAuthHint: `error="invalid_token"`},
+		{name: "missing scope", header: "Authorization", value: "Bearer " + unscoped, expectedStatus: http.StatusForbidden, expectedCode: "INSUFFICIENT_SCOPE", expectedAuthHint: `error="insufficient_scope"`},
+		{name: "basic auth", header: "Authorization", value: "Basic Y2k6c2VjcmV0", expectedStatus: http.StatusUnauthorized, expectedCode: "UNAUTHORIZED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject, code string
+			h := Middleware(a, func(w http.ResponseWriter, r *http.Request, err *Error) {
+				code = err.Code
+				w.WriteHeader(err.Status)
+			}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				subject = FromContext(r.Context()).Subject
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if code != tt.expectedCode {
+				t.Errorf("expected code '%s', got '%s'", tt.expectedCode, code)
+			}
+			if subject != tt.expectedSubject {
+				t.Errorf("expected subject '%s', got '%s'", tt.expectedSubject, subject)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); !strings.Contains(got, tt.expectedAuthHint) {
+				t.Errorf("expected WWW-Authenticate containing '%s', got '%s'", tt.expectedAuthHint, got)
+			}
+		})
+	}
+}
+
+func TestFromContextAnonymous(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if p := FromContext(req.Context()); p != nil {
+		t.Errorf("expected no principal, got %+v", p)
+	}
+}
@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// JWTVerifier validates compact JWS tokens signed with HS256/384/512 or
+// RS256/384/512 and checks their registered claims.
+type JWTVerifier struct {
+	hmacSecret []byte
+	rsaKey     *rsa.PublicKey
+	issuer     string
+	audience   string
+	leeway     time.Duration
+	now        func() time.Time
+}
+
+// JWTOptions configures a JWTVerifier. At least one of HMACSecret and
+// RSAPublicKey must be set.
+type JWTOptions struct {
+	HMACSecret   []byte
+	RSAPublicKey *rsa.PublicKey
+	// Issuer and Audience are required claim values when non-empty.
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew when checking exp, nbf and iat.
+	Leeway time.Duration
+}
+
+// NewJWTVerifier returns a verifier for opts.
+func NewJWTVerifier(opts JWTOptions) (*JWTVerifier, error) {
+	if len(opts.HMACSecret) == 0 && opts.RSAPublicKey == nil {
+		return nil, errors.New("JWT verification needs an HMAC secret or an RSA public key")
+	}
+	return &JWTVerifier{
+		hmacSecret: opts.HMACSecret,
+		rsaKey:     opts.RSAPublicKey,
+		issuer:     opts.Issuer,
+		audience:   opts.Audience,
+		leeway:     opts.Leeway,
+		now:        time.Now,
+	}, nil
+}
+
+// ReadRSAPublicKey loads a PEM encoded PKIX or PKCS#1 RSA public key.
+func ReadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s: not an RSA public key", path)
+		}
+		return rsaKey, nil
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+type jwtClaims struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *json.Number    `json:"exp"`
+	NotBefore *json.Number    `json:"nbf"`
+	IssuedAt  *json.Number    `json:"iat"`
+	Name      string          `json:"name"`
+	Scope     string          `json:"scope"`
+	Scp       []string        `json:"scp"`
+}
+
+var hashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// Verify checks token's signature and claims and returns its principal.
+func (v *JWTVerifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token must have three parts")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("signature: %w", err)
+	}
+	if err := v.verifySignature(header.Alg, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("claims: %w", err)
+	}
+	if err := v.validateClaims(&claims); err != nil {
+		return nil, err
+	}
+
+	scopes := claims.Scp
+	if claims.Scope != "" {
+		scopes = append(scopes, strings.Fields(claims.Scope)...)
+	}
+	return &Principal{Subject: claims.Subject, Name: claims.Name, Method: MethodJWT, Scopes: scopes}, nil
+}
+
+func (v *JWTVerifier) verifySignature(alg, signingInput string, signature []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	hash, ok := hashes[alg[2:]]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+
+	switch alg[:2] {
+	case "HS":
+		if len(v.hmacSecret) == 0 {
+			return fmt.Errorf("algorithm %q is not accepted", alg)
+		}
+		mac := hmac.New(hash.New, v.hmacSecret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	case "RS":
+		if v.rsaKey == nil {
+			return fmt.Errorf("algorithm %q is not accepted", alg)
+		}
+		h := hash.New()
+		h.Write([]byte(signingInput))
+		if err := rsa.VerifyPKCS1v15(v.rsaKey, hash, h.Sum(nil), signature); err != nil {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
+
+func (v *JWTVerifier) validateClaims(c *jwtClaims) error {
+	now := v.now()
+
+	if c.ExpiresAt == nil {
+		return errors.New("token has no exp claim")
+	}
+	exp, err := numericDate(c.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("exp: %w", err)
+	}
+	if !now.Before(exp.Add(v.leeway)) {
+		return errors.New("token has expired")
+	}
+	if c.NotBefore != nil {
+		nbf, err := numericDate(c.NotBefore)
+		if err != nil {
+			return fmt.Errorf("nbf: %w", err)
+		}
+		if now.Add(v.leeway).Before(nbf) {
+			return errors.New("token is not valid yet")
+		}
+	}
+	if c.IssuedAt != nil {
+		iat, err := numericDate(c.IssuedAt)
+		if err != nil {
+			return fmt.Errorf("iat: %w", err)
+		}
+		if now.Add(v.leeway).Before(iat) {
+			return errors.New("token was issued in the future")
+		}
+	}
+
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return fmt.Errorf("unexpected issuer %q", c.Issuer)
+	}
+	if v.audience != "" {
+		audiences, err := parseAudience(c.Audience)
+		if err != nil {
+			return fmt.Errorf("aud: %w", err)
+		}
+		if !slices.Contains(audiences, v.audience) {
+			return fmt.Errorf("token is not intended for audience %q", v.audience)
+		}
+	}
+	if c.Subject == "" {
+		return errors.New("token has no sub claim")
+	}
+	return nil
+}
+
+// parseAudience accepts the string or array forms of the aud claim.
+func parseAudience(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, nil
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err != nil {
+		return nil, errors.New("must be a string or an array of strings")
+	}
+	return many, nil
+}
+
+func numericDate(n *json.Number) (time.Time, error) {
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, errors.New("must be a number")
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal token segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signHS256(t *testing.T, secret string, claims map[string]interface{}) string {
+	t.Helper()
+	input := encodeSegment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	t.Helper()
+	input := encodeSegment(t, map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTVerifyHMAC(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v, err := NewJWTVerifier(JWTOptions{
+		HMACSecret: []byte("shh"),
+		Issuer:     "https://issuer.example",
+		Audience:   "hello-api",
+		Leeway:     time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v.now = func() time.Time { return now }
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss": "https://issuer.example",
+			"aud": "hello-api",
+			"sub": "user-1",
+			"exp": now.Add(time.Hour).Unix(),
+		}
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		c := valid()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		expectedErr string
+	}{
+		{name: "valid", token: signHS256(t, "shh", valid())},
+		{name: "audience array", token: signHS256(t, "shh", with("aud", []string{"other", "hello-api"}))},
+		{name: "expired within leeway", token: signHS256(t, "shh", with("exp", now.Add(-30*time.Second).Unix()))},
+		{name: "wrong secret", token: signHS256(t, "other", valid()), expectedErr: "signature mismatch"},
+		{name: "expired", token: signHS256(t, "shh", with("exp", now.Add(-2*time.Minute).Unix())), expectedErr: "expired"},
+		{name: "missing exp", token: signHS256(t, "shh", with("exp", nil)), expectedErr: "no exp claim"},
+		{name: "not yet valid", token: signHS256(t, "shh", with("nbf", now.Add(time.Hour).Unix())), expectedErr: "not valid yet"},
+		{name: "issued in the future", token: signHS256(t, "shh", with("iat", now.Add(time.Hour).Unix())), expectedErr: "issued in the future"},
+		{name: "wrong issuer", token: signHS256(t, "shh", with("iss", "https://evil.example")), expectedErr: "unexpected issuer"},
+		{name: "wrong audience", token: signHS256(t, "shh", with("aud", "other")), expectedErr: "audience"},
+		{name: "missing subject", token: signHS256(t, "shh", with("sub", nil)), expectedErr: "no sub claim"},
+		{name: "malformed", token: "abc.def", expectedErr: "three parts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := v.Verify(tt.token)
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if p.Subject != "user-1" || p.Method != MethodJWT {
+					t.Errorf("unexpected principal %+v", p)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("expected error containing '%s', got '%v'", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestJWTRejectsUnacceptedAlgorithms(t *testing.T) {
+	v, _ := NewJWTVerifier(JWTOptions{HMACSecret: []byte("shh")})
+	claims := encodeSegment(t, map[string]interface{}{"sub": "u", "exp": time.Now().Add(time.Hour).Unix()})
+
+	for _, alg := range []string{"none", "RS256", "ES256", "HS1"} {
+		token := encodeSegment(t, map[string]string{"alg": alg}) + "." + claims + "."
+		if _, err := v.Verify(token); err == nil {
+			t.Errorf("expected alg %s to be rejected", alg)
+		}
+	}
+}
+
+func TestJWTVerifyRSA(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	pub, err := ReadRSAPublicKey(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v, _ := NewJWTVerifier(JWTOptions{RSAPublicKey: pub})
+
+	token := signRS256(t, key, map[string]interface{}{
+		"sub":   "svc",
+		"name":  "Build Bot",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scp":   []string{"hello:read"},
+		"scope": "info:read",
+	})
+	p, err := v.Verify(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.DisplayName() != "Build Bot" {
+		t.Errorf("expected display name 'Build Bot', got '%s'", p.DisplayName())
+	}
+	if !reflect.DeepEqual(p.Scopes, []string{"hello:read", "info:read"}) {
+		t.Errorf("expected scopes [hello:read info:read], got %v", p.Scopes)
+	}
+
+	// An HS256 token must not be accepted by an RSA-only verifier, even when
+	// signed with the public key bytes.
+	forged := signHS256(t, string(der), map[string]interface{}{"sub": "svc", "exp": time.Now().Add(time.Hour).Unix()})
+	if _, err := v.Verify(forged); err == nil {
+		t.Error("expected HS256 token to be rejected by RSA verifier")
+	}
+}
+
+func TestNewJWTVerifierRequiresKey(t *testing.T) {
+	if _, err := NewJWTVerifier(JWTOptions{}); err == nil {
+		t.Error("expected an error without keys")
+	}
+}
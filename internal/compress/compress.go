@@ -0,0 +1,147 @@
+// Package compress implements response compression negotiated from the
+// Accept-Encoding header. gzip and deflate are built in; other codings such
+// as br or zstd can be plugged in by implementing Encoder.
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder produces compressing writers for one content coding.
+type Encoder interface {
+	// Encoding is the Content-Encoding token, such as "gzip".
+	Encoding() string
+	// NewWriter returns a writer compressing into w. Closing it flushes the
+	// compressed stream but does not close w.
+	NewWriter(w io.Writer) io.WriteCloser
+}
+
+// pooledEncoder reuses compressor state, which is expensive to allocate, across
+// responses.
+type pooledEncoder struct {
+	encoding string
+	pool     sync.Pool
+}
+
+type resetWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+func (e *pooledEncoder) Encoding() string { return e.encoding }
+
+func (e *pooledEncoder) NewWriter(w io.Writer) io.WriteCloser {
+	zw := e.pool.Get().(resetWriter)
+	zw.Reset(w)
+	return &pooledWriter{resetWriter: zw, pool: &e.pool}
+}
+
+type pooledWriter struct {
+	resetWriter
+	pool *sync.Pool
+}
+
+func (w *pooledWriter) Close() error {
+	err := w.resetWriter.Close()
+	w.resetWriter.Reset(io.Discard)
+	w.pool.Put(w.resetWriter)
+	return err
+}
+
+// Flush exposes the compressor's Flush so streamed responses reach the
+// client promptly.
+func (w *pooledWriter) Flush() error {
+	if f, ok := w.resetWriter.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Gzip returns the gzip encoder for a compress/flate level.
+func Gzip(level int) (Encoder, error) {
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		return nil, err
+	}
+	return &pooledEncoder{encoding: "gzip", pool: sync.Pool{New: func() interface{} {
+		zw, _ := gzip.NewWriterLevel(io.Discard, level)
+		return zw
+	}}}, nil
+}
+
+// Deflate returns the deflate encoder for a compress/flate level. As RFC
+// 9110 specifies, the DEFLATE stream is wrapped in the zlib format.
+func Deflate(level int) (Encoder, error) {
+	if _, err := zlib.NewWriterLevel(io.Discard, level); err != nil {
+		return nil, err
+	}
+	return &pooledEncoder{encoding: "deflate", pool: sync.Pool{New: func() interface{} {
+		zw, _ := zlib.NewWriterLevel(io.Discard, level)
+		return zw
+	}}}, nil
+}
+
+// Lookup returns the built-in encoder named encoding.
+func Lookup(encoding string, level int) (Encoder, error) {
+	switch encoding {
+	case "gzip":
+		return Gzip(level)
+	case "deflate":
+		return Deflate(level)
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", encoding)
+}
+
+// negotiate picks the encoder for an Accept-Encoding value: the highest
+// quality wins and ties go to the order of encoders. It returns nil when
+// the response should not be encoded.
+func negotiate(acceptEncoding string, encoders []Encoder) Encoder {
+	if acceptEncoding == "" {
+		return nil
+	}
+
+	qualities := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = f
+			}
+		}
+		if coding == "*" {
+			wildcard = q
+			continue
+		}
+		qualities[coding] = q
+	}
+
+	type candidate struct {
+		encoder Encoder
+		q       float64
+		order   int
+	}
+	var candidates []candidate
+	for i, enc := range encoders {
+		q, ok := qualities[enc.Encoding()]
+		if !ok {
+			q = wildcard
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{enc, q, i})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].encoder
+}
@@ -0,0 +1,250 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func encoders(t *testing.T) []Encoder {
+	t.Helper()
+	gz, err := Gzip(gzip.DefaultCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	df, err := Deflate(zlib.DefaultCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return []Encoder{gz, df}
+}
+
+func TestNegotiate(t *testing.T) {
+	encs := encoders(t)
+
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"GZIP", "gzip"},
+		{"deflate, gzip", "gzip"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"gzip;q=0, deflate;q=0", ""},
+		{"br", ""},
+		{"br, *", "gzip"},
+		{"*;q=0.1, deflate;q=0.5", "deflate"},
+		{"gzip;q=0, *", "deflate"},
+	}
+
+	for _, tt := range tests {
+		got := ""
+		if enc := negotiate(tt.acceptEncoding, encs); enc != nil {
+			got = enc.Encoding()
+		}
+		if got != tt.expected {
+			t.Errorf("negotiate(%q): expected '%s', got '%s'", tt.acceptEncoding, tt.expected, got)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if _, err := Lookup("br", -1); err == nil {
+		t.Error("expected an error for an unsupported encoding")
+	}
+	if _, err := Lookup("gzip", 42); err == nil {
+		t.Error("expected an error for an invalid level")
+	}
+}
+
+func decode(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+	var r io.Reader = body
+	var err error
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(body)
+	case "deflate":
+		r, err = zlib.NewReader(body)
+	}
+	if err != nil {
+		t.Fatalf("failed to open %s body: %v", encoding, err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to read %s body: %v", encoding, err)
+	}
+	return string(data)
+}
+
+func TestMiddleware(t *testing.T) {
+	large := strings.Repeat("hello, world ", 200)
+
+	tests := []struct {
+		name             string
+		method           string
+		acceptEncoding   string
+		contentType      string
+		contentEncoding  string
+		status           int
+		body             string
+		writes           int
+		expectedEncoding string
+	}{
+		{name: "large JSON gzip", acceptEncoding: "gzip", contentType: "application/json", body: large, expectedEncoding: "gzip"},
+		{name: "large JSON deflate", acceptEncoding: "deflate", contentType: "application/json", body: large, expectedEncoding: "deflate"},
+		{name: "many small writes", acceptEncoding: "gzip", contentType: "text/plain", body: "x", writes: 5000, expectedEncoding: "gzip"},
+		{name: "error status", acceptEncoding: "gzip", contentType: "application/json", status: http.StatusNotFound, body: large, expectedEncoding: "gzip"},
+		{name: "small body", acceptEncoding: "gzip", contentType: "application/json", body: `{"pong":"pong"}`},
+		{name: "no Accept-Encoding", contentType: "application/json", body: large},
+		{name: "already compressed type", acceptEncoding: "gzip", contentType: "image/png", body: large},
+		{name: "already encoded", acceptEncoding: "gzip", contentType: "application/json", contentEncoding: "br", body: large, expectedEncoding: "br"},
+		{name: "event stream", acceptEncoding: "gzip", contentType: "text/event-stream", body: large},
+		{name: "no content", acceptEncoding: "gzip", status: http.StatusNoContent},
+		{name: "HEAD", method: http.MethodHead, acceptEncoding: "gzip", contentType: "application/json", body: large},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Middleware(Options{Encoders: encoders(t), MinSize: 1024}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				if tt.contentEncoding != "" {
+					w.Header().Set("Content-Encoding", tt.contentEncoding)
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				for i := 0; i < max(tt.writes, 1); i++ {
+					io.WriteString(w, tt.body)
+				}
+			}))
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			expectedStatus := tt.status
+			if expectedStatus == 0 {
+				expectedStatus = http.StatusOK
+			}
+			if rec.Code != expectedStatus {
+				t.Errorf("expected status %d, got %d", expectedStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("expected Vary 'Accept-Encoding', got '%s'", got)
+			}
+			encoding := rec.Header().Get("Content-Encoding")
+			if encoding != tt.expectedEncoding {
+				t.Fatalf("expected Content-Encoding '%s', got '%s'", tt.expectedEncoding, encoding)
+			}
+
+			expectedBody := strings.Repeat(tt.body, max(tt.writes, 1))
+			if got := decode(t, encoding, rec.Body); got != expectedBody {
+				t.Errorf("expected body of %d bytes, got %d", len(expectedBody), len(got))
+			}
+		})
+	}
+}
+
+func TestMiddlewareSniffsContentType(t *testing.T) {
+	h := Middleware(Options{Encoders: encoders(t), MinSize: 10}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html><body>"+strings.Repeat("hi ", 100)+"</body></html>")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("expected sniffed Content-Type 'text/html; charset=utf-8', got '%s'", got)
+	}
+}
+
+func TestMiddlewareFlush(t *testing.T) {
+	flushed := make(chan string, 1)
+	h := Middleware(Options{Encoders: encoders(t), MinSize: 1024}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "first chunk")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("unexpected flush error: %v", err)
+		}
+		flushed <- w.(*compressWriter).ResponseWriter.(*httptest.ResponseRecorder).Header().Get("Content-Encoding")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := <-flushed; got != "gzip" {
+		t.Errorf("expected flushing to start compression, got Content-Encoding '%s'", got)
+	}
+	if !rec.Flushed {
+		t.Error("expected the underlying writer to be flushed")
+	}
+	if got := decode(t, "gzip", rec.Body); got != "first chunk" {
+		t.Errorf("expected 'first chunk', got '%s'", got)
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	serve := func(t *testing.T, minSize int) *httptest.ResponseRecorder {
+		t.Helper()
+		h := Middleware(Options{Encoders: encoders(t), MinSize: minSize}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+			io.WriteString(w, "partial")
+			panic("test panic")
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		func() {
+			defer func() {
+				if p := recover(); p != "test panic" {
+					t.Errorf("expected the panic to propagate, got %v", p)
+				}
+			}()
+			h.ServeHTTP(rec, req)
+		}()
+
+		if encoding := rec.Header().Get("Content-Encoding"); encoding != "" {
+			t.Errorf("expected Content-Encoding to be cleared, got '%s'", encoding)
+		}
+		if vary := rec.Header().Values("Vary"); len(vary) != 1 || vary[0] != "Origin" {
+			t.Errorf("expected Vary to keep only 'Origin', got %v", vary)
+		}
+		return rec
+	}
+
+	t.Run("buffered", func(t *testing.T) {
+		rec := serve(t, 1024)
+		if rec.Body.Len() != 0 {
+			t.Errorf("expected nothing to be sent, got body %q", rec.Body.String())
+		}
+		if rec.Flushed {
+			t.Error("expected the response not to be flushed")
+		}
+	})
+
+	t.Run("compressing", func(t *testing.T) {
+		serve(t, 1)
+	})
+}
@@ -0,0 +1,239 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultSkipTypes are media type prefixes that are already compressed or
+// must be streamed unbuffered, so compressing them wastes CPU or breaks
+// delivery.
+var DefaultSkipTypes = []string{
+	"image/", "video/", "audio/",
+	"application/gzip", "application/zip", "application/zstd", "application/x-brotli",
+	"application/octet-stream",
+	"text/event-stream",
+}
+
+// Options configure Middleware.
+type Options struct {
+	// Encoders in order of server preference.
+	Encoders []Encoder
+	// MinSize is the smallest body, in bytes, worth compressing.
+	MinSize int
+	// SkipTypes are Content-Type prefixes that are never compressed.
+	// Nil means DefaultSkipTypes.
+	SkipTypes []string
+}
+
+// Middleware compresses response bodies with the best encoder the client
+// accepts. Bodies are buffered until MinSize bytes have been written, so
+// small responses are sent as is and keep their Content-Length.
+func Middleware(opts Options, next http.Handler) http.Handler {
+	if opts.SkipTypes == nil {
+		opts.SkipTypes = DefaultSkipTypes
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		enc := negotiate(r.Header.Get("Accept-Encoding"), opts.Encoders)
+		if enc == nil || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, opts: &opts, encoder: enc, status: http.StatusOK}
+		defer func() {
+			// A panicking handler's response is left unsent, so the panic
+			// can still be answered with an error status further out. That
+			// answer is not compressed, so it must not be labeled as such.
+			if p := recover(); p != nil {
+				h := w.Header()
+				h.Del("Content-Encoding")
+				removeVary(h, "Accept-Encoding")
+				panic(p)
+			}
+			cw.Close()
+		}()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// removeVary drops field from the Vary header, keeping the fields other
+// handlers listed.
+func removeVary(h http.Header, field string) {
+	var kept []string
+	for _, v := range h.Values("Vary") {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" && !strings.EqualFold(f, field) {
+				kept = append(kept, f)
+			}
+		}
+	}
+	h.Del("Vary")
+	if len(kept) > 0 {
+		h.Set("Vary", strings.Join(kept, ", "))
+	}
+}
+
+// compressWriter buffers the start of a response to decide whether it is
+// worth compressing, then either streams it through the encoder or passes
+// it through untouched.
+type compressWriter struct {
+	http.ResponseWriter
+	opts    *Options
+	encoder Encoder
+
+	status      int
+	wroteHeader bool // handler called WriteHeader
+	decided     bool // headers have been sent downstream
+	buf         bytes.Buffer
+	zw          io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader || cw.decided {
+		return
+	}
+	// Informational responses are sent immediately and do not end the header.
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = code
+	if !cw.compressible() {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.zw != nil {
+			return cw.zw.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.buf.Write(b)
+	if cw.buf.Len() >= cw.opts.MinSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends buffered data, compressing it if the response qualifies, so
+// streaming handlers are not held back by the MinSize buffer.
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.decide(true)
+	}
+	if f, ok := cw.zw.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Hijack hands the connection over to the handler, as for a WebSocket
+// upgrade. Nothing is written to the response afterwards.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(cw.ResponseWriter).Hijack()
+	if err == nil {
+		cw.decided = true
+	}
+	return conn, brw, err
+}
+
+// Close finishes the response: small bodies are sent uncompressed, and the
+// compressed stream is terminated.
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			cw.WriteHeader(http.StatusOK)
+		}
+		if err := cw.decide(cw.buf.Len() >= cw.opts.MinSize); err != nil {
+			return err
+		}
+	}
+	if cw.zw != nil {
+		return cw.zw.Close()
+	}
+	return nil
+}
+
+// compressible reports whether the status and headers allow compression.
+func (cw *compressWriter) compressible() bool {
+	switch {
+	case cw.status < http.StatusOK, cw.status == http.StatusNoContent, cw.status == http.StatusNotModified:
+		return false
+	}
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if cl := h.Get("Content-Length"); cl != "" {
+		if n, err := strconv.Atoi(cl); err == nil && n < cw.opts.MinSize {
+			return false
+		}
+	}
+	contentType := strings.ToLower(h.Get("Content-Type"))
+	for _, prefix := range cw.opts.SkipTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// decide sends the headers downstream, compressing the rest of the response
+// if compress is set and the response qualifies, then writes out the
+// buffered body.
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+
+	if compress && cw.compressible() {
+		h := cw.Header()
+		// net/http would otherwise sniff the compressed bytes.
+		if _, typed := h["Content-Type"]; !typed {
+			h.Set("Content-Type", http.DetectContentType(cw.buf.Bytes()))
+		}
+		h.Set("Content-Encoding", cw.encoder.Encoding())
+		h.Del("Content-Length")
+		// A strong validator no longer matches the encoded representation.
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.zw = cw.encoder.NewWriter(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if cw.zw != nil {
+		_, err = cw.zw.Write(cw.buf.Bytes())
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf.Bytes())
+	}
+	cw.buf.Reset()
+	return err
+}
@@ -0,0 +1,88 @@
+package compress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// RequestEncodings lists the content codings DecodeRequest understands, for
+// the Accept-Encoding header of a 415 response.
+const RequestEncodings = "gzip, deflate"
+
+// ErrUnsupportedEncoding is returned by DecodeRequest for content codings it
+// cannot decode.
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// DecodeRequest replaces r.Body with a reader that undoes the request's
+// Content-Encoding. Size limits such as http.MaxBytesReader should be applied
+// afterwards so they bound the decompressed size.
+//
+// At most one coding other than identity is accepted: every coding wraps the
+// body in another decompressor, so a request stacking thousands of them could
+// hold a decompressor's state for each one.
+func DecodeRequest(r *http.Request) error {
+	values := r.Header.Values("Content-Encoding")
+	if len(values) == 0 {
+		return nil
+	}
+
+	var coding string
+	for _, c := range strings.Split(strings.Join(values, ","), ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "" || c == "identity" {
+			continue
+		}
+		if coding != "" {
+			return fmt.Errorf("%w: more than one coding", ErrUnsupportedEncoding)
+		}
+		coding = c
+	}
+
+	body := r.Body
+	var err error
+	switch coding {
+	case "":
+	case "gzip", "x-gzip":
+		body, err = wrapReader(body, func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) })
+	case "deflate":
+		body, err = wrapReader(body, zlib.NewReader)
+	default:
+		return fmt.Errorf("%w %q", ErrUnsupportedEncoding, coding)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s body: %w", coding, err)
+	}
+
+	r.Body = body
+	r.ContentLength = -1
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	return nil
+}
+
+// decodingReader closes both the decompressor and the body it reads from.
+type decodingReader struct {
+	io.ReadCloser
+	body io.Closer
+}
+
+func (d *decodingReader) Close() error {
+	err := d.ReadCloser.Close()
+	if bodyErr := d.body.Close(); err == nil {
+		err = bodyErr
+	}
+	return err
+}
+
+func wrapReader(body io.ReadCloser, open func(io.Reader) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	zr, err := open(body)
+	if err != nil {
+		return nil, err
+	}
+	return &decodingReader{ReadCloser: zr, body: body}, nil
+}
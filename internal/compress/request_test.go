@@ -0,0 +1,96 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func zlibBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to deflate: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeRequest(t *testing.T) {
+	payload := []byte(`{"name":"Alice"}`)
+
+	tests := []struct {
+		name            string
+		encoding        string
+		body            []byte
+		expectedErr     string
+		unsupported     bool
+		expectedPayload []byte
+	}{
+		{name: "no encoding", body: payload, expectedPayload: payload},
+		{name: "identity", encoding: "identity", body: payload, expectedPayload: payload},
+		{name: "gzip", encoding: "gzip", body: gzipBytes(t, payload), expectedPayload: payload},
+		{name: "x-gzip", encoding: "x-gzip", body: gzipBytes(t, payload), expectedPayload: payload},
+		{name: "deflate", encoding: "Deflate", body: zlibBytes(t, payload), expectedPayload: payload},
+		{name: "identity and gzip", encoding: "identity, gzip", body: gzipBytes(t, payload), expectedPayload: payload},
+		{name: "stacked", encoding: "deflate, gzip", body: gzipBytes(t, zlibBytes(t, payload)), expectedErr: "more than one coding", unsupported: true},
+		{name: "stacked gzip", encoding: strings.Repeat("gzip,", 3000) + "gzip", body: payload, expectedErr: "more than one coding", unsupported: true},
+		{name: "unsupported", encoding: "br", body: payload, expectedErr: `unsupported content encoding "br"`, unsupported: true},
+		{name: "corrupt gzip", encoding: "gzip", body: payload, expectedErr: "invalid gzip body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			err := DecodeRequest(req)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error containing '%s', got '%v'", tt.expectedErr, err)
+				}
+				if errors.Is(err, ErrUnsupportedEncoding) != tt.unsupported {
+					t.Errorf("expected errors.Is(ErrUnsupportedEncoding) to be %v", tt.unsupported)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+			if !bytes.Equal(got, tt.expectedPayload) {
+				t.Errorf("expected body '%s', got '%s'", tt.expectedPayload, got)
+			}
+			if req.Header.Get("Content-Encoding") != "" {
+				t.Error("expected Content-Encoding to be removed")
+			}
+			if err := req.Body.Close(); err != nil {
+				t.Errorf("unexpected close error: %v", err)
+			}
+		})
+	}
+}
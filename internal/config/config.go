@@ -0,0 +1,905 @@
+// Package config loads the hello-api runtime configuration from defaults, an
+// optional YAML or JSON file, environment variables and command-line flags.
+//
+// Sources are applied in increasing order of precedence:
+//
+//	defaults < config file < environment < flags
+//
+// Every setting has a dotted key used in config files (server.addr), an
+// environment variable derived from it (SERVER_ADDR) and a flag
+// (-server-addr). The config file itself is selected with -config or
+// CONFIG_FILE.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"hello-api/internal/auth"
+	"hello-api/internal/compress"
+	"hello-api/internal/cors"
+	"hello-api/internal/ratelimit"
+	"hello-api/internal/validation"
+)
+
+// Config is the complete runtime configuration of the server.
+type Config struct {
+	Server      ServerConfig
+	Log         LogConfig
+	Tracing     TracingConfig
+	RateLimit   RateLimitConfig
+	Auth        AuthConfig
+	CORS        CORSConfig
+	Errors      ErrorsConfig
+	Compression CompressionConfig
+	WebSocket   WebSocketConfig
+	GRPC        GRPCConfig
+	Hello       HelloConfig
+}
+
+// ServerConfig feeds http.Server and the graceful shutdown sequence.
+type ServerConfig struct {
+	Addr            string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+	DrainDelay      time.Duration
+}
+
+// LogConfig controls the application logger.
+type LogConfig struct {
+	Level  string
+	Format string
+}
+
+// TracingConfig selects where spans are exported and how many are sampled.
+type TracingConfig struct {
+	Exporter     string
+	OTLPEndpoint string
+	SampleRatio  float64
+}
+
+// RateLimitConfig controls the per-client token bucket limiter applied to
+// Routes.
+type RateLimitConfig struct {
+	Enabled        bool
+	Rate           float64
+	Burst          int
+	Key            string
+	Routes         []string
+	TrustedProxies []string
+	IdleTTL        time.Duration
+	MaxKeys        int
+}
+
+// AuthConfig controls authentication of Routes. Requests may present a
+// static API key, whose SHA-256 hash is listed in APIKeys or APIKeysFile,
+// or a JWT bearer token verified with JWT.
+type AuthConfig struct {
+	Enabled       bool
+	Routes        []string
+	APIKeys       []string
+	APIKeysFile   string
+	RequiredScope string
+	JWT           JWTConfig
+}
+
+// JWTConfig controls bearer token verification. Tokens are accepted when
+// HMACSecret or RSAPublicKeyFile is set.
+type JWTConfig struct {
+	HMACSecret       string
+	RSAPublicKeyFile string
+	Issuer           string
+	Audience         string
+	Leeway           time.Duration
+}
+
+// Enabled reports whether any bearer token verification key is configured.
+func (c JWTConfig) Enabled() bool {
+	return c.HMACSecret != "" || c.RSAPublicKeyFile != ""
+}
+
+// CORSConfig is the cross-origin policy applied to every route.
+type CORSConfig struct {
+	Enabled          bool
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// Options converts c into the options understood by cors.New.
+func (c CORSConfig) Options() cors.Options {
+	return cors.Options{
+		AllowedOrigins:   c.AllowedOrigins,
+		AllowedMethods:   c.AllowedMethods,
+		AllowedHeaders:   c.AllowedHeaders,
+		ExposedHeaders:   c.ExposedHeaders,
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           c.MaxAge,
+	}
+}
+
+// ErrorsConfig selects the shape of error response bodies: "legacy" for
+// the {"error","code"} ErrorResponse, or "problem" for RFC 9457 problem
+// details. Clients can ask for problem details per request either way.
+type ErrorsConfig struct {
+	Format string
+}
+
+// CompressionConfig controls response compression. Encodings are listed in
+// order of preference.
+type CompressionConfig struct {
+	Enabled   bool
+	Encodings []string
+	Level     int
+	MinSize   int
+}
+
+// Encoders returns the configured encoders in order of preference.
+func (c CompressionConfig) Encoders() ([]compress.Encoder, error) {
+	encoders := make([]compress.Encoder, 0, len(c.Encodings))
+	for _, name := range c.Encodings {
+		enc, err := compress.Lookup(name, c.Level)
+		if err != nil {
+			return nil, err
+		}
+		encoders = append(encoders, enc)
+	}
+	return encoders, nil
+}
+
+// WebSocketConfig controls connections on /ws. Clients are pinged every
+// PingInterval and disconnected after two intervals without any frame.
+type WebSocketConfig struct {
+	MaxMessageBytes int64
+	PingInterval    time.Duration
+}
+
+// GRPCConfig controls the gRPC server that runs alongside the HTTP server.
+type GRPCConfig struct {
+	Enabled bool
+	Addr    string
+}
+
+// HelloConfig controls the behavior of the /hello handler. An empty
+// DefaultName greets the localized "World".
+type HelloConfig struct {
+	DefaultName  string
+	MaxBodyBytes int64
+	Name         validation.Rules
+	Batch        BatchConfig
+	Stream       StreamConfig
+}
+
+// StreamConfig paces the GET /hello/stream event stream. Retry is the
+// reconnection delay suggested to clients.
+type StreamConfig struct {
+	Interval  time.Duration
+	Heartbeat time.Duration
+	Retry     time.Duration
+}
+
+// BatchConfig limits the size of POST /hello/batch requests.
+type BatchConfig struct {
+	MaxItems     int
+	MaxBodyBytes int64
+}
+
+// Default returns the configuration the server uses when nothing else is set.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:            ":8080",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPEndpoint: "http://localhost:4318",
+			SampleRatio:  1,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Rate:    10,
+			Burst:   20,
+			Key:     "ip",
+			Routes:  []string{"/hello", "/hello/batch", "/hello/stream", "/ws", "/info"},
+			IdleTTL: 10 * time.Minute,
+			MaxKeys: 10000,
+		},
+		Auth: AuthConfig{
+			Routes: []string{"/hello", "/hello/batch", "/hello/stream", "/ws", "/info"},
+			JWT: JWTConfig{
+				Leeway: 30 * time.Second,
+			},
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"get", "head", "post"},
+			AllowedHeaders: []string{"accept", "accept-language", "authorization", "content-type", "traceparent", "tracestate", "x-api-key", "x-request-id"},
+			ExposedHeaders: []string{"content-language", "ratelimit-limit", "ratelimit-remaining", "ratelimit-reset", "retry-after", "x-request-id"},
+			MaxAge:         10 * time.Minute,
+		},
+		Errors: ErrorsConfig{
+			Format: "legacy",
+		},
+		Compression: CompressionConfig{
+			Enabled:   true,
+			Encodings: []string{"gzip", "deflate"},
+			Level:     -1, // compress/flate default
+			MinSize:   1024,
+		},
+		WebSocket: WebSocketConfig{
+			MaxMessageBytes: 65536, // 64KB
+			PingInterval:    30 * time.Second,
+		},
+		GRPC: GRPCConfig{
+			Enabled: true,
+			Addr:    ":9090",
+		},
+		Hello: HelloConfig{
+			DefaultName:  "",
+			MaxBodyBytes: 1048576, // 1MB
+			Name:         validation.DefaultRules(),
+			Batch: BatchConfig{
+				MaxItems:     1000,
+				MaxBodyBytes: 10485760, // 10MB
+			},
+			Stream: StreamConfig{
+				Interval:  time.Second,
+				Heartbeat: 15 * time.Second,
+				Retry:     3 * time.Second,
+			},
+		},
+	}
+}
+
+// setting describes a single configuration key and how to apply a raw string
+// value from any source to a Config.
+type setting struct {
+	key   string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+func (s setting) env() string {
+	return strings.ToUpper(strings.ReplaceAll(s.key, ".", "_"))
+}
+
+func (s setting) flag() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+var settings = []setting{
+	{"server.addr", "address the HTTP server listens on", func(c *Config, v string) error {
+		c.Server.Addr = v
+		return nil
+	}},
+	{"server.read_timeout", "maximum duration for reading an entire request", durationSetter(func(c *Config) *time.Duration { return &c.Server.ReadTimeout })},
+	{"server.write_timeout", "maximum duration before timing out writes of a response", durationSetter(func(c *Config) *time.Duration { return &c.Server.WriteTimeout })},
+	{"server.idle_timeout", "maximum time to wait for the next request on keep-alive connections", durationSetter(func(c *Config) *time.Duration { return &c.Server.IdleTimeout })},
+	{"server.shutdown_timeout", "maximum time to wait for in-flight requests during shutdown", durationSetter(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{"server.drain_delay", "time between failing readiness on SIGTERM and starting shutdown", durationSetter(func(c *Config) *time.Duration { return &c.Server.DrainDelay })},
+	{"log.level", "minimum log level (debug, info, warn, error)", func(c *Config, v string) error {
+		c.Log.Level = strings.ToLower(v)
+		return nil
+	}},
+	{"log.format", "log output format (json, text)", func(c *Config, v string) error {
+		c.Log.Format = strings.ToLower(v)
+		return nil
+	}},
+	{"tracing.exporter", "span exporter (none, stdout, otlp)", func(c *Config, v string) error {
+		c.Tracing.Exporter = strings.ToLower(v)
+		return nil
+	}},
+	{"tracing.otlp_endpoint", "OTLP/HTTP collector URL used by the otlp exporter", func(c *Config, v string) error {
+		c.Tracing.OTLPEndpoint = v
+		return nil
+	}},
+	{"tracing.sample_ratio", "fraction of new traces to sample, between 0 and 1", func(c *Config, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		c.Tracing.SampleRatio = f
+		return nil
+	}},
+	{"ratelimit.enabled", "enable per-client rate limiting", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		c.RateLimit.Enabled = b
+		return nil
+	}},
+	{"ratelimit.rate", "tokens added to each bucket per second", func(c *Config, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		c.RateLimit.Rate = f
+		return nil
+	}},
+	{"ratelimit.burst", "bucket capacity, the largest burst a client may send", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		c.RateLimit.Burst = n
+		return nil
+	}},
+	{"ratelimit.key", "what a bucket is keyed by (ip, api_key, route)", func(c *Config, v string) error {
+		c.RateLimit.Key = strings.ToLower(v)
+		return nil
+	}},
+	{"ratelimit.routes", "comma-separated routes to rate limit", func(c *Config, v string) error {
+		c.RateLimit.Routes = splitList(v)
+		return nil
+	}},
+	{"ratelimit.trusted_proxies", "comma-separated proxy CIDRs whose X-Forwarded-For is trusted", func(c *Config, v string) error {
+		c.RateLimit.TrustedProxies = splitList(v)
+		return nil
+	}},
+	{"ratelimit.idle_ttl", "how long an idle client bucket is kept", durationSetter(func(c *Config) *time.Duration { return &c.RateLimit.IdleTTL })},
+	{"ratelimit.max_keys", "maximum number of client buckets kept in memory", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		c.RateLimit.MaxKeys = n
+		return nil
+	}},
+	{"auth.enabled", "require authentication on auth.routes", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		c.Auth.Enabled = b
+		return nil
+	}},
+	{"auth.routes", "comma-separated routes that require authentication", func(c *Config, v string) error {
+		c.Auth.Routes = splitList(v)
+		return nil
+	}},
+	{"auth.api_keys", "comma-separated name:sha256hex API key entries", func(c *Config, v string) error {
+		c.Auth.APIKeys = splitEntries(v)
+		return nil
+	}},
+	{"auth.api_keys_file", "file with one name:sha256hex API key entry per line", func(c *Config, v string) error {
+		c.Auth.APIKeysFile = v
+		return nil
+	}},
+	{"auth.required_scope", "scope a bearer token must grant to access auth.routes", func(c *Config, v string) error {
+		c.Auth.RequiredScope = v
+		return nil
+	}},
+	{"auth.jwt.hmac_secret", "shared secret for HS256/HS384/HS512 bearer tokens", func(c *Config, v string) error {
+		c.Auth.JWT.HMACSecret = v
+		return nil
+	}},
+	{"auth.jwt.rsa_public_key_file", "PEM RSA public key for RS256/RS384/RS512 bearer tokens", func(c *Config, v string) error {
+		c.Auth.JWT.RSAPublicKeyFile = v
+		return nil
+	}},
+	{"auth.jwt.issuer", "required iss claim of bearer tokens", func(c *Config, v string) error {
+		c.Auth.JWT.Issuer = v
+		return nil
+	}},
+	{"auth.jwt.audience", "required aud claim of bearer tokens", func(c *Config, v string) error {
+		c.Auth.JWT.Audience = v
+		return nil
+	}},
+	{"auth.jwt.leeway", "clock skew tolerated when checking exp, nbf and iat", durationSetter(func(c *Config) *time.Duration { return &c.Auth.JWT.Leeway })},
+	{"cors.enabled", "answer CORS preflights and add Access-Control-* headers", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		c.CORS.Enabled = b
+		return nil
+	}},
+	{"cors.allowed_origins", "comma-separated origins allowed to call the API (https://*.example.com matches subdomains, * matches any)", func(c *Config, v string) error {
+		c.CORS.AllowedOrigins = splitList(v)
+		return nil
+	}},
+	{"cors.allowed_methods", "comma-separated methods allowed in cross-origin requests", func(c *Config, v string) error {
+		c.CORS.AllowedMethods = splitList(v)
+		return nil
+	}},
+	{"cors.allowed_headers", "comma-separated request headers allowed in cross-origin requests (* for any)", func(c *Config, v string) error {
+		c.CORS.AllowedHeaders = splitList(v)
+		return nil
+	}},
+	{"cors.exposed_headers", "comma-separated response headers exposed to cross-origin scripts", func(c *Config, v string) error {
+		c.CORS.ExposedHeaders = splitList(v)
+		return nil
+	}},
+	{"cors.allow_credentials", "allow cookies and Authorization headers on cross-origin requests", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		c.CORS.AllowCredentials = b
+		return nil
+	}},
+	{"cors.max_age", "how long browsers may cache a preflight response", durationSetter(func(c *Config) *time.Duration { return &c.CORS.MaxAge })},
+	{"errors.format", "error body format (legacy, problem)", func(c *Config, v string) error {
+		c.Errors.Format = strings.ToLower(v)
+		return nil
+	}},
+	{"compression.enabled", "compress responses the client accepts in a supported encoding", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		c.Compression.Enabled = b
+		return nil
+	}},
+	{"compression.encodings", "comma-separated content codings in order of preference (gzip, deflate)", func(c *Config, v string) error {
+		c.Compression.Encodings = splitList(v)
+		return nil
+	}},
+	{"compression.level", "compression level from 1 (fastest) to 9 (smallest), or -1 for the default", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		c.Compression.Level = n
+		return nil
+	}},
+	{"compression.min_size", "smallest response body in bytes worth compressing", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		c.Compression.MinSize = n
+		return nil
+	}},
+	{"websocket.max_message_bytes", "maximum size of a message received on /ws in bytes", func(c *Config, v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		c.WebSocket.MaxMessageBytes = n
+		return nil
+	}},
+	{"websocket.ping_interval", "time between pings on idle /ws connections", durationSetter(func(c *Config) *time.Duration { return &c.WebSocket.PingInterval })},
+	{"grpc.enabled", "serve the gRPC API alongside HTTP", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		c.GRPC.Enabled = b
+		return nil
+	}},
+	{"grpc.addr", "address the gRPC server listens on", func(c *Config, v string) error {
+		c.GRPC.Addr = v
+		return nil
+	}},
+	{"hello.default_name", "name used by /hello when none is supplied (default: localized \"World\")", func(c *Config, v string) error {
+		c.Hello.DefaultName = v
+		return nil
+	}},
+	{"hello.max_body_bytes", "maximum size of a POST /hello body in bytes", func(c *Config, v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		c.Hello.MaxBodyBytes = n
+		return nil
+	}},
+	{"hello.batch.max_items", "maximum number of items in a POST /hello/batch request", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		c.Hello.Batch.MaxItems = n
+		return nil
+	}},
+	{"hello.batch.max_body_bytes", "maximum size of a POST /hello/batch body in bytes", func(c *Config, v string) error {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		c.Hello.Batch.MaxBodyBytes = n
+		return nil
+	}},
+	{"hello.stream.interval", "time between greetings on GET /hello/stream", durationSetter(func(c *Config) *time.Duration { return &c.Hello.Stream.Interval })},
+	{"hello.stream.heartbeat", "time between heartbeat comments on GET /hello/stream", durationSetter(func(c *Config) *time.Duration { return &c.Hello.Stream.Heartbeat })},
+	{"hello.stream.retry", "reconnection delay suggested to GET /hello/stream clients", durationSetter(func(c *Config) *time.Duration { return &c.Hello.Stream.Retry })},
+	{"hello.name.max_length", "maximum number of characters in a name", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		c.Hello.Name.MaxLength = n
+		return nil
+	}},
+	{"hello.name.normalization", "Unicode normalization applied to names (none, nfc, nfkc)", func(c *Config, v string) error {
+		c.Hello.Name.Normalization = strings.ToLower(v)
+		return nil
+	}},
+	{"hello.name.allowed_classes", "comma-separated character classes allowed in names (letter, mark, number, space, punctuation, symbol)", func(c *Config, v string) error {
+		c.Hello.Name.AllowedClasses = splitList(v)
+		return nil
+	}},
+	{"hello.name.strip_control", "strip control characters from names instead of rejecting them", func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		c.Hello.Name.StripControl = b
+		return nil
+	}},
+}
+
+// splitList parses a comma-separated setting of case-insensitive items such
+// as routes and header names, lowercasing them and dropping empty entries.
+func splitList(v string) []string {
+	out := splitEntries(v)
+	for i, item := range out {
+		out[i] = strings.ToLower(item)
+	}
+	return out
+}
+
+// splitEntries parses a comma-separated setting whose items keep their case,
+// such as the name:hash entries of auth.api_keys, dropping empty entries.
+func splitEntries(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func durationSetter(field func(c *Config) *time.Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", v)
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+// Load builds a Config from the given command-line arguments (without the
+// program name) and environment lookup function. Pass os.Args[1:] and
+// os.LookupEnv in production; tests can supply their own.
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("hello-api", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML or JSON config file (env CONFIG_FILE)")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.key] = fs.String(s.flag(), "", fmt.Sprintf("%s (env %s)", s.usage, s.env()))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := lookupEnv(s.env()); ok {
+			if err := s.set(cfg, v); err != nil {
+				return nil, fmt.Errorf("config: %s: %w", s.env(), err)
+			}
+		}
+	}
+
+	byFlag := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byFlag[s.flag()] = s
+	}
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		s, ok := byFlag[f.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		if err := s.set(cfg, *flagValues[s.key]); err != nil {
+			flagErr = fmt.Errorf("config: -%s: %w", f.Name, err)
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile applies the settings found in a YAML or JSON file. The format is
+// chosen by extension; unknown keys are rejected so typos surface at startup.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&raw)
+	default:
+		return fmt.Errorf("config: unsupported config file extension %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("config: parsing %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten("", raw, values); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	byKey := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s, ok := byKey[k]
+		if !ok {
+			return fmt.Errorf("config: %s: unknown key %q", path, k)
+		}
+		if err := s.set(cfg, values[k]); err != nil {
+			return fmt.Errorf("config: %s: %s: %w", path, k, err)
+		}
+	}
+	return nil
+}
+
+// flatten turns nested maps into dotted keys with string values.
+func flatten(prefix string, m map[string]interface{}, out map[string]string) error {
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			if err := flatten(key, val, out); err != nil {
+				return err
+			}
+		case []interface{}:
+			// Lists map onto comma-separated settings.
+			items := make([]string, len(val))
+			for i, item := range val {
+				if _, nested := item.(map[string]interface{}); nested {
+					return fmt.Errorf("key %q: lists of objects are not supported", key)
+				}
+				items[i] = fmt.Sprint(item)
+			}
+			out[key] = strings.Join(items, ",")
+		case nil:
+			// An empty value leaves the lower-precedence setting in place.
+		default:
+			out[key] = fmt.Sprint(val)
+		}
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once so operators can fix a
+// configuration in a single pass.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr must not be empty"))
+	}
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	} {
+		if d.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %s", d.key, d.value))
+		}
+	}
+
+	if c.Server.DrainDelay < 0 {
+		errs = append(errs, fmt.Errorf("server.drain_delay must not be negative, got %s", c.Server.DrainDelay))
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level must be one of debug, info, warn, error, got %q", c.Log.Level))
+	}
+
+	switch c.Log.Format {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("log.format must be one of json, text, got %q", c.Log.Format))
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if u, err := url.Parse(c.Tracing.OTLPEndpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("tracing.otlp_endpoint must be an absolute URL, got %q", c.Tracing.OTLPEndpoint))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter must be one of none, stdout, otlp, got %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sample_ratio must be between 0 and 1, got %g", c.Tracing.SampleRatio))
+	}
+
+	if c.RateLimit.Enabled {
+		if c.RateLimit.Rate <= 0 {
+			errs = append(errs, fmt.Errorf("ratelimit.rate must be positive, got %g", c.RateLimit.Rate))
+		}
+		if c.RateLimit.Burst < 1 {
+			errs = append(errs, fmt.Errorf("ratelimit.burst must be at least 1, got %d", c.RateLimit.Burst))
+		}
+		switch c.RateLimit.Key {
+		case ratelimit.KeyIP, ratelimit.KeyAPIKey, ratelimit.KeyRoute:
+		default:
+			errs = append(errs, fmt.Errorf("ratelimit.key must be one of ip, api_key, route, got %q", c.RateLimit.Key))
+		}
+		if _, err := ratelimit.ParseCIDRs(c.RateLimit.TrustedProxies); err != nil {
+			errs = append(errs, fmt.Errorf("ratelimit.trusted_proxies: %w", err))
+		}
+		if c.RateLimit.IdleTTL <= 0 {
+			errs = append(errs, fmt.Errorf("ratelimit.idle_ttl must be positive, got %s", c.RateLimit.IdleTTL))
+		}
+		if c.RateLimit.MaxKeys < 1 {
+			errs = append(errs, fmt.Errorf("ratelimit.max_keys must be at least 1, got %d", c.RateLimit.MaxKeys))
+		}
+	}
+
+	if c.Auth.Enabled {
+		errs = append(errs, c.Auth.validate()...)
+	}
+
+	if c.CORS.Enabled {
+		if len(c.CORS.AllowedOrigins) == 0 {
+			errs = append(errs, errors.New("cors.allowed_origins must not be empty when CORS is enabled"))
+		}
+		if _, err := cors.New(c.CORS.Options()); err != nil {
+			errs = append(errs, fmt.Errorf("cors: %w", err))
+		}
+	}
+
+	switch c.Errors.Format {
+	case "legacy", "problem":
+	default:
+		errs = append(errs, fmt.Errorf("errors.format must be one of legacy, problem, got %q", c.Errors.Format))
+	}
+
+	if c.GRPC.Enabled && c.GRPC.Addr == "" {
+		errs = append(errs, errors.New("grpc.addr must not be empty"))
+	}
+
+	if c.Compression.Enabled {
+		if len(c.Compression.Encodings) == 0 {
+			errs = append(errs, errors.New("compression.encodings must not be empty when compression is enabled"))
+		}
+		if _, err := c.Compression.Encoders(); err != nil {
+			errs = append(errs, fmt.Errorf("compression: %w", err))
+		}
+		if c.Compression.MinSize < 0 {
+			errs = append(errs, fmt.Errorf("compression.min_size must not be negative, got %d", c.Compression.MinSize))
+		}
+	}
+
+	if c.WebSocket.MaxMessageBytes <= 0 {
+		errs = append(errs, fmt.Errorf("websocket.max_message_bytes must be positive, got %d", c.WebSocket.MaxMessageBytes))
+	}
+	if c.WebSocket.PingInterval <= 0 {
+		errs = append(errs, fmt.Errorf("websocket.ping_interval must be positive, got %s", c.WebSocket.PingInterval))
+	}
+
+	if c.Hello.MaxBodyBytes <= 0 {
+		errs = append(errs, fmt.Errorf("hello.max_body_bytes must be positive, got %d", c.Hello.MaxBodyBytes))
+	}
+
+	if c.Hello.Batch.MaxItems <= 0 {
+		errs = append(errs, fmt.Errorf("hello.batch.max_items must be positive, got %d", c.Hello.Batch.MaxItems))
+	}
+	if c.Hello.Batch.MaxBodyBytes <= 0 {
+		errs = append(errs, fmt.Errorf("hello.batch.max_body_bytes must be positive, got %d", c.Hello.Batch.MaxBodyBytes))
+	}
+
+	if c.Hello.Stream.Interval <= 0 {
+		errs = append(errs, fmt.Errorf("hello.stream.interval must be positive, got %s", c.Hello.Stream.Interval))
+	}
+	if c.Hello.Stream.Heartbeat <= 0 {
+		errs = append(errs, fmt.Errorf("hello.stream.heartbeat must be positive, got %s", c.Hello.Stream.Heartbeat))
+	}
+	if c.Hello.Stream.Retry < 0 {
+		errs = append(errs, fmt.Errorf("hello.stream.retry must not be negative, got %s", c.Hello.Stream.Retry))
+	}
+
+	if err := c.Hello.Name.Check(); err != nil {
+		errs = append(errs, fmt.Errorf("hello.name: %w", err))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("config: invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// validate checks the credential sources, reading the referenced files so
+// unreadable keys are reported at startup.
+func (c AuthConfig) validate() []error {
+	var errs []error
+
+	if len(c.APIKeys) == 0 && c.APIKeysFile == "" && !c.JWT.Enabled() {
+		errs = append(errs, errors.New("auth: enabled without auth.api_keys, auth.api_keys_file or a JWT key"))
+	}
+	entries := c.APIKeys
+	if c.APIKeysFile != "" {
+		fromFile, err := auth.ReadAPIKeysFile(c.APIKeysFile)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("auth.api_keys_file: %w", err))
+		}
+		entries = append(entries[:len(entries):len(entries)], fromFile...)
+	}
+	if _, err := auth.ParseAPIKeys(entries); err != nil {
+		errs = append(errs, fmt.Errorf("auth.api_keys: %w", err))
+	}
+	if c.JWT.RSAPublicKeyFile != "" {
+		if _, err := auth.ReadRSAPublicKey(c.JWT.RSAPublicKeyFile); err != nil {
+			errs = append(errs, fmt.Errorf("auth.jwt.rsa_public_key_file: %w", err))
+		}
+	}
+	if c.JWT.Leeway < 0 {
+		errs = append(errs, fmt.Errorf("auth.jwt.leeway must not be negative, got %s", c.JWT.Leeway))
+	}
+	return errs
+}
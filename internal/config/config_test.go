@@ -0,0 +1,420 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func envFrom(m map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := m[key]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil, envFrom(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := Default()
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("expected defaults %+v, got %+v", want, cfg)
+	}
+	if cfg.Server.Addr != ":8080" {
+		t.Errorf("expected addr ':8080', got '%s'", cfg.Server.Addr)
+	}
+	if cfg.Server.ShutdownTimeout != 30*time.Second {
+		t.Errorf("expected shutdown timeout 30s, got %s", cfg.Server.ShutdownTimeout)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", `
+server:
+  addr: ":9000"
+  read_timeout: 5s
+  write_timeout: 6s
+log:
+  level: debug
+hello:
+  default_name: File
+`)
+
+	tests := []struct {
+		name   string
+		args   []string
+		env    map[string]string
+		verify func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "file overrides defaults",
+			args: []string{"-config", yamlFile},
+			verify: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Addr != ":9000" {
+					t.Errorf("expected addr ':9000', got '%s'", cfg.Server.Addr)
+				}
+				if cfg.Server.ReadTimeout != 5*time.Second {
+					t.Errorf("expected read timeout 5s, got %s", cfg.Server.ReadTimeout)
+				}
+				if cfg.Server.IdleTimeout != 60*time.Second {
+					t.Errorf("expected idle timeout to keep default 60s, got %s", cfg.Server.IdleTimeout)
+				}
+				if cfg.Hello.DefaultName != "File" {
+					t.Errorf("expected default name 'File', got '%s'", cfg.Hello.DefaultName)
+				}
+			},
+		},
+		{
+			name: "config file selected from environment",
+			env:  map[string]string{"CONFIG_FILE": yamlFile},
+			verify: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Addr != ":9000" {
+					t.Errorf("expected addr ':9000', got '%s'", cfg.Server.Addr)
+				}
+			},
+		},
+		{
+			name: "environment overrides file",
+			args: []string{"-config", yamlFile},
+			env:  map[string]string{"SERVER_ADDR": ":9100", "LOG_LEVEL": "WARN"},
+			verify: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Addr != ":9100" {
+					t.Errorf("expected addr ':9100', got '%s'", cfg.Server.Addr)
+				}
+				if cfg.Log.Level != "warn" {
+					t.Errorf("expected log level 'warn', got '%s'", cfg.Log.Level)
+				}
+				if cfg.Server.WriteTimeout != 6*time.Second {
+					t.Errorf("expected write timeout 6s from file, got %s", cfg.Server.WriteTimeout)
+				}
+			},
+		},
+		{
+			name: "flags override environment",
+			args: []string{"-config", yamlFile, "-server-addr", ":9200", "-hello-max-body-bytes", "2048"},
+			env:  map[string]string{"SERVER_ADDR": ":9100", "HELLO_MAX_BODY_BYTES": "1024"},
+			verify: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Addr != ":9200" {
+					t.Errorf("expected addr ':9200', got '%s'", cfg.Server.Addr)
+				}
+				if cfg.Hello.MaxBodyBytes != 2048 {
+					t.Errorf("expected max body bytes 2048, got %d", cfg.Hello.MaxBodyBytes)
+				}
+			},
+		},
+		{
+			name: "nested batch keys",
+			args: []string{"-hello-batch-max-items", "50"},
+			env:  map[string]string{"HELLO_BATCH_MAX_BODY_BYTES": "65536"},
+			verify: func(t *testing.T, cfg *Config) {
+				if cfg.Hello.Batch.MaxItems != 50 {
+					t.Errorf("expected batch max items 50, got %d", cfg.Hello.Batch.MaxItems)
+				}
+				if cfg.Hello.Batch.MaxBodyBytes != 65536 {
+					t.Errorf("expected batch max body bytes 65536, got %d", cfg.Hello.Batch.MaxBodyBytes)
+				}
+			},
+		},
+		{
+			name: "grpc keys",
+			args: []string{"-grpc-addr", ":9191"},
+			env:  map[string]string{"GRPC_ENABLED": "false"},
+			verify: func(t *testing.T, cfg *Config) {
+				if cfg.GRPC.Addr != ":9191" {
+					t.Errorf("expected grpc addr ':9191', got '%s'", cfg.GRPC.Addr)
+				}
+				if cfg.GRPC.Enabled {
+					t.Error("expected grpc to be disabled")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(tt.args, envFrom(tt.env))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.verify(t, cfg)
+		})
+	}
+}
+
+func TestLoadJSONFile(t *testing.T) {
+	path := writeFile(t, "config.json", `{"server":{"idle_timeout":"2m"},"hello":{"max_body_bytes":4096}}`)
+
+	cfg, err := Load([]string{"-config", path}, envFrom(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Server.IdleTimeout != 2*time.Minute {
+		t.Errorf("expected idle timeout 2m, got %s", cfg.Server.IdleTimeout)
+	}
+	if cfg.Hello.MaxBodyBytes != 4096 {
+		t.Errorf("expected max body bytes 4096, got %d", cfg.Hello.MaxBodyBytes)
+	}
+}
+
+func TestLoadNameRules(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+hello:
+  name:
+    max_length: 20
+    allowed_classes: [letter, space]
+`)
+
+	cfg, err := Load([]string{"-config", path, "-hello-name-normalization", "NFKC"}, envFrom(map[string]string{"HELLO_NAME_STRIP_CONTROL": "false"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rules := cfg.Hello.Name
+	if rules.MaxLength != 20 || rules.Normalization != "nfkc" || rules.StripControl {
+		t.Errorf("unexpected name rules %+v", rules)
+	}
+	if !reflect.DeepEqual(rules.AllowedClasses, []string{"letter", "space"}) {
+		t.Errorf("expected allowed classes [letter space], got %v", rules.AllowedClasses)
+	}
+}
+
+func TestLoadRateLimitDisabledSkipsValidation(t *testing.T) {
+	cfg, err := Load([]string{"-ratelimit-enabled=false", "-ratelimit-rate", "0"}, envFrom(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.RateLimit.Enabled {
+		t.Error("expected rate limiting to be disabled")
+	}
+}
+
+func TestLoadAuth(t *testing.T) {
+	keysFile := writeFile(t, "keys", "ops:"+strings.Repeat("ab", 32)+"\n")
+	path := writeFile(t, "config.yaml", `
+auth:
+  enabled: true
+  api_keys:
+    - CI-Bot:`+strings.Repeat("cd", 32)+`
+  jwt:
+    issuer: https://issuer.example
+    leeway: 1m
+`)
+
+	cfg, err := Load([]string{"-config", path, "-auth-api-keys-file", keysFile}, envFrom(map[string]string{"AUTH_JWT_HMAC_SECRET": "shh"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := cfg.Auth
+	if !a.Enabled || len(a.APIKeys) != 1 || a.APIKeysFile != keysFile {
+		t.Errorf("unexpected auth config %+v", a)
+	}
+	if !strings.HasPrefix(a.APIKeys[0], "CI-Bot:") {
+		t.Errorf("expected API key names to keep their case, got %q", a.APIKeys[0])
+	}
+	if a.JWT.HMACSecret != "shh" || a.JWT.Issuer != "https://issuer.example" || a.JWT.Leeway != time.Minute {
+		t.Errorf("unexpected JWT config %+v", a.JWT)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		file        string
+		fileName    string
+		expectedErr string
+	}{
+		{
+			name:        "invalid duration in environment",
+			env:         map[string]string{"SERVER_READ_TIMEOUT": "soon"},
+			expectedErr: "SERVER_READ_TIMEOUT",
+		},
+		{
+			name:        "invalid integer flag",
+			args:        []string{"-hello-max-body-bytes", "lots"},
+			expectedErr: "-hello-max-body-bytes",
+		},
+		{
+			name:        "unknown log level",
+			env:         map[string]string{"LOG_LEVEL": "verbose"},
+			expectedErr: "log.level",
+		},
+		{
+			name:        "unknown log format",
+			args:        []string{"-log-format", "xml"},
+			expectedErr: "log.format",
+		},
+		{
+			name:        "non-positive timeout",
+			args:        []string{"-server-shutdown-timeout", "0s"},
+			expectedErr: "server.shutdown_timeout must be positive",
+		},
+		{
+			name:        "negative drain delay",
+			env:         map[string]string{"SERVER_DRAIN_DELAY": "-1s"},
+			expectedErr: "server.drain_delay must not be negative",
+		},
+		{
+			name:        "unknown tracing exporter",
+			env:         map[string]string{"TRACING_EXPORTER": "jaeger"},
+			expectedErr: "tracing.exporter",
+		},
+		{
+			name:        "relative OTLP endpoint",
+			env:         map[string]string{"TRACING_EXPORTER": "otlp", "TRACING_OTLP_ENDPOINT": "collector:4318"},
+			expectedErr: "tracing.otlp_endpoint",
+		},
+		{
+			name:        "sample ratio out of range",
+			args:        []string{"-tracing-sample-ratio", "1.5"},
+			expectedErr: "tracing.sample_ratio",
+		},
+		{
+			name:        "unknown character class",
+			env:         map[string]string{"HELLO_NAME_ALLOWED_CLASSES": "letter,emoji"},
+			expectedErr: `hello.name: unknown character class "emoji"`,
+		},
+		{
+			name:        "invalid trusted proxy",
+			env:         map[string]string{"RATELIMIT_TRUSTED_PROXIES": "10.0.0.0/8,not-an-ip"},
+			expectedErr: `ratelimit.trusted_proxies: invalid IP address "not-an-ip"`,
+		},
+		{
+			name:        "unknown rate limit key",
+			args:        []string{"-ratelimit-key", "user"},
+			expectedErr: "ratelimit.key",
+		},
+		{
+			name:        "auth without credentials",
+			args:        []string{"-auth-enabled=true"},
+			expectedErr: "auth: enabled without",
+		},
+		{
+			name:        "malformed API key entry",
+			env:         map[string]string{"AUTH_ENABLED": "true", "AUTH_API_KEYS": "ci"},
+			expectedErr: "auth.api_keys",
+		},
+		{
+			name:        "missing API keys file",
+			env:         map[string]string{"AUTH_ENABLED": "true", "AUTH_API_KEYS_FILE": "/does/not/exist"},
+			expectedErr: "auth.api_keys_file",
+		},
+		{
+			name:        "missing RSA public key",
+			env:         map[string]string{"AUTH_ENABLED": "true", "AUTH_JWT_RSA_PUBLIC_KEY_FILE": "/does/not/exist.pem"},
+			expectedErr: "auth.jwt.rsa_public_key_file",
+		},
+		{
+			name:        "CORS without origins",
+			env:         map[string]string{"CORS_ENABLED": "true"},
+			expectedErr: "cors.allowed_origins must not be empty",
+		},
+		{
+			name:        "CORS credentials for any origin",
+			args:        []string{"-cors-enabled=true", "-cors-allowed-origins", "*", "-cors-allow-credentials=true"},
+			expectedErr: "cors: credentials cannot be allowed for every origin",
+		},
+		{
+			name:        "unknown error format",
+			env:         map[string]string{"ERRORS_FORMAT": "html"},
+			expectedErr: "errors.format",
+		},
+		{
+			name:        "unknown compression encoding",
+			env:         map[string]string{"COMPRESSION_ENCODINGS": "gzip,br"},
+			expectedErr: `compression: unsupported encoding "br"`,
+		},
+		{
+			name:        "compression level out of range",
+			args:        []string{"-compression-level", "12"},
+			expectedErr: "compression: gzip: invalid compression level: 12",
+		},
+		{
+			name:        "non-positive batch size",
+			env:         map[string]string{"HELLO_BATCH_MAX_ITEMS": "0"},
+			expectedErr: "hello.batch.max_items must be positive, got 0",
+		},
+		{
+			name:        "non-positive stream interval",
+			args:        []string{"-hello-stream-interval", "0s"},
+			expectedErr: "hello.stream.interval must be positive, got 0s",
+		},
+		{
+			name:        "non-positive websocket ping interval",
+			env:         map[string]string{"WEBSOCKET_PING_INTERVAL": "-1s"},
+			expectedErr: "websocket.ping_interval must be positive, got -1s",
+		},
+		{
+			name:        "empty grpc address",
+			args:        []string{"-grpc-addr", ""},
+			expectedErr: "grpc.addr must not be empty",
+		},
+		{
+			name:        "unknown key in file",
+			file:        "server:\n  port: 8080\n",
+			fileName:    "config.yaml",
+			expectedErr: `unknown key "server.port"`,
+		},
+		{
+			name:        "unsupported file extension",
+			file:        "addr = ':8080'",
+			fileName:    "config.toml",
+			expectedErr: "unsupported config file extension",
+		},
+		{
+			name:        "missing file",
+			args:        []string{"-config", "/does/not/exist.yaml"},
+			expectedErr: "no such file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append(args, "-config", writeFile(t, tt.fileName, tt.file))
+			}
+
+			_, err := Load(args, envFrom(tt.env))
+			if err == nil {
+				t.Fatal("expected an error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("expected error containing '%s', got '%v'", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Addr = ""
+	cfg.Hello.MaxBodyBytes = -1
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	for _, want := range []string{"server.addr", "hello.max_body_bytes"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention '%s', got '%v'", want, err)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package cors implements Cross-Origin Resource Sharing: it answers preflight
+// requests and decorates actual responses with Access-Control-* headers
+// according to a configured policy.
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options describe a CORS policy.
+type Options struct {
+	// AllowedOrigins lists exact origins ("https://app.example.com"),
+	// wildcard subdomains ("https://*.example.com") or "*" for any origin.
+	AllowedOrigins []string
+	AllowedMethods []string
+	// AllowedHeaders lists request headers a preflight may ask for; "*"
+	// allows any header.
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight result. Zero omits
+	// Access-Control-Max-Age.
+	MaxAge time.Duration
+}
+
+// Policy is a validated CORS policy.
+type Policy struct {
+	anyOrigin        bool
+	origins          []string
+	suffixes         []originSuffix
+	methods          []string
+	anyHeader        bool
+	headers          []string
+	allowMethods     string
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+// originSuffix matches subdomains of a wildcard origin pattern.
+type originSuffix struct {
+	scheme string
+	suffix string // ".example.com", optionally followed by ":port"
+}
+
+// New validates opts and returns the policy they describe.
+func New(opts Options) (*Policy, error) {
+	p := &Policy{allowCredentials: opts.AllowCredentials}
+
+	for _, origin := range opts.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		if origin == "*" {
+			p.anyOrigin = true
+			continue
+		}
+		scheme, host, ok := strings.Cut(origin, "://")
+		if !ok || scheme == "" || host == "" || strings.ContainsAny(host, "/?#") {
+			return nil, fmt.Errorf("invalid origin %q", origin)
+		}
+		if rest, wildcard := strings.CutPrefix(host, "*."); wildcard {
+			if rest == "" || strings.Contains(rest, "*") {
+				return nil, fmt.Errorf("invalid origin %q", origin)
+			}
+			p.suffixes = append(p.suffixes, originSuffix{scheme: scheme, suffix: "." + rest})
+			continue
+		}
+		if strings.Contains(host, "*") {
+			return nil, fmt.Errorf("invalid origin %q: wildcards are only allowed as the leftmost label", origin)
+		}
+		p.origins = append(p.origins, origin)
+	}
+	if p.anyOrigin && p.allowCredentials {
+		return nil, errors.New("credentials cannot be allowed for every origin")
+	}
+
+	for _, method := range opts.AllowedMethods {
+		p.methods = append(p.methods, strings.ToUpper(strings.TrimSpace(method)))
+	}
+	p.allowMethods = strings.Join(p.methods, ", ")
+
+	for _, header := range opts.AllowedHeaders {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header == "*" {
+			p.anyHeader = true
+			continue
+		}
+		p.headers = append(p.headers, header)
+	}
+
+	exposed := make([]string, len(opts.ExposedHeaders))
+	for i, header := range opts.ExposedHeaders {
+		exposed[i] = http.CanonicalHeaderKey(strings.TrimSpace(header))
+	}
+	p.exposeHeaders = strings.Join(exposed, ", ")
+
+	if opts.MaxAge < 0 {
+		return nil, fmt.Errorf("max age must not be negative, got %s", opts.MaxAge)
+	}
+	if opts.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(opts.MaxAge / time.Second))
+	}
+	return p, nil
+}
+
+// AllowsOrigin reports whether origin may access the server.
+func (p *Policy) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if slices.Contains(p.origins, origin) {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, s := range p.suffixes {
+		if u.Scheme == s.scheme && strings.HasSuffix(u.Host, s.suffix) && len(u.Host) > len(s.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) allowsHeaders(requested string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header != "" && !slices.Contains(p.headers, header) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin.
+// Credentialed policies must echo the origin rather than use "*".
+func (p *Policy) allowOrigin(origin string) string {
+	if p.anyOrigin {
+		return "*"
+	}
+	return origin
+}
+
+// Middleware answers preflight requests itself and adds CORS headers to
+// every other response whose Origin is allowed.
+func Middleware(p *Policy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		header := w.Header()
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			header.Add("Vary", "Origin")
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+
+			method := r.Header.Get("Access-Control-Request-Method")
+			requested := r.Header.Get("Access-Control-Request-Headers")
+			if p.AllowsOrigin(origin) && slices.Contains(p.methods, method) && p.allowsHeaders(requested) {
+				header.Set("Access-Control-Allow-Origin", p.allowOrigin(origin))
+				header.Set("Access-Control-Allow-Methods", p.allowMethods)
+				if requested != "" {
+					header.Set("Access-Control-Allow-Headers", requested)
+				}
+				if p.allowCredentials {
+					header.Set("Access-Control-Allow-Credentials", "true")
+				}
+				if p.maxAge != "" {
+					header.Set("Access-Control-Max-Age", p.maxAge)
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if !p.anyOrigin {
+			header.Add("Vary", "Origin")
+		}
+		if p.AllowsOrigin(origin) {
+			header.Set("Access-Control-Allow-Origin", p.allowOrigin(origin))
+			if p.allowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if p.exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", p.exposeHeaders)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,213 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        Options
+		expectedErr string
+	}{
+		{name: "missing scheme", opts: Options{AllowedOrigins: []string{"example.com"}}, expectedErr: "invalid origin"},
+		{name: "origin with path", opts: Options{AllowedOrigins: []string{"https://example.com/app"}}, expectedErr: "invalid origin"},
+		{name: "inner wildcard", opts: Options{AllowedOrigins: []string{"https://api.*.example.com"}}, expectedErr: "leftmost label"},
+		{name: "bare wildcard host", opts: Options{AllowedOrigins: []string{"https://*."}}, expectedErr: "invalid origin"},
+		{name: "credentials for any origin", opts: Options{AllowedOrigins: []string{"*"}, AllowCredentials: true}, expectedErr: "credentials"},
+		{name: "negative max age", opts: Options{AllowedOrigins: []string{"*"}, MaxAge: -time.Second}, expectedErr: "max age"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("expected error containing '%s', got '%v'", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestAllowsOrigin(t *testing.T) {
+	p, err := New(Options{AllowedOrigins: []string{"https://app.example.com", "https://*.example.org", "http://*.local.test:3000"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		origin   string
+		expected bool
+	}{
+		{"https://app.example.com", true},
+		{"HTTPS://APP.EXAMPLE.COM", true},
+		{"http://app.example.com", false},
+		{"https://evil.example.com", false},
+		{"https://a.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://evilexample.org", false},
+		{"http://a.example.org", false},
+		{"http://web.local.test:3000", true},
+		{"http://web.local.test:4000", false},
+		{"null", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := p.AllowsOrigin(tt.origin); got != tt.expected {
+			t.Errorf("AllowsOrigin(%q): expected %v, got %v", tt.origin, tt.expected, got)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	p, err := New(Options{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedMethods:   []string{"get", "post"},
+		AllowedHeaders:   []string{"Content-Type", "X-Request-ID"},
+		ExposedHeaders:   []string{"x-request-id"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		method          string
+		headers         map[string]string
+		expectedStatus  int
+		expectedHeaders map[string]string
+		expectNext      bool
+	}{
+		{
+			name:   "preflight allowed",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "content-type, x-request-id",
+			},
+			expectedStatus: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Methods":     "GET, POST",
+				"Access-Control-Allow-Headers":     "content-type, x-request-id",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+		{
+			name:   "preflight with disallowed method",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://app.example.com",
+				"Access-Control-Request-Method": "DELETE",
+			},
+			expectedStatus:  http.StatusNoContent,
+			expectedHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:   "preflight with disallowed header",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  "GET",
+				"Access-Control-Request-Headers": "X-Secret",
+			},
+			expectedStatus:  http.StatusNoContent,
+			expectedHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:   "preflight from unknown origin",
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://evil.test",
+				"Access-Control-Request-Method": "GET",
+			},
+			expectedStatus:  http.StatusNoContent,
+			expectedHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name:           "actual request",
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://app.example.com"},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Request-Id",
+				"Vary":                             "Origin",
+			},
+			expectNext: true,
+		},
+		{
+			name:            "actual request from unknown origin",
+			method:          http.MethodGet,
+			headers:         map[string]string{"Origin": "https://evil.test"},
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+			expectNext:      true,
+		},
+		{
+			name:            "plain OPTIONS is not a preflight",
+			method:          http.MethodOptions,
+			headers:         map[string]string{"Origin": "https://app.example.com"},
+			expectedStatus:  http.StatusOK,
+			expectedHeaders: map[string]string{"Access-Control-Allow-Origin": "https://app.example.com"},
+			expectNext:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			h := Middleware(p, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			req := httptest.NewRequest(tt.method, "/hello", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if called != tt.expectNext {
+				t.Errorf("expected next handler called %v, got %v", tt.expectNext, called)
+			}
+			for k, v := range tt.expectedHeaders {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("expected %s '%s', got '%s'", k, v, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareAnyOrigin(t *testing.T) {
+	p, _ := New(Options{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, AllowedHeaders: []string{"*"}})
+	h := Middleware(p, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/hello", nil)
+	req.Header.Set("Origin", "https://anywhere.test")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	req.Header.Set("Access-Control-Request-Headers", "X-Anything")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected Access-Control-Allow-Origin '*', got '%s'", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "X-Anything" {
+		t.Errorf("expected Access-Control-Allow-Headers 'X-Anything', got '%s'", got)
+	}
+}
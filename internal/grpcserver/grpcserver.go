@@ -0,0 +1,123 @@
+// Package grpcserver provides the interceptors that give gRPC calls the same
+// request IDs and structured request logs as HTTP requests.
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"hello-api/internal/logging"
+	"hello-api/internal/requestid"
+)
+
+// RequestIDKey is the metadata key that carries the request ID, the gRPC
+// spelling of requestid.Header.
+const RequestIDKey = "x-request-id"
+
+// UnaryRequestID adopts a valid incoming request ID or generates a new one,
+// stores it in the call context and returns it in the response header
+// metadata.
+func UnaryRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+// StreamRequestID is UnaryRequestID for streaming calls.
+func StreamRequestID() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+	}
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDKey); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+	// Fails only outside a real server call, as in unit tests.
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, id))
+	return requestid.WithID(ctx, id)
+}
+
+// UnaryLogging stores a request-scoped logger in the call context and logs
+// every completed call, like the HTTP logging middleware. Panics are
+// recovered, logged and returned as INTERNAL. It must run after
+// UnaryRequestID so the logger carries the request ID.
+func UnaryLogging(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ctx, done := startCall(ctx, logger, info.FullMethod)
+		defer func() { err = done(recover(), err) }()
+		return handler(ctx, req)
+	}
+}
+
+// StreamLogging is UnaryLogging for streaming calls.
+func StreamLogging(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx, done := startCall(ss.Context(), logger, info.FullMethod)
+		defer func() { err = done(recover(), err) }()
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// startCall attaches a request logger to ctx. The returned function logs the
+// outcome and converts a recovered panic into an INTERNAL error.
+func startCall(ctx context.Context, logger *slog.Logger, method string) (context.Context, func(panicked interface{}, err error) error) {
+	start := time.Now()
+	reqLogger := logger.With(slog.String("request_id", requestid.FromContext(ctx)))
+	ctx = logging.WithLogger(ctx, reqLogger)
+
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+
+	return ctx, func(panicked interface{}, err error) error {
+		if panicked != nil {
+			reqLogger.Error("panic recovered", slog.Any("panic", panicked), slog.String("method", method))
+			err = status.Error(codes.Internal, "Internal Server Error")
+		}
+
+		code := status.Code(err)
+		reqLogger.LogAttrs(ctx, Level(code), "rpc completed",
+			slog.String("method", method),
+			slog.String("code", code.String()),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote_addr", remoteAddr),
+		)
+		return err
+	}
+}
+
+// Level maps a status code to the log level of the HTTP status it
+// corresponds to: error for server failures, warn for client errors.
+func Level(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK:
+		return slog.LevelInfo
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return slog.LevelError
+	}
+	return slog.LevelWarn
+}
+
+// serverStream overrides the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
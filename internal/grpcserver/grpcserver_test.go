@@ -0,0 +1,177 @@
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"hello-api/internal/logging"
+	"hello-api/internal/requestid"
+)
+
+var info = &grpc.UnaryServerInfo{FullMethod: "/hello.v1.HelloService/Ping"}
+
+func TestUnaryRequestID(t *testing.T) {
+	tests := []struct {
+		name       string
+		incoming   string
+		expectSame bool
+	}{
+		{name: "generated", incoming: ""},
+		{name: "adopted", incoming: "gateway-1234", expectSame: true},
+		{name: "invalid replaced", incoming: "has space"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.incoming != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(RequestIDKey, tt.incoming))
+			}
+
+			var got string
+			UnaryRequestID()(ctx, nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+				got = requestid.FromContext(ctx)
+				return nil, nil
+			})
+
+			if tt.expectSame && got != tt.incoming {
+				t.Errorf("expected request ID '%s', got '%s'", tt.incoming, got)
+			}
+			if !tt.expectSame && len(got) != 26 {
+				t.Errorf("expected a generated request ID, got '%s'", got)
+			}
+		})
+	}
+}
+
+func TestUnaryLogging(t *testing.T) {
+	tests := []struct {
+		name          string
+		handler       grpc.UnaryHandler
+		expectedCode  codes.Code
+		expectedLevel string
+	}{
+		{
+			name:          "ok",
+			handler:       func(context.Context, interface{}) (interface{}, error) { return "pong", nil },
+			expectedCode:  codes.OK,
+			expectedLevel: "INFO",
+		},
+		{
+			name: "client error",
+			handler: func(context.Context, interface{}) (interface{}, error) {
+				return nil, status.Error(codes.InvalidArgument, "bad")
+			},
+			expectedCode:  codes.InvalidArgument,
+			expectedLevel: "WARN",
+		},
+		{
+			name:          "panic",
+			handler:       func(context.Context, interface{}) (interface{}, error) { panic("boom") },
+			expectedCode:  codes.Internal,
+			expectedLevel: "ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, nil))
+			ctx := requestid.WithID(context.Background(), "req-1")
+
+			var handlerLogger *slog.Logger
+			_, err := UnaryLogging(logger)(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				handlerLogger = logging.FromContext(ctx)
+				return tt.handler(ctx, req)
+			})
+
+			if code := status.Code(err); code != tt.expectedCode {
+				t.Errorf("expected code %s, got %s", tt.expectedCode, code)
+			}
+			if handlerLogger == nil || handlerLogger == slog.Default() {
+				t.Error("expected the handler to receive a request logger")
+			}
+
+			// A panic logs the recovery before the completed call.
+			lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+			var entry map[string]interface{}
+			if err := json.Unmarshal(lines[len(lines)-1], &entry); err != nil {
+				t.Fatalf("failed to decode log entry: %v", err)
+			}
+			for key, want := range map[string]interface{}{
+				"msg":        "rpc completed",
+				"level":      tt.expectedLevel,
+				"method":     info.FullMethod,
+				"code":       tt.expectedCode.String(),
+				"request_id": "req-1",
+			} {
+				if entry[key] != want {
+					t.Errorf("expected %s %v, got %v", key, want, entry[key])
+				}
+			}
+		})
+	}
+}
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		code  codes.Code
+		level slog.Level
+	}{
+		{codes.OK, slog.LevelInfo},
+		{codes.NotFound, slog.LevelWarn},
+		{codes.Unauthenticated, slog.LevelWarn},
+		{codes.Internal, slog.LevelError},
+		{codes.Unavailable, slog.LevelError},
+	}
+
+	for _, tt := range tests {
+		if got := Level(tt.code); got != tt.level {
+			t.Errorf("Level(%s) = %s, want %s", tt.code, got, tt.level)
+		}
+	}
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDKey, "req-2"))
+	ss := &fakeStream{ctx: ctx}
+	sinfo := &grpc.StreamServerInfo{FullMethod: "/grpc.health.v1.Health/Watch"}
+
+	var gotID string
+	var gotLogger *slog.Logger
+	handler := func(_ interface{}, ss grpc.ServerStream) error {
+		gotID = requestid.FromContext(ss.Context())
+		gotLogger = logging.FromContext(ss.Context())
+		return errors.New("stream failed")
+	}
+	chained := func(srv interface{}, ss grpc.ServerStream) error {
+		return StreamLogging(logging.Discard())(srv, ss, sinfo, handler)
+	}
+
+	err := StreamRequestID()(nil, ss, sinfo, chained)
+	if status.Code(err) != codes.Unknown {
+		t.Errorf("expected the handler error to pass through, got %v", err)
+	}
+	if gotID != "req-2" {
+		t.Errorf("expected request ID 'req-2', got '%s'", gotID)
+	}
+	if gotLogger == slog.Default() {
+		t.Error("expected the handler to receive a request logger")
+	}
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,216 @@
+// Package health implements named health checks with per-check timeouts and
+// result caching, served as JSON for liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status values reported for individual checks and whole registries.
+const (
+	StatusHealthy   = "healthy"
+	StatusUnhealthy = "unhealthy"
+)
+
+// DefaultTimeout bounds a check registered without an explicit timeout.
+const DefaultTimeout = 2 * time.Second
+
+// Checker reports whether a dependency is healthy by returning nil.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to the Checker interface.
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx).
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Option configures a registered check.
+type Option func(*check)
+
+// WithTimeout bounds how long a single run of the check may take.
+func WithTimeout(d time.Duration) Option {
+	return func(c *check) { c.timeout = d }
+}
+
+// WithCacheTTL reuses a check's last result for d, so frequent probes do not
+// hammer expensive dependencies.
+func WithCacheTTL(d time.Duration) Option {
+	return func(c *check) { c.ttl = d }
+}
+
+type check struct {
+	name    string
+	checker Checker
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu       sync.Mutex
+	last     CheckResult
+	lastTime time.Time
+}
+
+// CheckResult is the outcome of a single check.
+type CheckResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+	Cached     bool    `json:"cached,omitempty"`
+}
+
+// Report is the JSON body served by Registry.Handler.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Registry is a set of named checks evaluated together.
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[string]*check
+	now    func() time.Time
+}
+
+// NewRegistry returns an empty Registry, which reports healthy.
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[string]*check), now: time.Now}
+}
+
+// Register adds a named check, replacing any check with the same name.
+func (r *Registry) Register(name string, checker Checker, opts ...Option) {
+	c := &check{name: name, checker: checker, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks[name] = c
+}
+
+// Run evaluates every check concurrently and returns the combined report.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := make([]*check, 0, len(r.checks))
+	for _, c := range r.checks {
+		checks = append(checks, c)
+	}
+	r.mu.RUnlock()
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = r.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusHealthy, Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusHealthy {
+			report.Status = StatusUnhealthy
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c *check) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ttl > 0 && !c.lastTime.IsZero() && r.now().Sub(c.lastTime) < c.ttl {
+		cached := c.last
+		cached.Cached = true
+		return cached
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := r.now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- c.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", c.timeout)
+		}
+	}
+
+	result := CheckResult{
+		Status:     StatusHealthy,
+		DurationMS: float64(r.now().Sub(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusUnhealthy
+		result.Error = err.Error()
+	}
+
+	c.last = result
+	c.lastTime = r.now()
+	return result
+}
+
+// Handler serves the registry's report, with 503 Service Unavailable when
+// any check fails.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Run(req.Context())
+
+		code := http.StatusOK
+		if report.Status != StatusHealthy {
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(report)
+	})
+}
+
+// ShutdownGate is a readiness check that starts failing once Drain is called,
+// so load balancers stop routing new traffic before the server shuts down.
+type ShutdownGate struct {
+	draining atomic.Bool
+}
+
+// Drain makes every subsequent Check fail.
+func (g *ShutdownGate) Drain() {
+	g.draining.Store(true)
+}
+
+// Check implements Checker.
+func (g *ShutdownGate) Check(context.Context) error {
+	if g.draining.Load() {
+		return errors.New("server is shutting down")
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegistryRun(t *testing.T) {
+	tests := []struct {
+		name           string
+		checks         map[string]Checker
+		expectedStatus string
+		validate       func(t *testing.T, report Report)
+	}{
+		{
+			name:           "empty registry is healthy",
+			checks:         nil,
+			expectedStatus: StatusHealthy,
+		},
+		{
+			name: "all checks pass",
+			checks: map[string]Checker{
+				"db":    CheckerFunc(func(context.Context) error { return nil }),
+				"cache": CheckerFunc(func(context.Context) error { return nil }),
+			},
+			expectedStatus: StatusHealthy,
+			validate: func(t *testing.T, report Report) {
+				if len(report.Checks) != 2 {
+					t.Errorf("expected 2 check results, got %d", len(report.Checks))
+				}
+			},
+		},
+		{
+			name: "one failing check fails the registry",
+			checks: map[string]Checker{
+				"db":    CheckerFunc(func(context.Context) error { return nil }),
+				"cache": CheckerFunc(func(context.Context) error { return errors.New("connection refused") }),
+			},
+			expectedStatus: StatusUnhealthy,
+			validate: func(t *testing.T, report Report) {
+				if report.Checks["db"].Status != StatusHealthy {
+					t.Errorf("expected db to be healthy, got %+v", report.Checks["db"])
+				}
+				if report.Checks["cache"].Error != "connection refused" {
+					t.Errorf("expected cache error detail, got %+v", report.Checks["cache"])
+				}
+			},
+		},
+		{
+			name: "panicking check is reported as unhealthy",
+			checks: map[string]Checker{
+				"broken": CheckerFunc(func(context.Context) error { panic("boom") }),
+			},
+			expectedStatus: StatusUnhealthy,
+			validate: func(t *testing.T, report Report) {
+				if !strings.Contains(report.Checks["broken"].Error, "boom") {
+					t.Errorf("expected panic detail, got %+v", report.Checks["broken"])
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := NewRegistry()
+			for name, c := range tt.checks {
+				reg.Register(name, c)
+			}
+
+			report := reg.Run(context.Background())
+			if report.Status != tt.expectedStatus {
+				t.Errorf("expected status %s, got %s", tt.expectedStatus, report.Status)
+			}
+			if tt.validate != nil {
+				tt.validate(t, report)
+			}
+		})
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		select {
+		case <-time.After(time.Second):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}), WithTimeout(10*time.Millisecond))
+
+	report := reg.Run(context.Background())
+	if report.Status != StatusUnhealthy {
+		t.Errorf("expected timed out check to be unhealthy, got %s", report.Status)
+	}
+	if !strings.Contains(report.Checks["slow"].Error, "timed out") {
+		t.Errorf("expected timeout error, got %+v", report.Checks["slow"])
+	}
+}
+
+func TestCheckCaching(t *testing.T) {
+	var calls atomic.Int32
+	now := time.Unix(1700000000, 0)
+
+	reg := NewRegistry()
+	reg.now = func() time.Time { return now }
+	reg.Register("counted", CheckerFunc(func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}), WithCacheTTL(5*time.Second))
+
+	first := reg.Run(context.Background())
+	second := reg.Run(context.Background())
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 call within TTL, got %d", calls.Load())
+	}
+	if first.Checks["counted"].Cached || !second.Checks["counted"].Cached {
+		t.Errorf("expected only the second result to be cached, got %+v and %+v", first.Checks["counted"], second.Checks["counted"])
+	}
+
+	now = now.Add(6 * time.Second)
+	reg.Run(context.Background())
+	if calls.Load() != 2 {
+		t.Errorf("expected check to rerun after TTL, got %d calls", calls.Load())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	gate := &ShutdownGate{}
+	reg := NewRegistry()
+	reg.Register("shutdown", gate)
+
+	serve := func() (*httptest.ResponseRecorder, Report) {
+		rec := httptest.NewRecorder()
+		reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var report Report
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatalf("failed to decode report: %v", err)
+		}
+		return rec, report
+	}
+
+	rec, report := serve()
+	if rec.Code != http.StatusOK || report.Status != StatusHealthy {
+		t.Errorf("expected 200 healthy before drain, got %d %s", rec.Code, report.Status)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected Content-Type 'application/json', got '%s'", ct)
+	}
+
+	gate.Drain()
+
+	rec, report = serve()
+	if rec.Code != http.StatusServiceUnavailable || report.Status != StatusUnhealthy {
+		t.Errorf("expected 503 unhealthy after drain, got %d %s", rec.Code, report.Status)
+	}
+	if report.Checks["shutdown"].Error != "server is shutting down" {
+		t.Errorf("expected shutdown detail, got %+v", report.Checks["shutdown"])
+	}
+}
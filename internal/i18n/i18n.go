@@ -0,0 +1,248 @@
+// Package i18n provides localized greetings from an embedded message catalog,
+// including Accept-Language negotiation and plural and formality variants.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed locales/*.json
+var embedded embed.FS
+
+// DefaultLocale is used when negotiation finds no supported locale.
+const DefaultLocale = "en"
+
+// Formality selects the register of a message.
+type Formality string
+
+// Supported formality variants.
+const (
+	Informal Formality = "informal"
+	Formal   Formality = "formal"
+)
+
+// ParseFormality maps user input to a Formality, defaulting to Informal.
+func ParseFormality(s string) Formality {
+	if Formality(strings.ToLower(strings.TrimSpace(s))) == Formal {
+		return Formal
+	}
+	return Informal
+}
+
+// Plural categories used as message variant keys.
+const (
+	pluralOne   = "one"
+	pluralOther = "other"
+)
+
+// localeFile is the on-disk format of a catalog entry.
+type localeFile struct {
+	Locale     string `json:"locale"`
+	PluralRule string `json:"plural_rule"`
+	World      string `json:"world"`
+	List       struct {
+		Separator string `json:"separator"`
+		Last      string `json:"last"`
+	} `json:"list"`
+	Greeting map[Formality]map[string]string `json:"greeting"`
+}
+
+// Catalog holds the messages for every supported locale.
+type Catalog struct {
+	locales map[string]*localeFile
+}
+
+// Default returns the catalog embedded in the binary.
+func Default() *Catalog {
+	c, err := Load(embedded)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Load reads every locales/*.json file from fsys.
+func Load(fsys fs.FS) (*Catalog, error) {
+	files, err := fs.Glob(fsys, "locales/*.json")
+	if err != nil {
+		return nil, fmt.Errorf("i18n: %w", err)
+	}
+
+	c := &Catalog{locales: make(map[string]*localeFile, len(files))}
+	for _, name := range files {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("i18n: %w", err)
+		}
+
+		var lf localeFile
+		if err := json.Unmarshal(data, &lf); err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", name, err)
+		}
+		if want := strings.TrimSuffix(path.Base(name), ".json"); lf.Locale != want {
+			return nil, fmt.Errorf("i18n: %s: locale %q does not match file name", name, lf.Locale)
+		}
+		if lf.Greeting[Informal][pluralOther] == "" {
+			return nil, fmt.Errorf("i18n: %s: missing informal \"other\" greeting", name)
+		}
+		lf.Locale = strings.ToLower(lf.Locale)
+		c.locales[lf.Locale] = &lf
+	}
+
+	if _, ok := c.locales[DefaultLocale]; !ok {
+		return nil, fmt.Errorf("i18n: catalog has no %q locale", DefaultLocale)
+	}
+	return c, nil
+}
+
+// Locales returns the supported locale tags, sorted.
+func (c *Catalog) Locales() []string {
+	tags := make([]string, 0, len(c.locales))
+	for tag := range c.locales {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Negotiate picks a supported locale. An explicit lang wins; otherwise the
+// Accept-Language header is consulted in quality order; otherwise
+// DefaultLocale is returned.
+func (c *Catalog) Negotiate(lang, acceptLanguage string) string {
+	if locale, ok := c.match(lang); ok {
+		return locale
+	}
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			return DefaultLocale
+		}
+		if locale, ok := c.match(tag); ok {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// match finds tag in the catalog, falling back from a regional tag such as
+// es-MX to its base language.
+func (c *Catalog) match(tag string) (string, bool) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if tag == "" {
+		return "", false
+	}
+	if _, ok := c.locales[tag]; ok {
+		return tag, true
+	}
+	if base, _, found := strings.Cut(tag, "-"); found {
+		if _, ok := c.locales[base]; ok {
+			return base, true
+		}
+	}
+	return "", false
+}
+
+// World returns the localized word used when no name is given.
+func (c *Catalog) World(locale string) string {
+	return c.lookup(locale).World
+}
+
+// Greeting returns the greeting for names in locale. Names are joined with
+// the locale's list separators, and the plural variant follows the number of
+// names. Missing formal variants fall back to informal ones.
+func (c *Catalog) Greeting(locale string, names []string, formality Formality) string {
+	lf := c.lookup(locale)
+
+	variants := lf.Greeting[formality]
+	if variants == nil {
+		variants = lf.Greeting[Informal]
+	}
+	template := variants[lf.plural(len(names))]
+	if template == "" {
+		template = variants[pluralOther]
+	}
+	if template == "" {
+		template = lf.Greeting[Informal][pluralOther]
+	}
+
+	return strings.ReplaceAll(template, "{name}", lf.join(names))
+}
+
+func (c *Catalog) lookup(locale string) *localeFile {
+	if lf, ok := c.locales[locale]; ok {
+		return lf
+	}
+	return c.locales[DefaultLocale]
+}
+
+// plural returns the CLDR plural category for n under the locale's rule.
+func (lf *localeFile) plural(n int) string {
+	switch lf.PluralRule {
+	case "none":
+		return pluralOther
+	case "zero_one":
+		if n <= 1 {
+			return pluralOne
+		}
+	default:
+		if n == 1 {
+			return pluralOne
+		}
+	}
+	return pluralOther
+}
+
+func (lf *localeFile) join(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], lf.List.Separator) + lf.List.Last + names[len(names)-1]
+}
+
+// parseAcceptLanguage returns the language ranges of an Accept-Language
+// header ordered by quality, dropping ranges with q=0.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var ranges []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, weighted{tag: tag, q: q})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	tags := make([]string, len(ranges))
+	for i, r := range ranges {
+		tags[i] = r.tag
+	}
+	return tags
+}
@@ -0,0 +1,151 @@
+package i18n
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNegotiate(t *testing.T) {
+	c := Default()
+
+	tests := []struct {
+		name           string
+		lang           string
+		acceptLanguage string
+		expected       string
+	}{
+		{name: "default without preferences", expected: "en"},
+		{name: "explicit lang", lang: "de", expected: "de"},
+		{name: "explicit lang wins over header", lang: "fr", acceptLanguage: "es", expected: "fr"},
+		{name: "regional lang falls back to base", lang: "es-MX", expected: "es"},
+		{name: "underscore separator", lang: "pt_BR", acceptLanguage: "ja", expected: "ja"},
+		{name: "unsupported lang uses header", lang: "xx", acceptLanguage: "de-DE", expected: "de"},
+		{name: "header quality order", acceptLanguage: "fr;q=0.5, es;q=0.9, en;q=0.1", expected: "es"},
+		{name: "header skips unsupported", acceptLanguage: "zh-CN, ja;q=0.8", expected: "ja"},
+		{name: "header ignores q=0", acceptLanguage: "de;q=0, fr;q=0.2", expected: "fr"},
+		{name: "wildcard selects default", acceptLanguage: "zh, *;q=0.5", expected: "en"},
+		{name: "malformed header", acceptLanguage: ";;,q=", expected: "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Negotiate(tt.lang, tt.acceptLanguage); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGreeting(t *testing.T) {
+	c := Default()
+
+	tests := []struct {
+		locale    string
+		names     []string
+		formality Formality
+		expected  string
+	}{
+		{"en", []string{"Alice"}, Informal, "Hello, Alice!"},
+		{"en", []string{"Alice", "Bob", "Carol"}, Informal, "Hello, Alice, Bob and Carol!"},
+		{"en", []string{"Alice"}, Formal, "Good day, Alice."},
+		{"en", []string{"Alice", "Bob"}, Formal, "Good day to you all, Alice and Bob."},
+		{"es", []string{"Mundo"}, Informal, "¡Hola, Mundo!"},
+		{"es", []string{"Ana", "Luis"}, Informal, "¡Hola a todos, Ana y Luis!"},
+		{"de", []string{"Alice"}, Formal, "Guten Tag, Alice."},
+		{"fr", []string{"Alice"}, Informal, "Salut, Alice !"},
+		{"ja", []string{"田中"}, Formal, "田中様、こんにちは。"},
+		{"ja", []string{"A", "B"}, Informal, "こんにちは、AとB！"},
+		{"xx", []string{"Alice"}, Informal, "Hello, Alice!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+"/"+strings.Join(tt.names, ","), func(t *testing.T) {
+			if got := c.Greeting(tt.locale, tt.names, tt.formality); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPluralRules(t *testing.T) {
+	tests := []struct {
+		rule     string
+		n        int
+		expected string
+	}{
+		{"one", 0, pluralOther},
+		{"one", 1, pluralOne},
+		{"one", 2, pluralOther},
+		{"zero_one", 0, pluralOne},
+		{"zero_one", 1, pluralOne},
+		{"zero_one", 2, pluralOther},
+		{"none", 1, pluralOther},
+	}
+
+	for _, tt := range tests {
+		lf := &localeFile{PluralRule: tt.rule}
+		if got := lf.plural(tt.n); got != tt.expected {
+			t.Errorf("rule %s, n=%d: expected %s, got %s", tt.rule, tt.n, tt.expected, got)
+		}
+	}
+}
+
+func TestParseFormality(t *testing.T) {
+	for input, want := range map[string]Formality{"formal": Formal, " FORMAL ": Formal, "informal": Informal, "": Informal, "rude": Informal} {
+		if got := ParseFormality(input); got != want {
+			t.Errorf("ParseFormality(%q) = %s, want %s", input, got, want)
+		}
+	}
+}
+
+func TestLocalesAndWorld(t *testing.T) {
+	c := Default()
+	if got := c.Locales(); !reflect.DeepEqual(got, []string{"de", "en", "es", "fr", "ja"}) {
+		t.Errorf("unexpected locales %v", got)
+	}
+	if got := c.World("de"); got != "Welt" {
+		t.Errorf("expected 'Welt', got '%s'", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	valid := `{"locale":"en","greeting":{"informal":{"other":"Hi {name}"}}}`
+
+	tests := []struct {
+		name        string
+		files       fstest.MapFS
+		expectedErr string
+	}{
+		{
+			name:        "invalid JSON",
+			files:       fstest.MapFS{"locales/en.json": {Data: []byte("{")}},
+			expectedErr: "locales/en.json",
+		},
+		{
+			name:        "locale does not match file name",
+			files:       fstest.MapFS{"locales/en.json": {Data: []byte(valid)}, "locales/de.json": {Data: []byte(valid)}},
+			expectedErr: "does not match file name",
+		},
+		{
+			name:        "missing fallback greeting",
+			files:       fstest.MapFS{"locales/en.json": {Data: []byte(`{"locale":"en"}`)}},
+			expectedErr: "missing informal",
+		},
+		{
+			name:        "missing default locale",
+			files:       fstest.MapFS{"locales/de.json": {Data: []byte(strings.Replace(valid, `"en"`, `"de"`, 1))}},
+			expectedErr: `no "en" locale`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("expected error containing '%s', got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"hello-api/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.LogConfig
+		validate func(t *testing.T, output string)
+	}{
+		{
+			name: "json format",
+			cfg:  config.LogConfig{Level: "info", Format: "json"},
+			validate: func(t *testing.T, output string) {
+				var entry map[string]interface{}
+				if err := json.Unmarshal([]byte(output), &entry); err != nil {
+					t.Fatalf("expected JSON output, got %q: %v", output, err)
+				}
+				if entry["msg"] != "hello" || entry["service"] != "hello-api" || entry["key"] != "value" {
+					t.Errorf("unexpected log entry %v", entry)
+				}
+			},
+		},
+		{
+			name: "text format",
+			cfg:  config.LogConfig{Level: "info", Format: "text"},
+			validate: func(t *testing.T, output string) {
+				if !strings.Contains(output, "msg=hello") || !strings.Contains(output, "key=value") {
+					t.Errorf("expected text output, got %q", output)
+				}
+			},
+		},
+		{
+			name: "level filters records",
+			cfg:  config.LogConfig{Level: "error", Format: "json"},
+			validate: func(t *testing.T, output string) {
+				if output != "" {
+					t.Errorf("expected info record to be filtered, got %q", output)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			New(&buf, tt.cfg).Info("hello", slog.String("key", "value"))
+			tt.validate(t, buf.String())
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug":   slog.LevelDebug,
+		"info":    slog.LevelInfo,
+		"warn":    slog.LevelWarn,
+		"error":   slog.LevelError,
+		"unknown": slog.LevelInfo,
+	}
+
+	for input, want := range tests {
+		if got := ParseLevel(input); got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("expected default logger when none is stored")
+	}
+
+	logger := Discard()
+	ctx := WithLogger(context.Background(), logger)
+	if FromContext(ctx) != logger {
+		t.Error("expected stored logger to be returned")
+	}
+}
@@ -0,0 +1,149 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, reg *Registry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := reg.WriteTo(&buf); err != nil {
+		t.Fatalf("failed to write metrics: %v", err)
+	}
+	return buf.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	reg := NewRegistry()
+	c := NewCounterVec(reg, "requests_total", "Total requests.", "route", "code")
+
+	c.Inc("/hello", "200")
+	c.Inc("/hello", "200")
+	c.Add(3, "/ping", "500")
+
+	expected := `# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{route="/hello",code="200"} 2
+requests_total{route="/ping",code="500"} 3
+`
+	if got := render(t, reg); got != expected {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestGaugeVec(t *testing.T) {
+	reg := NewRegistry()
+	g := NewGaugeVec(reg, "in_flight", "In flight.", "route")
+
+	g.Inc("/hello")
+	g.Inc("/hello")
+	g.Dec("/hello")
+	g.Set(7.5, "/info")
+
+	expected := `# HELP in_flight In flight.
+# TYPE in_flight gauge
+in_flight{route="/hello"} 1
+in_flight{route="/info"} 7.5
+`
+	if got := render(t, reg); got != expected {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	reg := NewRegistry()
+	h := NewHistogramVec(reg, "latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+
+	h.Observe(0.05, "/hello")
+	h.Observe(0.5, "/hello")
+	h.Observe(2, "/hello")
+
+	expected := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/hello",le="0.1"} 1
+latency_seconds_bucket{route="/hello",le="1"} 2
+latency_seconds_bucket{route="/hello",le="+Inf"} 3
+latency_seconds_sum{route="/hello"} 2.55
+latency_seconds_count{route="/hello"} 3
+`
+	if got := render(t, reg); got != expected {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", got, expected)
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	reg := NewRegistry()
+	c := NewCounterVec(reg, "escaped_total", "Help with \\ and\nnewline.", "value")
+	c.Inc("a\"b\\c\nd")
+
+	out := render(t, reg)
+	if !strings.Contains(out, `# HELP escaped_total Help with \\ and\nnewline.`) {
+		t.Errorf("expected escaped help text, got:\n%s", out)
+	}
+	if !strings.Contains(out, `escaped_total{value="a\"b\\c\nd"} 1`) {
+		t.Errorf("expected escaped label value, got:\n%s", out)
+	}
+}
+
+func TestRegistryPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{
+			name: "duplicate registration",
+			fn: func() {
+				reg := NewRegistry()
+				NewCounterVec(reg, "dup_total", "Dup.")
+				NewGaugeVec(reg, "dup_total", "Dup.")
+			},
+		},
+		{
+			name: "wrong label count",
+			fn: func() {
+				NewCounterVec(NewRegistry(), "labels_total", "Labels.", "a", "b").Inc("only-one")
+			},
+		},
+		{
+			name: "negative counter add",
+			fn: func() {
+				NewCounterVec(NewRegistry(), "neg_total", "Neg.").Add(-1)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			tt.fn()
+		})
+	}
+}
+
+func TestHandlerWithRuntime(t *testing.T) {
+	reg := NewRegistry()
+	RegisterRuntime(reg)
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("expected Content-Type '%s', got '%s'", ContentType, ct)
+	}
+	for _, name := range []string{"go_goroutines", "go_memstats_alloc_bytes", "go_gc_cycles_total", "process_start_time_seconds"} {
+		if !strings.Contains(rec.Body.String(), "\n"+name+" ") {
+			t.Errorf("expected runtime metric %s in output", name)
+		}
+	}
+}
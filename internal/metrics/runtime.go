@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// runtimeCollector exposes Go runtime statistics under the go_ and process_
+// prefixes used by the Prometheus Go client, so existing dashboards work.
+type runtimeCollector struct {
+	start time.Time
+}
+
+// RegisterRuntime adds Go runtime and process statistics to reg.
+func RegisterRuntime(reg *Registry) {
+	reg.register(&runtimeCollector{start: time.Now()})
+}
+
+func (c *runtimeCollector) name() string { return "go_" }
+
+func (c *runtimeCollector) write(w *bufio.Writer) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	writeSingle(w, "go_goroutines", "Number of goroutines that currently exist.", "gauge", float64(runtime.NumGoroutine()))
+	writeSingle(w, "go_sched_gomaxprocs_threads", "The current runtime.GOMAXPROCS setting.", "gauge", float64(runtime.GOMAXPROCS(0)))
+	fmt.Fprintf(w, "# HELP go_info Information about the Go environment.\n# TYPE go_info gauge\ngo_info%s 1\n",
+		formatLabels([]string{"version"}, []string{runtime.Version()}))
+
+	writeSingle(w, "go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", "gauge", float64(ms.Alloc))
+	writeSingle(w, "go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.", "counter", float64(ms.TotalAlloc))
+	writeSingle(w, "go_memstats_sys_bytes", "Number of bytes obtained from system.", "gauge", float64(ms.Sys))
+	writeSingle(w, "go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", "gauge", float64(ms.HeapAlloc))
+	writeSingle(w, "go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", "gauge", float64(ms.HeapInuse))
+	writeSingle(w, "go_memstats_heap_objects", "Number of allocated objects.", "gauge", float64(ms.HeapObjects))
+	writeSingle(w, "go_memstats_mallocs_total", "Total number of mallocs.", "counter", float64(ms.Mallocs))
+	writeSingle(w, "go_memstats_frees_total", "Total number of frees.", "counter", float64(ms.Frees))
+	writeSingle(w, "go_memstats_next_gc_bytes", "Number of heap bytes when next garbage collection will take place.", "gauge", float64(ms.NextGC))
+	writeSingle(w, "go_gc_cycles_total", "Number of completed GC cycles.", "counter", float64(ms.NumGC))
+	writeSingle(w, "go_gc_pause_seconds_total", "Total time spent in GC stop-the-world pauses.", "counter", float64(ms.PauseTotalNs)/1e9)
+
+	writeSingle(w, "process_start_time_seconds", "Start time of the process since unix epoch in seconds.", "gauge", float64(c.start.UnixNano())/1e9)
+	writeSingle(w, "process_uptime_seconds", "Time since the process started in seconds.", "gauge", time.Since(c.start).Seconds())
+}
+
+func writeSingle(w *bufio.Writer, name, help, typ string, v float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, escapeHelp(help), name, typ, name, formatFloat(v))
+}
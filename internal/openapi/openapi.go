@@ -0,0 +1,277 @@
+// Package openapi reads the subset of OpenAPI 3.1 documents that hello-api
+// publishes: paths, operations, parameters, request bodies, responses and
+// JSON Schema component schemas, with local $ref pointers resolved.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info is the document's metadata.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components holds the reusable objects that $ref pointers refer to.
+type Components struct {
+	Schemas    map[string]*Schema    `json:"schemas"`
+	Responses  map[string]*Response  `json:"responses"`
+	Parameters map[string]*Parameter `json:"parameters"`
+}
+
+// PathItem describes the operations available on a single path.
+type PathItem struct {
+	Parameters []*Parameter `json:"parameters"`
+	Get        *Operation   `json:"get"`
+	Put        *Operation   `json:"put"`
+	Post       *Operation   `json:"post"`
+	Delete     *Operation   `json:"delete"`
+	Options    *Operation   `json:"options"`
+	Head       *Operation   `json:"head"`
+	Patch      *Operation   `json:"patch"`
+	Trace      *Operation   `json:"trace"`
+}
+
+// Operations returns the path's operations keyed by HTTP method.
+func (p *PathItem) Operations() map[string]*Operation {
+	ops := make(map[string]*Operation)
+	for method, op := range map[string]*Operation{
+		http.MethodGet:     p.Get,
+		http.MethodPut:     p.Put,
+		http.MethodPost:    p.Post,
+		http.MethodDelete:  p.Delete,
+		http.MethodOptions: p.Options,
+		http.MethodHead:    p.Head,
+		http.MethodPatch:   p.Patch,
+		http.MethodTrace:   p.Trace,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+// Operation is a single API operation on a path.
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Response returns the response documented for status: an exact match, then
+// its range (for example "4XX"), then "default". It returns nil when status
+// is not documented.
+func (o *Operation) Response(status int) *Response {
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", "default"} {
+		if resp, ok := o.Responses[key]; ok {
+			return resp
+		}
+	}
+	return nil
+}
+
+// Parameter is a query, header, path or cookie parameter.
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes the bodies an operation accepts.
+type RequestBody struct {
+	Required bool    `json:"required"`
+	Content  Content `json:"content"`
+}
+
+// Response describes a response and the bodies it may carry.
+type Response struct {
+	Ref         string  `json:"$ref"`
+	Description string  `json:"description"`
+	Content     Content `json:"content"`
+}
+
+// Content maps media types to their schemas.
+type Content map[string]*MediaType
+
+// Lookup returns the entry for the media type of contentType, ignoring
+// parameters such as charset.
+func (c Content) Lookup(contentType string) (*MediaType, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	mt, ok := c[mediaType]
+	return mt, ok
+}
+
+// MediaType describes a body in one media type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Parse decodes an OpenAPI 3.1 JSON document and resolves its local $ref
+// pointers.
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.1.") {
+		return nil, fmt.Errorf("openapi: unsupported version %q", doc.OpenAPI)
+	}
+
+	r := &resolver{doc: &doc, resolving: make(map[*Schema]bool)}
+	for path, item := range doc.Paths {
+		if err := r.pathItem(item); err != nil {
+			return nil, fmt.Errorf("openapi: %s: %w", path, err)
+		}
+	}
+	for name, s := range doc.Components.Schemas {
+		if err := r.schema(s); err != nil {
+			return nil, fmt.Errorf("openapi: schema %s: %w", name, err)
+		}
+	}
+	return &doc, nil
+}
+
+// Operation returns the operation for method on path, or nil when the
+// document does not describe it.
+func (d *Document) Operation(path, method string) *Operation {
+	item, ok := d.Paths[path]
+	if !ok {
+		return nil
+	}
+	return item.Operations()[method]
+}
+
+// resolver replaces $ref pointers with the components they name.
+type resolver struct {
+	doc       *Document
+	resolving map[*Schema]bool
+}
+
+const (
+	schemaPrefix    = "#/components/schemas/"
+	responsePrefix  = "#/components/responses/"
+	parameterPrefix = "#/components/parameters/"
+)
+
+func (r *resolver) pathItem(item *PathItem) error {
+	if err := r.parameters(item.Parameters); err != nil {
+		return err
+	}
+	for method, op := range item.Operations() {
+		if err := r.operation(op); err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+	}
+	return nil
+}
+
+func (r *resolver) operation(op *Operation) error {
+	if err := r.parameters(op.Parameters); err != nil {
+		return err
+	}
+	if op.RequestBody != nil {
+		if err := r.content(op.RequestBody.Content); err != nil {
+			return err
+		}
+	}
+	for status, resp := range op.Responses {
+		if resp.Ref != "" {
+			target, ok := r.doc.Components.Responses[strings.TrimPrefix(resp.Ref, responsePrefix)]
+			if !ok || !strings.HasPrefix(resp.Ref, responsePrefix) {
+				return fmt.Errorf("unresolved $ref %q", resp.Ref)
+			}
+			op.Responses[status] = target
+			resp = target
+		}
+		if err := r.content(resp.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *resolver) parameters(params []*Parameter) error {
+	for i, p := range params {
+		if p.Ref != "" {
+			target, ok := r.doc.Components.Parameters[strings.TrimPrefix(p.Ref, parameterPrefix)]
+			if !ok || !strings.HasPrefix(p.Ref, parameterPrefix) {
+				return fmt.Errorf("unresolved $ref %q", p.Ref)
+			}
+			params[i] = target
+			p = target
+		}
+		if p.Schema != nil {
+			if err := r.schema(p.Schema); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *resolver) content(c Content) error {
+	for _, mt := range c {
+		if mt.Schema != nil {
+			if err := r.schema(mt.Schema); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *resolver) schema(s *Schema) error {
+	if r.resolving[s] {
+		return fmt.Errorf("circular $ref %q", s.Ref)
+	}
+	r.resolving[s] = true
+	defer delete(r.resolving, s)
+
+	if s.Ref != "" {
+		target, ok := r.doc.Components.Schemas[strings.TrimPrefix(s.Ref, schemaPrefix)]
+		if !ok || !strings.HasPrefix(s.Ref, schemaPrefix) {
+			return fmt.Errorf("unresolved $ref %q", s.Ref)
+		}
+		if err := r.schema(target); err != nil {
+			return err
+		}
+		*s = *target
+		return nil
+	}
+
+	for _, prop := range s.Properties {
+		if err := r.schema(prop); err != nil {
+			return err
+		}
+	}
+	for _, sub := range []*Schema{s.Items, s.AdditionalProperties} {
+		if sub != nil {
+			if err := r.schema(sub); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
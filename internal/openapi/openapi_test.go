@@ -0,0 +1,246 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"hello-api/internal/validation"
+)
+
+const testDocument = `{
+  "openapi": "3.1.0",
+  "info": {"title": "Test", "version": "1"},
+  "paths": {
+    "/items": {
+      "get": {
+        "parameters": [{"$ref": "#/components/parameters/Limit"}],
+        "responses": {
+          "200": {"description": "ok", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}},
+          "4XX": {"$ref": "#/components/responses/Error"},
+          "default": {"description": "anything else"}
+        }
+      },
+      "post": {
+        "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Item"}}}},
+        "responses": {"201": {"description": "created"}}
+      }
+    }
+  },
+  "components": {
+    "parameters": {
+      "Limit": {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1}}
+    },
+    "responses": {
+      "Error": {"description": "error", "content": {"application/json": {"schema": {"type": "object"}}}}
+    },
+    "schemas": {
+      "Kind": {"type": "string", "enum": ["a", "b"]},
+      "Item": {
+        "type": "object",
+        "properties": {
+          "name": {"type": "string", "minLength": 1, "maxLength": 5},
+          "kind": {"$ref": "#/components/schemas/Kind"},
+          "tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+          "count": {"type": ["integer", "null"], "minimum": 0},
+          "version": {"const": 1},
+          "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+        },
+        "required": ["name"],
+        "additionalProperties": false
+      }
+    }
+  }
+}`
+
+func TestParse(t *testing.T) {
+	doc, err := Parse([]byte(testDocument))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	get := doc.Operation("/items", http.MethodGet)
+	if get == nil {
+		t.Fatal("expected GET /items to be documented")
+	}
+	if doc.Operation("/items", http.MethodDelete) != nil || doc.Operation("/other", http.MethodGet) != nil {
+		t.Error("expected undocumented operations to be nil")
+	}
+
+	if p := get.Parameters[0]; p.Name != "limit" || p.Schema == nil || !reflect.DeepEqual(p.Schema.Type, Types{"integer"}) {
+		t.Errorf("expected the limit parameter to be resolved, got %+v", p)
+	}
+
+	mt, ok := get.Response(http.StatusOK).Content.Lookup("application/json; charset=utf-8")
+	if !ok {
+		t.Fatal("expected application/json content for 200")
+	}
+	if kind := mt.Schema.Properties["kind"]; kind.Ref != "" || len(kind.Enum) != 2 {
+		t.Errorf("expected the kind schema to be resolved, got %+v", kind)
+	}
+	if _, ok := get.Response(http.StatusOK).Content.Lookup("text/plain"); ok {
+		t.Error("expected no text/plain content")
+	}
+
+	tests := []struct {
+		status      int
+		description string
+	}{
+		{http.StatusOK, "ok"},
+		{http.StatusNotFound, "error"},
+		{http.StatusInternalServerError, "anything else"},
+	}
+	for _, tt := range tests {
+		resp := get.Response(tt.status)
+		if resp == nil || resp.Description != tt.description {
+			t.Errorf("expected response %d to be '%s', got %+v", tt.status, tt.description, resp)
+		}
+	}
+
+	ops := doc.Paths["/items"].Operations()
+	if len(ops) != 2 || ops[http.MethodGet] == nil || ops[http.MethodPost] == nil {
+		t.Errorf("expected GET and POST operations, got %v", ops)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		doc         string
+		expectedErr string
+	}{
+		{name: "invalid JSON", doc: `{`, expectedErr: "openapi:"},
+		{name: "unsupported version", doc: `{"openapi": "3.0.3"}`, expectedErr: `unsupported version "3.0.3"`},
+		{
+			name:        "unresolved schema",
+			doc:         `{"openapi": "3.1.0", "components": {"schemas": {"A": {"$ref": "#/components/schemas/B"}}}}`,
+			expectedErr: `unresolved $ref "#/components/schemas/B"`,
+		},
+		{
+			name:        "circular schema",
+			doc:         `{"openapi": "3.1.0", "components": {"schemas": {"A": {"$ref": "#/components/schemas/A"}}}}`,
+			expectedErr: "circular $ref",
+		},
+		{
+			name:        "unresolved response",
+			doc:         `{"openapi": "3.1.0", "paths": {"/x": {"get": {"responses": {"200": {"$ref": "#/components/responses/Missing"}}}}}}`,
+			expectedErr: `unresolved $ref "#/components/responses/Missing"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("expected error containing '%s', got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	doc, err := Parse([]byte(testDocument))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	item := doc.Components.Schemas["Item"]
+
+	tests := []struct {
+		name     string
+		value    string
+		expected validation.Errors
+	}{
+		{name: "valid", value: `{"name":"ab","kind":"a","tags":["x"],"count":2,"version":1,"labels":{"k":"v"}}`},
+		{name: "null allowed", value: `{"name":"ab","count":null}`},
+		{
+			name:     "not an object",
+			value:    `[]`,
+			expected: validation.Errors{{Field: "", Code: CodeInvalidType, Message: "must be an object"}},
+		},
+		{
+			name:     "missing required",
+			value:    `{}`,
+			expected: validation.Errors{{Field: "name", Code: CodeRequired, Message: "is required"}},
+		},
+		{
+			name:  "unknown field",
+			value: `{"name":"ab","nickname":"x"}`,
+			expected: validation.Errors{
+				{Field: "nickname", Code: CodeUnknownField, Message: "is not allowed"},
+			},
+		},
+		{
+			name:  "string bounds",
+			value: `{"name":""}`,
+			expected: validation.Errors{
+				{Field: "name", Code: CodeTooShort, Message: "must be at least 1 characters, got 0"},
+			},
+		},
+		{
+			name:  "too long",
+			value: `{"name":"abcdef"}`,
+			expected: validation.Errors{
+				{Field: "name", Code: validation.CodeTooLong, Message: "must be at most 5 characters, got 6"},
+			},
+		},
+		{
+			name:  "enum",
+			value: `{"name":"ab","kind":"c"}`,
+			expected: validation.Errors{
+				{Field: "kind", Code: CodeInvalidValue, Message: `must be one of "a", "b"`},
+			},
+		},
+		{
+			name:  "const",
+			value: `{"name":"ab","version":2}`,
+			expected: validation.Errors{
+				{Field: "version", Code: CodeInvalidValue, Message: "must be 1"},
+			},
+		},
+		{
+			name:  "integer and minimum",
+			value: `{"name":"ab","count":1.5}`,
+			expected: validation.Errors{
+				{Field: "count", Code: CodeInvalidType, Message: "must be an integer or null"},
+			},
+		},
+		{
+			name:  "array items and length",
+			value: `{"name":"ab","tags":["x",1,"z"]}`,
+			expected: validation.Errors{
+				{Field: "tags", Code: validation.CodeTooLong, Message: "must have at most 2 items, got 3"},
+				{Field: "tags[1]", Code: CodeInvalidType, Message: "must be a string"},
+			},
+		},
+		{
+			name:  "additional properties schema",
+			value: `{"name":"ab","labels":{"k":true}}`,
+			expected: validation.Errors{
+				{Field: "labels.k", Code: CodeInvalidType, Message: "must be a string"},
+			},
+		},
+		{
+			name:  "every violation reported",
+			value: `{"kind":"c","count":-1}`,
+			expected: validation.Errors{
+				{Field: "name", Code: CodeRequired, Message: "is required"},
+				{Field: "count", Code: CodeOutOfRange, Message: "must be at least 0, got -1"},
+				{Field: "kind", Code: CodeInvalidValue, Message: `must be one of "a", "b"`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			if err := json.Unmarshal([]byte(tt.value), &v); err != nil {
+				t.Fatalf("invalid test value: %v", err)
+			}
+			if got := item.Validate("", v); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"hello-api/internal/validation"
+)
+
+// CodeInvalidSyntax is reported by ValidateJSON for bodies that are not
+// well-formed JSON.
+const CodeInvalidSyntax = "invalid_syntax"
+
+// MustParse is like Parse but panics if the document cannot be parsed. It
+// simplifies initializing globals holding embedded documents.
+func MustParse(data []byte) *Document {
+	doc, err := Parse(data)
+	if err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+// ValidateParameters checks r's query and header parameters against those
+// documented for op. Parameters that are not documented are ignored. Query
+// values are converted to the schema's type before they are checked, so
+// ?limit=10 matches {"type": "integer"}.
+func (op *Operation) ValidateParameters(r *http.Request) validation.Errors {
+	var errs validation.Errors
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		var values []string
+		switch p.In {
+		case "query":
+			values = query[p.Name]
+		case "header":
+			values = r.Header.Values(p.Name)
+		default:
+			continue
+		}
+
+		if len(values) == 0 {
+			if p.Required {
+				errs = append(errs, validation.FieldError{Field: p.Name, Code: CodeRequired, Message: "is required"})
+			}
+			continue
+		}
+		if p.Schema != nil {
+			errs = append(errs, p.Schema.Validate(p.Name, p.Schema.parameterValue(values))...)
+		}
+	}
+	return errs
+}
+
+// parameterValue converts raw parameter values to the JSON value s expects:
+// an array for array schemas, otherwise the first value as a number,
+// boolean or string. Values that do not convert are left as strings for
+// Validate to reject.
+func (s *Schema) parameterValue(values []string) interface{} {
+	if slices.Contains(s.Type, "array") {
+		items := make([]interface{}, len(values))
+		for i, v := range values {
+			items[i] = v
+			if s.Items != nil {
+				items[i] = s.Items.parameterValue([]string{v})
+			}
+		}
+		return items
+	}
+
+	v := values[0]
+	if slices.Contains(s.Type, "integer") || slices.Contains(s.Type, "number") {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	if slices.Contains(s.Type, "boolean") {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// MediaType returns the documented entry for a request body sent with
+// contentType, or false when the media type is not accepted.
+func (b *RequestBody) MediaType(contentType string) (*MediaType, bool) {
+	return b.Content.Lookup(contentType)
+}
+
+// MediaTypes returns the accepted media types in sorted order.
+func (b *RequestBody) MediaTypes() []string {
+	types := make([]string, 0, len(b.Content))
+	for mt := range b.Content {
+		types = append(types, mt)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// IsJSON reports whether contentType is application/json or a structured
+// syntax type such as application/problem+json.
+func IsJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// ErrInvalidSyntax is returned by ValidateJSON for malformed JSON, along
+// with a CodeInvalidSyntax field error describing where it went wrong.
+var ErrInvalidSyntax = errors.New("openapi: invalid JSON")
+
+// ValidateJSON decodes a JSON body and checks it against mt's schema. field
+// names the body in errors about the value as a whole; its properties are
+// named on their own, like "name". It returns ErrInvalidSyntax with a
+// single field error when data is not well-formed JSON.
+func (mt *MediaType) ValidateJSON(field string, data []byte) (validation.Errors, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		message := err.Error()
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			message = fmt.Sprintf("%s at offset %d", syntaxErr, syntaxErr.Offset)
+		}
+		return validation.Errors{{Field: field, Code: CodeInvalidSyntax, Message: message}}, ErrInvalidSyntax
+	}
+	if mt.Schema == nil {
+		return nil, nil
+	}
+
+	errs := mt.Schema.Validate("", v)
+	for i := range errs {
+		if errs[i].Field == "" {
+			errs[i].Field = field
+		}
+	}
+	return errs, nil
+}
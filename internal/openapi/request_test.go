@@ -0,0 +1,178 @@
+package openapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"hello-api/internal/validation"
+)
+
+const requestDocument = `{
+  "openapi": "3.1.0",
+  "paths": {
+    "/items": {
+      "post": {
+        "parameters": [
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "minimum": 1}},
+          {"name": "tag", "in": "query", "schema": {"type": "array", "items": {"enum": ["a", "b"]}}},
+          {"name": "dry_run", "in": "query", "schema": {"type": "boolean"}},
+          {"name": "X-Tenant", "in": "header", "required": true, "schema": {"type": "string", "minLength": 2}}
+        ],
+        "requestBody": {
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "properties": {"name": {"type": "string"}},
+                "required": ["name"],
+                "additionalProperties": false
+              }
+            },
+            "text/csv": {}
+          }
+        },
+        "responses": {"201": {"description": "created"}}
+      }
+    }
+  }
+}`
+
+func TestValidateParameters(t *testing.T) {
+	doc := MustParse([]byte(requestDocument))
+	op := doc.Operation("/items", http.MethodPost)
+
+	tests := []struct {
+		name     string
+		url      string
+		tenant   string
+		expected validation.Errors
+	}{
+		{name: "valid", url: "/items?limit=10&tag=a&tag=b&dry_run=true", tenant: "acme"},
+		{name: "optional parameters omitted", url: "/items", tenant: "acme"},
+		{name: "undocumented parameters ignored", url: "/items?other=x", tenant: "acme"},
+		{
+			name:     "missing required header",
+			url:      "/items",
+			expected: validation.Errors{{Field: "X-Tenant", Code: CodeRequired, Message: "is required"}},
+		},
+		{
+			name:   "every violation reported",
+			url:    "/items?limit=ten&tag=a&tag=c&dry_run=maybe",
+			tenant: "a",
+			expected: validation.Errors{
+				{Field: "limit", Code: CodeInvalidType, Message: "must be an integer"},
+				{Field: "tag[1]", Code: CodeInvalidValue, Message: `must be one of "a", "b"`},
+				{Field: "dry_run", Code: CodeInvalidType, Message: "must be a boolean"},
+				{Field: "X-Tenant", Code: CodeTooShort, Message: "must be at least 2 characters, got 1"},
+			},
+		},
+		{
+			name:     "converted values still checked",
+			url:      "/items?limit=0",
+			tenant:   "acme",
+			expected: validation.Errors{{Field: "limit", Code: CodeOutOfRange, Message: "must be at least 1, got 0"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.url, nil)
+			if tt.tenant != "" {
+				req.Header.Set("X-Tenant", tt.tenant)
+			}
+			if got := op.ValidateParameters(req); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRequestBody(t *testing.T) {
+	body := MustParse([]byte(requestDocument)).Operation("/items", http.MethodPost).RequestBody
+
+	if got := body.MediaTypes(); !reflect.DeepEqual(got, []string{"application/json", "text/csv"}) {
+		t.Errorf("expected sorted media types, got %v", got)
+	}
+	if _, ok := body.MediaType("application/json; charset=utf-8"); !ok {
+		t.Error("expected application/json with a charset to be accepted")
+	}
+	if _, ok := body.MediaType("application/xml"); ok {
+		t.Error("expected application/xml to be rejected")
+	}
+
+	mt, _ := body.MediaType("application/json")
+	tests := []struct {
+		name        string
+		data        string
+		expected    validation.Errors
+		expectedErr error
+	}{
+		{name: "valid", data: `{"name":"Ana"}`},
+		{
+			name:     "schema violations",
+			data:     `{"nickname":"Ana"}`,
+			expected: validation.Errors{{Field: "name", Code: CodeRequired, Message: "is required"}, {Field: "nickname", Code: CodeUnknownField, Message: "is not allowed"}},
+		},
+		{
+			name:     "root named after the body",
+			data:     `"Ana"`,
+			expected: validation.Errors{{Field: "body", Code: CodeInvalidType, Message: "must be an object"}},
+		},
+		{
+			name:        "malformed",
+			data:        `{"name" "Ana"}`,
+			expected:    validation.Errors{{Field: "body", Code: CodeInvalidSyntax, Message: "invalid character '\"' after object key at offset 9"}},
+			expectedErr: ErrInvalidSyntax,
+		},
+		{
+			name:        "empty",
+			data:        ``,
+			expected:    validation.Errors{{Field: "body", Code: CodeInvalidSyntax, Message: "unexpected end of JSON input at offset 0"}},
+			expectedErr: ErrInvalidSyntax,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mt.ValidateJSON("body", []byte(tt.data))
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIsJSON(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expected    bool
+	}{
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"application/problem+json", true},
+		{"application/x-ndjson", false},
+		{"text/plain", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsJSON(tt.contentType); got != tt.expected {
+			t.Errorf("IsJSON(%q) = %v, want %v", tt.contentType, got, tt.expected)
+		}
+	}
+}
+
+func TestMustParsePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected MustParse to panic on an invalid document")
+		}
+	}()
+	MustParse([]byte(`{"openapi": "2.0"}`))
+}
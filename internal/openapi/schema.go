@@ -0,0 +1,243 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"hello-api/internal/validation"
+)
+
+// Codes reported in validation.FieldError values by Schema.Validate, in
+// addition to validation.CodeTooLong.
+const (
+	CodeRequired     = "required"
+	CodeInvalidType  = "invalid_type"
+	CodeInvalidValue = "invalid_value"
+	CodeUnknownField = "unknown_field"
+	CodeTooShort     = "too_short"
+	CodeOutOfRange   = "out_of_range"
+)
+
+// Schema is the subset of a JSON Schema 2020-12 object that hello-api uses.
+// Other keywords, such as description and format, are ignored.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 Types              `json:"type"`
+	Enum                 []interface{}      `json:"enum"`
+	Const                interface{}        `json:"const"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *Schema            `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+
+	// never is set for the boolean schema false, which no value matches.
+	never bool
+}
+
+// UnmarshalJSON accepts boolean schemas as well as schema objects.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	switch string(bytes.TrimSpace(data)) {
+	case "true":
+		*s = Schema{}
+		return nil
+	case "false":
+		*s = Schema{never: true}
+		return nil
+	}
+	type plain Schema
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// Types is the value of the type keyword, a single type name or a list.
+type Types []string
+
+// UnmarshalJSON accepts a string or an array of strings.
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = Types{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+// Validate checks v, a value decoded by encoding/json into interface{}, and
+// returns one error per violation. field names the value in the errors;
+// nested values are named like "details[0].field".
+func (s *Schema) Validate(field string, v interface{}) validation.Errors {
+	var errs validation.Errors
+	s.validate(field, v, &errs)
+	return errs
+}
+
+func (s *Schema) validate(field string, v interface{}, errs *validation.Errors) {
+	fail := func(code, format string, args ...interface{}) {
+		*errs = append(*errs, validation.FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if s.never {
+		fail(CodeUnknownField, "is not allowed")
+		return
+	}
+	if len(s.Type) > 0 && !s.Type.matches(v) {
+		fail(CodeInvalidType, "must be %s", s.Type)
+		return
+	}
+	if s.Const != nil && !equal(s.Const, v) {
+		fail(CodeInvalidValue, "must be %s", literal(s.Const))
+		return
+	}
+	if len(s.Enum) > 0 && !s.enumContains(v) {
+		values := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			values[i] = literal(e)
+		}
+		fail(CodeInvalidValue, "must be one of %s", strings.Join(values, ", "))
+		return
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail(CodeTooShort, "must be at least %d characters, got %d", *s.MinLength, n)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail(validation.CodeTooLong, "must be at most %d characters, got %d", *s.MaxLength, n)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail(CodeOutOfRange, "must be at least %v, got %v", *s.Minimum, v)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail(CodeOutOfRange, "must be at most %v, got %v", *s.Maximum, v)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail(CodeTooShort, "must have at least %d items, got %d", *s.MinItems, len(v))
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail(validation.CodeTooLong, "must have at most %d items, got %d", *s.MaxItems, len(v))
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", field, i), item, errs)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, validation.FieldError{Field: join(field, name), Code: CodeRequired, Message: "is required"})
+			}
+		}
+
+		// Sorted so errors are reported in a stable order.
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sub, ok := s.Properties[key]
+			if !ok {
+				sub = s.AdditionalProperties
+			}
+			if sub != nil {
+				sub.validate(join(field, key), v[key], errs)
+			}
+		}
+	}
+}
+
+func (t Types) matches(v interface{}) bool {
+	for _, name := range t {
+		switch v := v.(type) {
+		case nil:
+			if name == "null" {
+				return true
+			}
+		case bool:
+			if name == "boolean" {
+				return true
+			}
+		case string:
+			if name == "string" {
+				return true
+			}
+		case float64:
+			if name == "number" || name == "integer" && v == math.Trunc(v) {
+				return true
+			}
+		case []interface{}:
+			if name == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if name == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String describes the types for error messages, for example "a string".
+func (t Types) String() string {
+	names := make([]string, len(t))
+	for i, name := range t {
+		switch name {
+		case "array", "integer", "object":
+			names[i] = "an " + name
+		case "null":
+			names[i] = "null"
+		default:
+			names[i] = "a " + name
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+func (s *Schema) enumContains(v interface{}) bool {
+	for _, e := range s.Enum {
+		if equal(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares JSON values by their encoding, which is canonical for the
+// scalars used in enum and const.
+func equal(a, b interface{}) bool {
+	x, errA := json.Marshal(a)
+	y, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(x, y)
+}
+
+func literal(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func join(field, key string) string {
+	if field == "" {
+		return key
+	}
+	return field + "." + key
+}
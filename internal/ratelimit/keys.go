@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"hello-api/internal/auth"
+)
+
+// Key strategies accepted by KeyFunc.
+const (
+	KeyIP     = "ip"
+	KeyAPIKey = "api_key"
+	KeyRoute  = "route"
+)
+
+// ParseCIDRs parses trusted proxy networks. Bare IP addresses are accepted
+// as single-host networks.
+func ParseCIDRs(values []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(values))
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", v)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", v)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// ClientIP returns the address of the client that made r. Forwarding
+// headers are only honored when the direct peer is a trusted proxy; the
+// X-Forwarded-For chain is then walked from the right, skipping trusted
+// hops, so clients cannot spoof their address by prepending entries. A
+// chain that does not lead to a valid address falls back to the peer, not
+// to X-Real-IP, which is only read when X-Forwarded-For is absent.
+func ClientIP(r *http.Request, trusted []*net.IPNet) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	if !isTrusted(peer, trusted) {
+		return peer
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if !isTrusted(hop, trusted) || i == 0 {
+				return hop
+			}
+		}
+		return peer
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return peer
+}
+
+func isTrusted(addr string, trusted []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeyFunc returns the function deriving a bucket key for route under
+// strategy. Keys are scoped to the route so each route has its own limit.
+// The api_key strategy keys on the name of the caller's API key once keys
+// has verified it; requests without a valid key, or with keys nil, fall
+// back to the client IP so made-up keys cannot each get a fresh bucket.
+func KeyFunc(strategy, route string, trusted []*net.IPNet, keys *auth.APIKeys) func(*http.Request) string {
+	switch strategy {
+	case KeyRoute:
+		return func(*http.Request) string { return route }
+	case KeyAPIKey:
+		return func(r *http.Request) string {
+			if key := r.Header.Get(auth.APIKeyHeader); key != "" && keys != nil {
+				if p, ok := keys.Verify(key); ok {
+					return route + "|key:" + p.Subject
+				}
+			}
+			return route + "|ip:" + ClientIP(r, trusted)
+		}
+	default:
+		return func(r *http.Request) string { return route + "|ip:" + ClientIP(r, trusted) }
+	}
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"hello-api/internal/auth"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	nets, err := ParseCIDRs([]string{"10.0.0.0/8", "192.168.1.1", "::1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nets) != 3 || nets[1].String() != "192.168.1.1/32" || nets[2].String() != "::1/128" {
+		t.Errorf("unexpected networks %v", nets)
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "proxy.local"} {
+		if _, err := ParseCIDRs([]string{bad}); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, _ := ParseCIDRs([]string{"10.0.0.0/8"})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		realIP     string
+		expected   string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:5000", expected: "203.0.113.7"},
+		{name: "untrusted peer cannot spoof", remoteAddr: "203.0.113.7:5000", xff: "1.2.3.4", expected: "203.0.113.7"},
+		{name: "trusted proxy forwards client", remoteAddr: "10.0.0.2:80", xff: "198.51.100.9", expected: "198.51.100.9"},
+		{name: "spoofed prefix ignored", remoteAddr: "10.0.0.2:80", xff: "1.2.3.4, 198.51.100.9, 10.0.0.5", expected: "198.51.100.9"},
+		{name: "all hops trusted", remoteAddr: "10.0.0.2:80", xff: "10.1.1.1, 10.0.0.5", expected: "10.1.1.1"},
+		{name: "X-Real-IP fallback", remoteAddr: "10.0.0.2:80", realIP: "198.51.100.10", expected: "198.51.100.10"},
+		{name: "garbage header falls back to peer", remoteAddr: "10.0.0.2:80", xff: "unknown", expected: "10.0.0.2"},
+		{name: "garbage header ignores X-Real-IP", remoteAddr: "10.0.0.2:80", xff: "1.2.3.4, unknown", realIP: "198.51.100.10", expected: "10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			if got := ClientIP(req, trusted); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestKeyFunc(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+	req.RemoteAddr = "203.0.113.7:5000"
+
+	keys, err := auth.ParseAPIKeys([]string{"ci:" + auth.HashAPIKey("secret-key")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := KeyFunc(KeyIP, "/hello", nil, keys)(req); got != "/hello|ip:203.0.113.7" {
+		t.Errorf("unexpected ip key %q", got)
+	}
+	if got := KeyFunc(KeyRoute, "/hello", nil, keys)(req); got != "/hello" {
+		t.Errorf("unexpected route key %q", got)
+	}
+	if got := KeyFunc(KeyAPIKey, "/hello", nil, keys)(req); got != "/hello|ip:203.0.113.7" {
+		t.Errorf("expected anonymous api_key requests to fall back to IP, got %q", got)
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		keys     *auth.APIKeys
+		expected string
+	}{
+		{name: "valid key", key: "secret-key", keys: keys, expected: "/hello|key:ci"},
+		{name: "unknown key", key: "made-up", keys: keys, expected: "/hello|ip:203.0.113.7"},
+		{name: "no keys configured", key: "secret-key", keys: nil, expected: "/hello|ip:203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req.Header.Set(auth.APIKeyHeader, tt.key)
+			if got := KeyFunc(KeyAPIKey, "/hello", nil, tt.keys)(req); got != tt.expected {
+				t.Errorf("expected key %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
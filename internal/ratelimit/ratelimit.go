@@ -0,0 +1,162 @@
+// Package ratelimit implements per-client token bucket rate limiting with
+// IETF RateLimit-* response headers.
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limiter holds one token bucket per key. Buckets that stay idle longer than
+// the idle TTL are evicted, and the number of buckets is capped, so memory
+// stays bounded no matter how many clients appear. Buckets are kept in least
+// recently used order, so neither eviction scans every bucket.
+type Limiter struct {
+	rate    float64
+	burst   float64
+	idleTTL time.Duration
+	maxKeys int
+	now     func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*list.Element
+	lru       *list.List // of *bucket, most recently used first
+	lastSweep time.Time
+}
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// Result is the outcome of a single Allow call.
+type Result struct {
+	Allowed bool
+	// Limit is the bucket capacity.
+	Limit int
+	// Remaining is the number of whole tokens left after this request.
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next token is available; zero when
+	// the request was allowed.
+	RetryAfter time.Duration
+}
+
+// New returns a Limiter refilling rate tokens per second up to burst.
+func New(rate float64, burst int, idleTTL time.Duration, maxKeys int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		idleTTL: idleTTL,
+		maxKeys: maxKeys,
+		now:     time.Now,
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Allow takes a token from key's bucket if one is available.
+func (l *Limiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.maybeSweep(now)
+
+	var b *bucket
+	if e, ok := l.buckets[key]; ok {
+		l.lru.MoveToFront(e)
+		b = e.Value.(*bucket)
+	} else {
+		if len(l.buckets) >= l.maxKeys {
+			l.evictOldest()
+		}
+		b = &bucket{key: key, tokens: l.burst, last: now}
+		l.buckets[key] = l.lru.PushFront(b)
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	res := Result{Limit: int(l.burst)}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.durationFor(1 - b.tokens)
+	}
+	res.Remaining = int(math.Floor(b.tokens))
+	res.Reset = l.durationFor(l.burst - b.tokens)
+	return res
+}
+
+// Len returns the number of tracked buckets.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+func (l *Limiter) durationFor(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+// maybeSweep evicts idle buckets at most twice per idle TTL. The idle
+// buckets are the least recently used, so it stops at the first active one.
+func (l *Limiter) maybeSweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.idleTTL/2 {
+		return
+	}
+	l.lastSweep = now
+	for e := l.lru.Back(); e != nil && now.Sub(e.Value.(*bucket).last) >= l.idleTTL; e = l.lru.Back() {
+		l.remove(e)
+	}
+}
+
+// evictOldest drops the least recently used bucket to make room for a new
+// key. A fresh bucket is full, so this only ever favors the evicted client.
+func (l *Limiter) evictOldest() {
+	if e := l.lru.Back(); e != nil {
+		l.remove(e)
+	}
+}
+
+func (l *Limiter) remove(e *list.Element) {
+	l.lru.Remove(e)
+	delete(l.buckets, e.Value.(*bucket).key)
+}
+
+// Middleware limits requests to next by the key returned from keyFunc.
+// Every response carries RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers; rejected requests also get Retry-After and are
+// answered by onLimited.
+func Middleware(l *Limiter, keyFunc func(*http.Request) string, onLimited, next http.Handler) http.Handler {
+	policy := strconv.Itoa(int(l.burst)) + ";w=" + strconv.Itoa(int(math.Ceil(l.burst/l.rate)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := l.Allow(keyFunc(r))
+
+		h := w.Header()
+		h.Set("RateLimit-Policy", policy)
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			onLimited.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,174 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(rate float64, burst int) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	l := New(rate, burst, time.Minute, 100)
+	l.now = clock.now
+	return l, clock
+}
+
+func TestAllow(t *testing.T) {
+	l, clock := newTestLimiter(2, 3)
+
+	for i := 0; i < 3; i++ {
+		res := l.Allow("client")
+		if !res.Allowed {
+			t.Fatalf("request %d: expected burst to be allowed", i)
+		}
+		if res.Remaining != 2-i {
+			t.Errorf("request %d: expected remaining %d, got %d", i, 2-i, res.Remaining)
+		}
+	}
+
+	res := l.Allow("client")
+	if res.Allowed {
+		t.Fatal("expected request beyond burst to be rejected")
+	}
+	if res.RetryAfter != 500*time.Millisecond {
+		t.Errorf("expected retry after 500ms at 2 tokens/s, got %s", res.RetryAfter)
+	}
+	if res.Reset != 1500*time.Millisecond {
+		t.Errorf("expected reset 1.5s, got %s", res.Reset)
+	}
+
+	if other := l.Allow("other"); !other.Allowed {
+		t.Error("expected buckets to be independent per key")
+	}
+
+	clock.advance(500 * time.Millisecond)
+	if !l.Allow("client").Allowed {
+		t.Error("expected a token to be refilled")
+	}
+
+	clock.advance(time.Hour)
+	if res := l.Allow("client"); res.Remaining != 2 {
+		t.Errorf("expected refill to be capped at burst, got remaining %d", res.Remaining)
+	}
+}
+
+func TestEviction(t *testing.T) {
+	t.Run("idle buckets are swept", func(t *testing.T) {
+		l, clock := newTestLimiter(1, 1)
+		l.Allow("a")
+		l.Allow("b")
+
+		clock.advance(2 * time.Minute)
+		l.Allow("c")
+
+		if l.Len() != 1 {
+			t.Errorf("expected idle buckets to be evicted, got %d buckets", l.Len())
+		}
+	})
+
+	t.Run("bucket count is capped", func(t *testing.T) {
+		l, clock := newTestLimiter(1, 1)
+		l.maxKeys = 2
+		l.Allow("a")
+		clock.advance(time.Second)
+		l.Allow("b")
+		clock.advance(time.Second)
+		l.Allow("c")
+
+		if l.Len() != 2 {
+			t.Fatalf("expected at most 2 buckets, got %d", l.Len())
+		}
+		if _, ok := l.buckets["a"]; ok {
+			t.Error("expected least recently used bucket to be evicted")
+		}
+	})
+
+	t.Run("recently used buckets are kept", func(t *testing.T) {
+		l, clock := newTestLimiter(1, 1)
+		l.maxKeys = 2
+		l.Allow("a")
+		clock.advance(time.Second)
+		l.Allow("b")
+		clock.advance(time.Second)
+		l.Allow("a")
+		clock.advance(time.Second)
+		l.Allow("c")
+
+		if _, ok := l.buckets["a"]; !ok {
+			t.Error("expected the recently used bucket to be kept")
+		}
+		if _, ok := l.buckets["b"]; ok {
+			t.Error("expected least recently used bucket to be evicted")
+		}
+	})
+
+	t.Run("sweep keeps active buckets", func(t *testing.T) {
+		l, clock := newTestLimiter(1, 1)
+		l.Allow("a")
+		l.Allow("b")
+		clock.advance(50 * time.Second)
+		l.Allow("a")
+		clock.advance(40 * time.Second)
+		l.Allow("c")
+
+		if l.Len() != 2 || l.lru.Len() != 2 {
+			t.Fatalf("expected 2 buckets, got %d (%d listed)", l.Len(), l.lru.Len())
+		}
+		if _, ok := l.buckets["b"]; ok {
+			t.Error("expected the idle bucket to be swept")
+		}
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	l, _ := newTestLimiter(1, 2)
+
+	limited := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Middleware(l, func(*http.Request) string { return "key" }, limited, next)
+
+	expected := []struct {
+		status     int
+		remaining  string
+		retryAfter string
+	}{
+		{http.StatusOK, "1", ""},
+		{http.StatusOK, "0", ""},
+		{http.StatusTooManyRequests, "0", "1"},
+	}
+
+	for i, want := range expected {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rec.Code != want.status {
+			t.Errorf("request %d: expected status %d, got %d", i, want.status, rec.Code)
+		}
+		if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("request %d: expected RateLimit-Limit 2, got %s", i, got)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != want.remaining {
+			t.Errorf("request %d: expected RateLimit-Remaining %s, got %s", i, want.remaining, got)
+		}
+		if got := rec.Header().Get("Retry-After"); got != want.retryAfter {
+			t.Errorf("request %d: expected Retry-After %q, got %q", i, want.retryAfter, got)
+		}
+		if reset, err := strconv.Atoi(rec.Header().Get("RateLimit-Reset")); err != nil || reset < 0 {
+			t.Errorf("request %d: expected numeric RateLimit-Reset, got %q", i, rec.Header().Get("RateLimit-Reset"))
+		}
+		if got := rec.Header().Get("RateLimit-Policy"); got != "2;w=2" {
+			t.Errorf("request %d: expected RateLimit-Policy '2;w=2', got %q", i, got)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package render
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JSON encodes values with encoding/json. Type overrides the Content-Type
+// for JSON-based media types such as application/problem+json.
+type JSON struct {
+	Type string
+}
+
+func (j JSON) ContentType() string {
+	if j.Type != "" {
+		return j.Type
+	}
+	return "application/json"
+}
+
+func (JSON) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// XML encodes values as an element named after the value's type, with one
+// child element per field. Map entries become <entry key="..."> elements
+// and slice items become <item> elements, since neither keys nor items are
+// guaranteed to be valid XML names.
+type XML struct{}
+
+func (XML) ContentType() string { return "application/xml; charset=utf-8" }
+
+func (XML) Encode(w io.Writer, v interface{}) error {
+	value, err := normalize(v)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := encodeXML(enc, xml.StartElement{Name: xml.Name{Local: rootName(v)}}, value); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func encodeXML(enc *xml.Encoder, start xml.StartElement, value interface{}) error {
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch val := value.(type) {
+	case object:
+		for _, m := range val {
+			child := xml.StartElement{Name: xml.Name{Local: m.name}}
+			if !validXMLName(m.name) {
+				child = xml.StartElement{
+					Name: xml.Name{Local: "entry"},
+					Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: m.name}},
+				}
+			}
+			if err := encodeXML(enc, child, m.value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			if err := encodeXML(enc, xml.StartElement{Name: xml.Name{Local: "item"}}, item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := enc.EncodeToken(xml.CharData(scalarString(val))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// validXMLName reports whether name can be used as an element name. It is
+// deliberately conservative: ASCII letters, digits, '-', '_' and '.',
+// starting with a letter or underscore, and not starting with "xml".
+func validXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+		case i > 0 && (r >= '0' && r <= '9' || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// YAML encodes values as a YAML document using the JSON field names.
+type YAML struct{}
+
+func (YAML) ContentType() string { return "application/yaml" }
+
+func (YAML) Encode(w io.Writer, v interface{}) error {
+	value, err := normalize(v)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlNode(value)); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func yamlNode(value interface{}) *yaml.Node {
+	switch val := value.(type) {
+	case object:
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, m := range val {
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: m.name}, yamlNode(m.value))
+		}
+		return n
+	case []interface{}:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range val {
+			n.Content = append(n.Content, yamlNode(item))
+		}
+		return n
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: scalarString(val)}
+	case int64, uint64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: scalarString(val)}
+	case float64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: scalarString(val)}
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: scalarString(value)}
+}
+
+// Text encodes values as "key: value" lines. Nested keys are joined with
+// dots and slice items are addressed by index, so every scalar is on its own
+// greppable line:
+//
+//	message: Hello, World!
+//	details.0.field: name
+type Text struct{}
+
+func (Text) ContentType() string { return "text/plain; charset=utf-8" }
+
+func (Text) Encode(w io.Writer, v interface{}) error {
+	value, err := normalize(v)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	writeText(&b, "", value)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func writeText(b *strings.Builder, prefix string, value interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch val := value.(type) {
+	case object:
+		for _, m := range val {
+			writeText(b, join(m.name), m.value)
+		}
+	case []interface{}:
+		for i, item := range val {
+			writeText(b, join(strconv.Itoa(i)), item)
+		}
+	default:
+		if prefix != "" {
+			b.WriteString(prefix)
+			b.WriteString(": ")
+		}
+		// Keep one value per line even if it contains newlines.
+		b.WriteString(strings.ReplaceAll(scalarString(val), "\n", `\n`))
+		b.WriteByte('\n')
+	}
+}
+
+func scalarString(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case uint64:
+		return strconv.FormatUint(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,136 @@
+package render
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// MessagePack encodes values in the MessagePack binary format
+// (https://msgpack.org/), using the JSON field names as map keys.
+type MessagePack struct{}
+
+func (MessagePack) ContentType() string { return "application/msgpack" }
+
+func (MessagePack) Encode(w io.Writer, v interface{}) error {
+	value, err := normalize(v)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	if err := encodeMsgpack(bw, value); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func encodeMsgpack(w *bufio.Writer, value interface{}) error {
+	switch val := value.(type) {
+	case nil:
+		return w.WriteByte(0xc0)
+	case bool:
+		if val {
+			return w.WriteByte(0xc3)
+		}
+		return w.WriteByte(0xc2)
+	case int64:
+		if val >= 0 {
+			writeMsgpackUint(w, uint64(val))
+		} else {
+			writeMsgpackInt(w, val)
+		}
+	case uint64:
+		writeMsgpackUint(w, val)
+	case float64:
+		w.WriteByte(0xcb)
+		writeBigEndian(w, math.Float64bits(val), 8)
+	case string:
+		writeMsgpackHeader(w, len(val), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		w.WriteString(val)
+	case []interface{}:
+		writeMsgpackHeader(w, len(val), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range val {
+			if err := encodeMsgpack(w, item); err != nil {
+				return err
+			}
+		}
+	case object:
+		writeMsgpackHeader(w, len(val), 0x80, 15, 0, 0xde, 0xdf)
+		for _, m := range val {
+			if err := encodeMsgpack(w, m.name); err != nil {
+				return err
+			}
+			if err := encodeMsgpack(w, m.value); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("render: cannot encode %T as MessagePack", value)
+	}
+	return nil
+}
+
+// writeMsgpackHeader writes the length prefix of a str, array or map. fixed
+// is the fix-format base byte used for lengths up to fixMax; the 8, 16 and
+// 32-bit formats follow (b8 is 0 for types without an 8-bit format).
+func writeMsgpackHeader(w *bufio.Writer, n int, fixed byte, fixMax int, b8, b16, b32 byte) {
+	switch {
+	case n <= fixMax:
+		w.WriteByte(fixed | byte(n))
+	case b8 != 0 && n <= math.MaxUint8:
+		w.WriteByte(b8)
+		w.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		w.WriteByte(b16)
+		writeBigEndian(w, uint64(n), 2)
+	default:
+		w.WriteByte(b32)
+		writeBigEndian(w, uint64(n), 4)
+	}
+}
+
+func writeMsgpackUint(w *bufio.Writer, v uint64) {
+	switch {
+	case v <= 0x7f:
+		w.WriteByte(byte(v))
+	case v <= math.MaxUint8:
+		w.WriteByte(0xcc)
+		w.WriteByte(byte(v))
+	case v <= math.MaxUint16:
+		w.WriteByte(0xcd)
+		writeBigEndian(w, v, 2)
+	case v <= math.MaxUint32:
+		w.WriteByte(0xce)
+		writeBigEndian(w, v, 4)
+	default:
+		w.WriteByte(0xcf)
+		writeBigEndian(w, v, 8)
+	}
+}
+
+func writeMsgpackInt(w *bufio.Writer, v int64) {
+	switch {
+	case v >= -32:
+		w.WriteByte(byte(v))
+	case v >= math.MinInt8:
+		w.WriteByte(0xd0)
+		w.WriteByte(byte(v))
+	case v >= math.MinInt16:
+		w.WriteByte(0xd1)
+		writeBigEndian(w, uint64(v), 2)
+	case v >= math.MinInt32:
+		w.WriteByte(0xd2)
+		writeBigEndian(w, uint64(v), 4)
+	default:
+		w.WriteByte(0xd3)
+		writeBigEndian(w, uint64(v), 8)
+	}
+}
+
+func writeBigEndian(w *bufio.Writer, v uint64, size int) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	w.Write(buf[8-size:])
+}
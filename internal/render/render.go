@@ -0,0 +1,156 @@
+// Package render encodes response values in the media type a client asks
+// for in its Accept header, from a registry of encoders for JSON, XML, YAML,
+// MessagePack and plain text.
+package render
+
+import (
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encoder writes values in one media type.
+type Encoder interface {
+	// ContentType is the Content-Type header value of encoded responses.
+	ContentType() string
+	Encode(w io.Writer, v interface{}) error
+}
+
+// Registry maps media types to encoders. Registration order is the server's
+// preference when the client accepts several types equally.
+type Registry struct {
+	entries []entry
+}
+
+type entry struct {
+	mediaType string
+	encoder   Encoder
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Default returns a registry with JSON (preferred), XML, YAML, MessagePack
+// and plain text encoders.
+func Default() *Registry {
+	r := NewRegistry()
+	r.Register(JSON{}, "application/json")
+	r.Register(XML{}, "application/xml", "text/xml")
+	r.Register(YAML{}, "application/yaml", "application/x-yaml", "text/yaml")
+	r.Register(MessagePack{}, "application/msgpack", "application/x-msgpack", "application/vnd.msgpack")
+	r.Register(Text{}, "text/plain")
+	return r
+}
+
+// Register adds enc under each of mediaTypes.
+func (r *Registry) Register(enc Encoder, mediaTypes ...string) {
+	for _, mt := range mediaTypes {
+		r.entries = append(r.entries, entry{mediaType: strings.ToLower(mt), encoder: enc})
+	}
+}
+
+// Fallback returns the most preferred encoder, used when a client sends no
+// Accept header or for errors that must be reported in some format.
+func (r *Registry) Fallback() Encoder {
+	return r.entries[0].encoder
+}
+
+// MediaTypes returns every registered media type in preference order.
+func (r *Registry) MediaTypes() []string {
+	types := make([]string, len(r.entries))
+	for i, e := range r.entries {
+		types[i] = e.mediaType
+	}
+	return types
+}
+
+// Negotiate picks the encoder for an Accept header value following RFC 9110:
+// the most specific matching range sets a type's quality, the highest
+// quality wins, and ties go to registration order. An empty header accepts
+// anything. ok is false when no registered type is acceptable.
+func (r *Registry) Negotiate(accept string) (enc Encoder, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return r.Fallback(), true
+	}
+	ranges := parseAccept(accept)
+
+	best, bestQ := -1, 0.0
+	for i, e := range r.entries {
+		if q := quality(ranges, e.mediaType); q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	if best < 0 {
+		return nil, false
+	}
+	return r.entries[best].encoder, true
+}
+
+// Mentions reports whether accept explicitly names mediaType with a
+// non-zero quality. Wildcard ranges do not count, so clients only opt in to
+// alternative representations such as application/problem+json by asking
+// for them.
+func Mentions(accept, mediaType string) bool {
+	typ, subtype, _ := strings.Cut(strings.ToLower(mediaType), "/")
+	for _, m := range parseAccept(accept) {
+		if m.typ == typ && m.subtype == subtype {
+			return m.q > 0
+		}
+	}
+	return false
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func (m mediaRange) specificity() int {
+	switch {
+	case m.typ == "*":
+		return 0
+	case m.subtype == "*":
+		return 1
+	}
+	return 2
+}
+
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mt, "/")
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 1 {
+				q = f
+			}
+		}
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+	// Most specific ranges first so the first match determines the quality.
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+func quality(ranges []mediaRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	for _, m := range ranges {
+		if (m.typ == "*" || m.typ == typ) && (m.subtype == "*" || m.subtype == subtype) {
+			return m.q
+		}
+	}
+	return 0
+}
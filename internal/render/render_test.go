@@ -0,0 +1,200 @@
+package render
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+type greeting struct {
+	Message string            `json:"message"`
+	Locale  string            `json:"locale,omitempty"`
+	Count   int               `json:"count"`
+	Tags    []string          `json:"tags,omitempty"`
+	Extra   map[string]string `json:"extra,omitempty"`
+	Hidden  string            `json:"-"`
+	secret  string
+}
+
+func TestNegotiate(t *testing.T) {
+	r := Default()
+
+	tests := []struct {
+		name     string
+		accept   string
+		expected string
+	}{
+		{name: "no header", accept: "", expected: "application/json"},
+		{name: "any", accept: "*/*", expected: "application/json"},
+		{name: "exact XML", accept: "application/xml", expected: "application/xml; charset=utf-8"},
+		{name: "XML alias", accept: "text/xml", expected: "application/xml; charset=utf-8"},
+		{name: "YAML alias", accept: "application/x-yaml", expected: "application/yaml"},
+		{name: "MessagePack", accept: "application/vnd.msgpack", expected: "application/msgpack"},
+		{name: "text wildcard subtype", accept: "text/*", expected: "application/xml; charset=utf-8"},
+		{name: "plain text", accept: "text/plain", expected: "text/plain; charset=utf-8"},
+		{name: "quality order", accept: "application/json;q=0.5, application/yaml", expected: "application/yaml"},
+		{name: "case insensitive", accept: "Application/YAML", expected: "application/yaml"},
+		{name: "specific range overrides wildcard", accept: "*/*;q=0.9, application/json;q=0.1", expected: "application/xml; charset=utf-8"},
+		{name: "browser", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", expected: "application/xml; charset=utf-8"},
+		{name: "malformed ranges skipped", accept: "garbage, text/plain", expected: "text/plain; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, ok := r.Negotiate(tt.accept)
+			if !ok {
+				t.Fatal("expected an encoder")
+			}
+			if enc.ContentType() != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, enc.ContentType())
+			}
+		})
+	}
+}
+
+func TestNegotiateNotAcceptable(t *testing.T) {
+	r := Default()
+	for _, accept := range []string{"image/png", "application/json;q=0", "text/html, application/pdf"} {
+		if enc, ok := r.Negotiate(accept); ok {
+			t.Errorf("expected %q to be unacceptable, got %s", accept, enc.ContentType())
+		}
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	v := greeting{
+		Message: "Hello, <World> & \"friends\"",
+		Count:   2,
+		Tags:    []string{"a", "b"},
+		Extra:   map[string]string{"X-Custom": "1", "1st key": "2"},
+		Hidden:  "hidden",
+		secret:  "secret",
+	}
+
+	tests := []struct {
+		name     string
+		encoder  Encoder
+		expected string
+	}{
+		{
+			name:    "XML",
+			encoder: XML{},
+			expected: `<?xml version="1.0" encoding="UTF-8"?>
+<greeting>
+  <message>Hello, &lt;World&gt; &amp; &#34;friends&#34;</message>
+  <count>2</count>
+  <tags>
+    <item>a</item>
+    <item>b</item>
+  </tags>
+  <extra>
+    <entry key="1st key">2</entry>
+    <X-Custom>1</X-Custom>
+  </extra>
+</greeting>
+`,
+		},
+		{
+			name:    "YAML",
+			encoder: YAML{},
+			expected: `message: Hello, <World> & "friends"
+count: 2
+tags:
+  - a
+  - b
+extra:
+  1st key: "2"
+  X-Custom: "1"
+`,
+		},
+		{
+			name:    "Text",
+			encoder: Text{},
+			expected: `message: Hello, <World> & "friends"
+count: 2
+tags.0: a
+tags.1: b
+extra.1st key: 2
+extra.X-Custom: 1
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.encoder.Encode(&buf, v); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestMessagePack(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{name: "nil", value: nil, expected: "c0"},
+		{name: "bools", value: []bool{true, false}, expected: "92c3c2"},
+		{name: "positive fixint", value: 7, expected: "07"},
+		{name: "uint8", value: 200, expected: "ccc8"},
+		{name: "uint16", value: 1000, expected: "cd03e8"},
+		{name: "uint32", value: 70000, expected: "ce00011170"},
+		{name: "negative fixint", value: -3, expected: "fd"},
+		{name: "int8", value: -100, expected: "d09c"},
+		{name: "int16", value: -1000, expected: "d1fc18"},
+		{name: "float64", value: 1.5, expected: "cb3ff8000000000000"},
+		{name: "fixstr", value: "hi", expected: "a26869"},
+		{name: "str8", value: string(bytes.Repeat([]byte("x"), 40)), expected: "d928" + hex.EncodeToString(bytes.Repeat([]byte("x"), 40))},
+		{
+			name:  "struct as map",
+			value: greeting{Message: "hi", Count: 1},
+			// {"message": "hi", "count": 1}
+			expected: "82" + "a76d657373616765" + "a26869" + "a5636f756e74" + "01",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := (MessagePack{}).Encode(&buf, tt.value); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := hex.EncodeToString(buf.Bytes()); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestUnsupportedValue(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (YAML{}).Encode(&buf, map[int]string{1: "a"}); err == nil {
+		t.Error("expected an error for non-string map keys")
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected bool
+	}{
+		{"application/problem+json", true},
+		{"application/json, Application/Problem+JSON;q=0.5", true},
+		{"application/problem+json;q=0", false},
+		{"*/*", false},
+		{"application/*", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := Mentions(tt.accept, "application/problem+json"); got != tt.expected {
+			t.Errorf("Mentions(%q): expected %v, got %v", tt.accept, tt.expected, got)
+		}
+	}
+}
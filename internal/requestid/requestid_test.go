@@ -0,0 +1,96 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	id := New()
+	if len(id) != 26 {
+		t.Fatalf("expected 26 character ID, got %q", id)
+	}
+	for _, c := range id {
+		if !strings.ContainsRune(crockford, c) {
+			t.Errorf("unexpected character %q in ID %q", c, id)
+		}
+	}
+	if New() == id {
+		t.Error("expected IDs to be unique")
+	}
+}
+
+func TestNewSortsByTime(t *testing.T) {
+	earlier := newAt(time.UnixMilli(1700000000000))
+	later := newAt(time.UnixMilli(1700000000001))
+	if earlier[:10] >= later[:10] {
+		t.Errorf("expected %q to sort before %q", earlier, later)
+	}
+	if earlier[:10] != "01HF7YAT00" {
+		t.Errorf("expected timestamp prefix 01HF7YAT00, got %q", earlier[:10])
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"01HF7YAT00ABCDEFGHJKMNPQRS", true},
+		{"gateway-1234", true},
+		{"", false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{"unicode-é", false},
+		{strings.Repeat("a", maxLength), true},
+		{strings.Repeat("a", maxLength+1), false},
+	}
+
+	for _, tt := range tests {
+		if got := Valid(tt.id); got != tt.valid {
+			t.Errorf("Valid(%q) = %v, want %v", tt.id, got, tt.valid)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		adopt    bool
+	}{
+		{name: "adopts incoming ID", incoming: "gateway-1234", adopt: true},
+		{name: "generates ID when missing", incoming: "", adopt: false},
+		{name: "replaces invalid ID", incoming: "bad id", adopt: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(Header, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			echoed := rec.Header().Get(Header)
+			if echoed == "" || echoed != seen {
+				t.Errorf("expected response header %q to match context ID %q", echoed, seen)
+			}
+			if tt.adopt && seen != tt.incoming {
+				t.Errorf("expected incoming ID %q to be adopted, got %q", tt.incoming, seen)
+			}
+			if !tt.adopt && len(seen) != 26 {
+				t.Errorf("expected generated ID, got %q", seen)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Package sse writes Server-Sent Events in the text/event-stream format
+// defined by the HTML Living Standard.
+package sse
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of an event stream.
+const ContentType = "text/event-stream"
+
+// LastEventIDHeader carries the ID of the last event a reconnecting client
+// received.
+const LastEventIDHeader = "Last-Event-ID"
+
+// Event is a single message on an event stream. Empty fields are omitted;
+// Data may span several lines.
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration
+}
+
+// Writer sends events to a client, flushing after each one so they are
+// delivered immediately.
+type Writer struct {
+	w            http.ResponseWriter
+	rc           *http.ResponseController
+	writeTimeout time.Duration
+	started      bool
+}
+
+// NewWriter returns a Writer for w. When writeTimeout is positive, every
+// event must be written within writeTimeout: the connection's write
+// deadline is pushed back before each write, so the server's WriteTimeout
+// bounds a stalled client rather than the lifetime of the stream.
+func NewWriter(w http.ResponseWriter, writeTimeout time.Duration) *Writer {
+	return &Writer{w: w, rc: http.NewResponseController(w), writeTimeout: writeTimeout}
+}
+
+// Start sends the response header. It is called by the first Send or
+// Comment if needed.
+func (sw *Writer) Start() error {
+	if sw.started {
+		return nil
+	}
+	sw.started = true
+
+	h := sw.w.Header()
+	h.Set("Content-Type", ContentType)
+	h.Set("Cache-Control", "no-cache")
+	// Stops nginx and similar proxies from buffering the stream.
+	h.Set("X-Accel-Buffering", "no")
+	sw.w.WriteHeader(http.StatusOK)
+	return sw.flush()
+}
+
+// Send writes e to the stream.
+func (sw *Writer) Send(e Event) error {
+	var b strings.Builder
+	if e.ID != "" {
+		writeField(&b, "id", e.ID)
+	}
+	if e.Event != "" {
+		writeField(&b, "event", e.Event)
+	}
+	if e.Retry > 0 {
+		writeField(&b, "retry", strconv.FormatInt(e.Retry.Milliseconds(), 10))
+	}
+	for _, line := range splitLines(e.Data) {
+		writeField(&b, "data", line)
+	}
+	b.WriteByte('\n')
+	return sw.write(b.String())
+}
+
+// Comment writes a comment line, which clients ignore. Comments keep idle
+// connections open through proxies that time out silent streams.
+func (sw *Writer) Comment(text string) error {
+	var b strings.Builder
+	for _, line := range splitLines(text) {
+		b.WriteString(": ")
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	return sw.write(b.String())
+}
+
+func (sw *Writer) write(s string) error {
+	if err := sw.Start(); err != nil {
+		return err
+	}
+	if err := sw.extendDeadline(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(sw.w, s); err != nil {
+		return err
+	}
+	return sw.flush()
+}
+
+func (sw *Writer) extendDeadline() error {
+	if sw.writeTimeout <= 0 {
+		return nil
+	}
+	err := sw.rc.SetWriteDeadline(time.Now().Add(sw.writeTimeout))
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
+
+func (sw *Writer) flush() error {
+	err := sw.rc.Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
+
+// writeField writes a "name: value" line. Line breaks cannot be escaped in
+// single-line fields, so they are dropped.
+func writeField(b *strings.Builder, name, value string) {
+	b.WriteString(name)
+	b.WriteString(": ")
+	b.WriteString(strings.NewReplacer("\r", "", "\n", "").Replace(value))
+	b.WriteByte('\n')
+}
+
+// splitLines splits s on CRLF, CR or LF, the line endings the event stream
+// format recognizes.
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.Split(s, "\n")
+}
+
+// LastEventID returns the ID a reconnecting client last received, or "" on
+// the first connection.
+func LastEventID(r *http.Request) string {
+	return r.Header.Get(LastEventIDHeader)
+}
@@ -0,0 +1,128 @@
+package sse
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    Event
+		expected string
+	}{
+		{
+			name:     "data only",
+			event:    Event{Data: "hello"},
+			expected: "data: hello\n\n",
+		},
+		{
+			name:     "all fields",
+			event:    Event{ID: "7", Event: "greeting", Data: `{"message":"Hello"}`, Retry: 3 * time.Second},
+			expected: "id: 7\nevent: greeting\nretry: 3000\ndata: {\"message\":\"Hello\"}\n\n",
+		},
+		{
+			name:     "multi-line data",
+			event:    Event{Data: "one\r\ntwo\rthree\nfour"},
+			expected: "data: one\ndata: two\ndata: three\ndata: four\n\n",
+		},
+		{
+			name:     "line breaks dropped from single-line fields",
+			event:    Event{ID: "1\n2", Event: "a\r\nb", Data: "x"},
+			expected: "id: 12\nevent: ab\ndata: x\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			if err := NewWriter(rec, time.Second).Send(tt.event); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := rec.Body.String(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestComment(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := NewWriter(rec, 0).Comment("heartbeat"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := rec.Body.String(); got != ": heartbeat\n\n" {
+		t.Errorf("expected heartbeat comment, got %q", got)
+	}
+}
+
+func TestStartHeaders(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sw := NewWriter(rec, 0)
+	if err := sw.Start(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sw.Send(Event{Data: "x"})
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if !rec.Flushed {
+		t.Error("expected the stream to be flushed")
+	}
+	for header, want := range map[string]string{
+		"Content-Type":      ContentType,
+		"Cache-Control":     "no-cache",
+		"X-Accel-Buffering": "no",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("expected %s '%s', got '%s'", header, want, got)
+		}
+	}
+}
+
+func TestWriteDeadline(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := NewWriter(w, 50*time.Millisecond)
+		// Each event pushes the deadline back, so the stream outlives the
+		// write timeout.
+		for i := 0; i < 5; i++ {
+			if err := sw.Send(Event{Data: "tick"}); err != nil {
+				t.Errorf("unexpected error on event %d: %v", i, err)
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read stream: %v", err)
+	}
+	if got, want := len(body), 5*len("data: tick\n\n"); got != want {
+		t.Errorf("expected %d bytes, got %d: %q", want, got, body)
+	}
+}
+
+func TestLastEventID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if id := LastEventID(req); id != "" {
+		t.Errorf("expected no ID, got '%s'", id)
+	}
+	req.Header.Set(LastEventIDHeader, "42")
+	if id := LastEventID(req); id != "42" {
+		t.Errorf("expected ID '42', got '%s'", id)
+	}
+}
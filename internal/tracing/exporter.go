@@ -0,0 +1,200 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Exporter sends finished spans to a backend.
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+type noopExporter struct{}
+
+func (noopExporter) Export(context.Context, []SpanData) error { return nil }
+
+func (noopExporter) Shutdown(context.Context) error { return nil }
+
+// StdoutExporter writes each span as a JSON line, for local debugging.
+type StdoutExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewStdoutExporter returns an exporter writing to w.
+func NewStdoutExporter(w io.Writer) *StdoutExporter {
+	return &StdoutExporter{w: w}
+}
+
+type stdoutSpan struct {
+	Name          string                 `json:"name"`
+	TraceID       string                 `json:"trace_id"`
+	SpanID        string                 `json:"span_id"`
+	ParentSpanID  string                 `json:"parent_span_id,omitempty"`
+	Kind          SpanKind               `json:"kind"`
+	Start         time.Time              `json:"start"`
+	End           time.Time              `json:"end"`
+	DurationMS    float64                `json:"duration_ms"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	StatusCode    StatusCode             `json:"status_code"`
+	StatusMessage string                 `json:"status_message,omitempty"`
+}
+
+// Export implements Exporter.
+func (e *StdoutExporter) Export(_ context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	encoder := json.NewEncoder(e.w)
+	for _, s := range spans {
+		out := stdoutSpan{
+			Name:          s.Name,
+			TraceID:       s.SpanContext.TraceID.String(),
+			SpanID:        s.SpanContext.SpanID.String(),
+			Kind:          s.Kind,
+			Start:         s.StartTime,
+			End:           s.EndTime,
+			DurationMS:    float64(s.EndTime.Sub(s.StartTime).Microseconds()) / 1000,
+			StatusCode:    s.StatusCode,
+			StatusMessage: s.StatusMessage,
+		}
+		if s.ParentSpanID.IsValid() {
+			out.ParentSpanID = s.ParentSpanID.String()
+		}
+		if len(s.Attributes) > 0 {
+			out.Attributes = make(map[string]interface{}, len(s.Attributes))
+			for _, attr := range s.Attributes {
+				out.Attributes[attr.Key] = attr.Value
+			}
+		}
+		if err := encoder.Encode(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown implements Exporter.
+func (e *StdoutExporter) Shutdown(context.Context) error { return nil }
+
+// Batch processor defaults.
+const (
+	defaultBatchTimeout = 5 * time.Second
+	defaultMaxQueueSize = 2048
+	defaultMaxBatchSize = 512
+)
+
+// batchProcessor queues ended spans and exports them in the background so
+// request latency never depends on the tracing backend.
+type batchProcessor struct {
+	exporter Exporter
+	interval time.Duration
+	maxQueue int
+	maxBatch int
+	onError  func(error)
+
+	mu      sync.Mutex
+	queue   []SpanData
+	stopped bool
+
+	kick chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newBatchProcessor(exporter Exporter) *batchProcessor {
+	return &batchProcessor{
+		exporter: exporter,
+		interval: defaultBatchTimeout,
+		maxQueue: defaultMaxQueueSize,
+		maxBatch: defaultMaxBatchSize,
+		onError:  func(error) {},
+		kick:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (p *batchProcessor) start() {
+	go p.loop()
+}
+
+// onEnd enqueues a span, dropping it if the queue is full or the processor
+// has shut down.
+func (p *batchProcessor) onEnd(span SpanData) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped || len(p.queue) >= p.maxQueue {
+		return
+	}
+	p.queue = append(p.queue, span)
+	if len(p.queue) >= p.maxBatch {
+		select {
+		case p.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (p *batchProcessor) loop() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.flush(context.Background())
+		case <-p.kick:
+			p.flush(context.Background())
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *batchProcessor) flush(ctx context.Context) {
+	for {
+		p.mu.Lock()
+		n := len(p.queue)
+		if n > p.maxBatch {
+			n = p.maxBatch
+		}
+		batch := append([]SpanData(nil), p.queue[:n]...)
+		p.queue = p.queue[n:]
+		p.mu.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.exporter.Export(ctx, batch); err != nil {
+			p.onError(err)
+		}
+	}
+}
+
+func (p *batchProcessor) shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return nil
+	}
+	p.stopped = true
+	p.mu.Unlock()
+
+	close(p.stop)
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	p.flush(ctx)
+	return p.exporter.Shutdown(ctx)
+}
@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// Middleware starts a server span named "METHOD route" for every request,
+// continuing any incoming W3C trace context, and records the route and
+// response status on it.
+func Middleware(tracer *Tracer, route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if remote, ok := Extract(r.Header); ok {
+			ctx = ContextWithRemoteSpanContext(ctx, remote)
+		}
+
+		ctx, span := tracer.Start(ctx, r.Method+" "+route, SpanKindServer,
+			String("http.request.method", r.Method),
+			String("http.route", route),
+			String("url.path", r.URL.Path),
+			String("server.address", r.Host),
+			String("client.address", r.RemoteAddr),
+			String("user_agent.original", r.UserAgent()),
+		)
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(Int("http.response.status_code", recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(StatusError, http.StatusText(recorder.status))
+		}
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Hijack records the switch to another protocol before handing over the
+// connection.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// collector is a stand-in for an OpenTelemetry collector's OTLP/HTTP
+// receiver.
+type collector struct {
+	server   *httptest.Server
+	status   int
+	requests []otlpRequest
+	headers  []http.Header
+}
+
+func newCollector(t *testing.T, status int) *collector {
+	c := &collector{status: status}
+	c.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/traces" {
+			http.NotFound(w, r)
+			return
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.requests = append(c.requests, req)
+		c.headers = append(c.headers, r.Header.Clone())
+		w.WriteHeader(c.status)
+	}))
+	t.Cleanup(c.server.Close)
+	return c
+}
+
+func TestOTLPExporter(t *testing.T) {
+	c := newCollector(t, http.StatusOK)
+
+	exporter, err := NewOTLPExporter(c.server.URL, "hello-api", map[string]string{"Authorization": "Bearer token"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tracer := NewTracer(exporter)
+
+	remote, _ := ParseTraceparent(validTraceparent)
+	_, span := tracer.Start(ContextWithRemoteSpanContext(context.Background(), remote), "GET /hello", SpanKindServer,
+		String("http.route", "/hello"), Int("http.response.status_code", 200), Bool("cached", false))
+	span.SetStatus(StatusOK, "")
+	span.End()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	if len(c.requests) != 1 {
+		t.Fatalf("expected 1 export request, got %d", len(c.requests))
+	}
+	if c.headers[0].Get("Content-Type") != "application/json" || c.headers[0].Get("Authorization") != "Bearer token" {
+		t.Errorf("unexpected export headers %v", c.headers[0])
+	}
+
+	rs := c.requests[0].ResourceSpans
+	if len(rs) != 1 || len(rs[0].ScopeSpans) != 1 || len(rs[0].ScopeSpans[0].Spans) != 1 {
+		t.Fatalf("unexpected export payload %+v", c.requests[0])
+	}
+	if attr := rs[0].Resource.Attributes[0]; attr.Key != "service.name" || *attr.Value.StringValue != "hello-api" {
+		t.Errorf("expected service.name resource attribute, got %+v", attr)
+	}
+
+	got := rs[0].ScopeSpans[0].Spans[0]
+	if got.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || got.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("unexpected span IDs %+v", got)
+	}
+	if got.Kind != SpanKindServer || got.Status.Code != StatusOK || got.Name != "GET /hello" {
+		t.Errorf("unexpected span %+v", got)
+	}
+	if got.StartTimeUnixNano == "" || got.EndTimeUnixNano < got.StartTimeUnixNano {
+		t.Errorf("unexpected span times %s-%s", got.StartTimeUnixNano, got.EndTimeUnixNano)
+	}
+	if len(got.Attributes) != 3 || *got.Attributes[1].Value.IntValue != "200" || *got.Attributes[2].Value.BoolValue {
+		t.Errorf("unexpected span attributes %+v", got.Attributes)
+	}
+}
+
+func TestOTLPExporterErrors(t *testing.T) {
+	if _, err := NewOTLPExporter("collector:4318", "hello-api", nil); err == nil {
+		t.Error("expected relative endpoint to be rejected")
+	}
+
+	c := newCollector(t, http.StatusServiceUnavailable)
+	exporter, err := NewOTLPExporter(c.server.URL, "hello-api", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var exportErr error
+	tracer := NewTracer(exporter, WithErrorHandler(func(err error) { exportErr = err }))
+	_, span := tracer.Start(context.Background(), "span", SpanKindInternal)
+	span.End()
+	tracer.Shutdown(context.Background())
+
+	if exportErr == nil || !strings.Contains(exportErr.Error(), "503") {
+		t.Errorf("expected collector error to reach the error handler, got %v", exportErr)
+	}
+}
@@ -0,0 +1,170 @@
+package tracing
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// W3C Trace Context header names.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// maxTracestateMembers is the list-member limit from the W3C specification.
+const maxTracestateMembers = 32
+
+var errInvalidTraceparent = errors.New("tracing: invalid traceparent")
+
+// ParseTraceparent parses a W3C traceparent header value such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+
+	value = strings.TrimSpace(value)
+	if len(value) < 55 {
+		return sc, errInvalidTraceparent
+	}
+
+	version, err := decodeHexByte(value[0:2])
+	if err != nil || version == 0xff {
+		return sc, errInvalidTraceparent
+	}
+	// Version 00 is exactly 55 characters; later versions may append fields
+	// after another dash, which we ignore as the specification requires.
+	if version == 0 && len(value) != 55 {
+		return sc, errInvalidTraceparent
+	}
+	if len(value) > 55 && value[55] != '-' {
+		return sc, errInvalidTraceparent
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return sc, errInvalidTraceparent
+	}
+
+	if !isLowerHex(value[3:35]) || !isLowerHex(value[36:52]) {
+		return sc, errInvalidTraceparent
+	}
+	hex.Decode(sc.TraceID[:], []byte(value[3:35]))
+	hex.Decode(sc.SpanID[:], []byte(value[36:52]))
+	if !sc.TraceID.IsValid() || !sc.SpanID.IsValid() {
+		return sc, errInvalidTraceparent
+	}
+
+	flags, err := decodeHexByte(value[53:55])
+	if err != nil {
+		return sc, errInvalidTraceparent
+	}
+	sc.Flags = TraceFlags(flags)
+	sc.Remote = true
+	return sc, nil
+}
+
+// Traceparent formats sc as a version 00 traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{byte(sc.Flags)})
+}
+
+// ParseTracestate validates a tracestate header value and returns it in
+// canonical form. Invalid values are dropped entirely, as the specification
+// recommends, and an empty string is returned.
+func ParseTracestate(value string) string {
+	var members []string
+	seen := make(map[string]bool)
+
+	for _, member := range strings.Split(value, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(member, "=")
+		if !ok || !validTracestateKey(key) || !validTracestateValue(val) || seen[key] {
+			return ""
+		}
+		seen[key] = true
+		members = append(members, key+"="+val)
+	}
+
+	if len(members) > maxTracestateMembers {
+		return ""
+	}
+	return strings.Join(members, ",")
+}
+
+func validTracestateKey(key string) bool {
+	if key == "" || len(key) > 256 {
+		return false
+	}
+	tenant, system, multi := strings.Cut(key, "@")
+	if multi && (tenant == "" || len(tenant) > 241 || system == "" || len(system) > 14) {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '_' || c == '-' || c == '*' || c == '/' || c == '@':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func validTracestateValue(val string) bool {
+	if val == "" || len(val) > 256 || val[len(val)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(val); i++ {
+		c := val[i]
+		if c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
+
+// Extract reads the W3C trace context from h. ok is false when no valid
+// traceparent is present, in which case tracestate is ignored too.
+func Extract(h http.Header) (sc SpanContext, ok bool) {
+	sc, err := ParseTraceparent(h.Get(TraceparentHeader))
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc.TraceState = ParseTracestate(strings.Join(h.Values(TracestateHeader), ","))
+	return sc, true
+}
+
+// Inject writes the trace context of sc into h for an outgoing request.
+func Inject(sc SpanContext, h http.Header) {
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		h.Set(TracestateHeader, sc.TraceState)
+	} else {
+		h.Del(TracestateHeader)
+	}
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func decodeHexByte(s string) (byte, error) {
+	if !isLowerHex(s) {
+		return 0, errInvalidTraceparent
+	}
+	var b [1]byte
+	_, err := hex.Decode(b[:], []byte(s))
+	return b[0], err
+}
@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"net/http"
+	"testing"
+)
+
+const validTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		valid   bool
+		sampled bool
+	}{
+		{name: "valid sampled", value: validTraceparent, valid: true, sampled: true},
+		{name: "valid unsampled", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", valid: true},
+		{name: "future version with extra fields", value: "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", valid: true, sampled: true},
+		{name: "version 00 with extra fields", value: validTraceparent + "-extra"},
+		{name: "forbidden version ff", value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "uppercase hex", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{name: "zero trace ID", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "zero span ID", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{name: "wrong separators", value: "00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01"},
+		{name: "too short", value: "00-4bf92f35-00f067aa-01"},
+		{name: "empty", value: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.value)
+			if tt.valid != (err == nil) {
+				t.Fatalf("expected valid=%v, got error %v", tt.valid, err)
+			}
+			if !tt.valid {
+				return
+			}
+			if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("unexpected trace ID %s", sc.TraceID)
+			}
+			if sc.SpanID.String() != "00f067aa0ba902b7" {
+				t.Errorf("unexpected span ID %s", sc.SpanID)
+			}
+			if sc.Flags.IsSampled() != tt.sampled {
+				t.Errorf("expected sampled=%v, got %v", tt.sampled, sc.Flags.IsSampled())
+			}
+			if !sc.Remote {
+				t.Error("expected parsed span context to be remote")
+			}
+		})
+	}
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	sc, err := ParseTraceparent(validTraceparent)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := sc.Traceparent(); got != validTraceparent {
+		t.Errorf("expected %s, got %s", validTraceparent, got)
+	}
+}
+
+func TestParseTracestate(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"congo=t61rcWkgMzE", "congo=t61rcWkgMzE"},
+		{"rojo=00f067aa0ba902b7, congo=t61rcWkgMzE", "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE"},
+		{"tenant@vendor=value", "tenant@vendor=value"},
+		{"", ""},
+		{"UPPER=value", ""},
+		{"novalue", ""},
+		{"dup=1,dup=2", ""},
+		{"key=bad,value", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ParseTracestate(tt.value); got != tt.expected {
+			t.Errorf("ParseTracestate(%q) = %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	in := http.Header{}
+	in.Set(TraceparentHeader, validTraceparent)
+	in.Add(TracestateHeader, "rojo=00f067aa0ba902b7")
+	in.Add(TracestateHeader, "congo=t61rcWkgMzE")
+
+	sc, ok := Extract(in)
+	if !ok {
+		t.Fatal("expected trace context to be extracted")
+	}
+	if sc.TraceState != "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE" {
+		t.Errorf("unexpected tracestate %q", sc.TraceState)
+	}
+
+	out := http.Header{}
+	Inject(sc, out)
+	if out.Get(TraceparentHeader) != validTraceparent {
+		t.Errorf("expected injected traceparent %s, got %s", validTraceparent, out.Get(TraceparentHeader))
+	}
+	if out.Get(TracestateHeader) != sc.TraceState {
+		t.Errorf("expected injected tracestate %s, got %s", sc.TraceState, out.Get(TracestateHeader))
+	}
+
+	if _, ok := Extract(http.Header{TracestateHeader: {"congo=t61rcWkgMzE"}}); ok {
+		t.Error("expected tracestate without traceparent to be ignored")
+	}
+}
@@ -0,0 +1,305 @@
+// Package tracing implements OpenTelemetry-style distributed tracing: W3C
+// Trace Context propagation, spans created per request and pluggable
+// exporters, including OTLP/HTTP and stdout.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a whole trace across services.
+type TraceID [16]byte
+
+// IsValid reports whether id is not all zeros.
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// String returns id as 32 lowercase hex characters.
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanID identifies a single span within a trace.
+type SpanID [8]byte
+
+// IsValid reports whether id is not all zeros.
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// String returns id as 16 lowercase hex characters.
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// TraceFlags are the W3C trace flags; only the sampled bit is defined.
+type TraceFlags byte
+
+// FlagsSampled marks a trace whose spans are recorded and exported.
+const FlagsSampled TraceFlags = 0x01
+
+// IsSampled reports whether the sampled bit is set.
+func (f TraceFlags) IsSampled() bool { return f&FlagsSampled != 0 }
+
+// SpanContext is the part of a span that crosses process boundaries.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      TraceFlags
+	TraceState string
+	Remote     bool
+}
+
+// IsValid reports whether sc has both a trace and a span ID.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// SpanKind describes the relationship of a span to its caller, using the
+// OTLP numbering.
+type SpanKind int
+
+// Span kinds.
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// StatusCode is the outcome of a span, using the OTLP numbering.
+type StatusCode int
+
+// Span status codes.
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// Attribute is a key/value pair attached to a span. Value is a string,
+// int64, float64 or bool.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute { return Attribute{Key: key, Value: value} }
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute { return Attribute{Key: key, Value: int64(value)} }
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute { return Attribute{Key: key, Value: value} }
+
+// SpanData is an immutable snapshot of an ended span handed to exporters.
+type SpanData struct {
+	Name          string
+	SpanContext   SpanContext
+	ParentSpanID  SpanID
+	Kind          SpanKind
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    []Attribute
+	StatusCode    StatusCode
+	StatusMessage string
+}
+
+// Span is an operation in progress. It is safe for concurrent use.
+type Span struct {
+	tracer *Tracer
+
+	mu   sync.Mutex
+	data SpanData
+	done bool
+}
+
+// SpanContext returns the span's propagation context.
+func (s *Span) SpanContext() SpanContext {
+	return s.data.SpanContext
+}
+
+// SetAttributes adds or replaces attributes on the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return
+	}
+	for _, attr := range attrs {
+		replaced := false
+		for i := range s.data.Attributes {
+			if s.data.Attributes[i].Key == attr.Key {
+				s.data.Attributes[i] = attr
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			s.data.Attributes = append(s.data.Attributes, attr)
+		}
+	}
+}
+
+// SetStatus records the outcome of the span.
+func (s *Span) SetStatus(code StatusCode, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return
+	}
+	s.data.StatusCode = code
+	s.data.StatusMessage = message
+}
+
+// End finishes the span and hands it to the exporter if it is sampled.
+// Calls after the first are ignored.
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return
+	}
+	s.done = true
+	s.data.EndTime = s.tracer.now()
+	data := s.data
+	s.mu.Unlock()
+
+	if data.SpanContext.Flags.IsSampled() {
+		s.tracer.processor.onEnd(data)
+	}
+}
+
+// Tracer creates spans and forwards finished ones to an exporter.
+type Tracer struct {
+	processor   *batchProcessor
+	sampleRatio float64
+	now         func() time.Time
+}
+
+// Option configures a Tracer.
+type Option func(*Tracer, *batchProcessor)
+
+// WithSampleRatio sets the fraction of new traces that are sampled. Requests
+// that arrive with a traceparent follow the caller's sampling decision.
+func WithSampleRatio(ratio float64) Option {
+	return func(t *Tracer, _ *batchProcessor) { t.sampleRatio = ratio }
+}
+
+// WithBatchTimeout sets how often queued spans are exported.
+func WithBatchTimeout(d time.Duration) Option {
+	return func(_ *Tracer, p *batchProcessor) { p.interval = d }
+}
+
+// WithErrorHandler receives export errors, which are otherwise dropped.
+func WithErrorHandler(fn func(error)) Option {
+	return func(_ *Tracer, p *batchProcessor) { p.onError = fn }
+}
+
+// NewTracer returns a Tracer exporting through exporter. A nil exporter
+// still produces span contexts for propagation and log correlation but
+// exports nothing.
+func NewTracer(exporter Exporter, opts ...Option) *Tracer {
+	if exporter == nil {
+		exporter = noopExporter{}
+	}
+	t := &Tracer{sampleRatio: 1, now: time.Now}
+	p := newBatchProcessor(exporter)
+	for _, opt := range opts {
+		opt(t, p)
+	}
+	t.processor = p
+	p.start()
+	return t
+}
+
+// Start begins a span as a child of the span or remote span context in ctx
+// and returns a context carrying the new span.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+
+	sc := SpanContext{SpanID: newSpanID()}
+	var parentID SpanID
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Flags = parent.Flags
+		sc.TraceState = parent.TraceState
+		parentID = parent.SpanID
+	} else {
+		sc.TraceID = newTraceID()
+		if t.shouldSample(sc.TraceID) {
+			sc.Flags |= FlagsSampled
+		}
+	}
+
+	span := &Span{
+		tracer: t,
+		data: SpanData{
+			Name:         name,
+			SpanContext:  sc,
+			ParentSpanID: parentID,
+			Kind:         kind,
+			StartTime:    t.now(),
+			Attributes:   append([]Attribute(nil), attrs...),
+		},
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// shouldSample makes a deterministic decision from the trace ID so every
+// service sharing the ratio agrees on the same traces.
+func (t *Tracer) shouldSample(id TraceID) bool {
+	switch {
+	case t.sampleRatio >= 1:
+		return true
+	case t.sampleRatio <= 0:
+		return false
+	}
+	bound := uint64(t.sampleRatio * (1 << 63))
+	return binary.BigEndian.Uint64(id[8:])>>1 < bound
+}
+
+// Shutdown flushes queued spans and shuts down the exporter.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.processor.shutdown(ctx)
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// ContextWithRemoteSpanContext returns a copy of ctx whose next span will be
+// a child of the remote sc.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanFromContext returns the current span, or nil if there is none.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// SpanContextFromContext returns the context of the current span, falling
+// back to a remote parent extracted from an incoming request.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,190 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// recordingExporter keeps exported spans in memory.
+type recordingExporter struct {
+	mu       sync.Mutex
+	spans    []SpanData
+	shutdown bool
+}
+
+func (e *recordingExporter) Export(_ context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdown = true
+	return nil
+}
+
+func TestTracerStart(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter)
+
+	ctx, parent := tracer.Start(context.Background(), "parent", SpanKindServer)
+	_, child := tracer.Start(ctx, "child", SpanKindInternal, String("key", "value"))
+	child.SetAttributes(Int("count", 1), String("key", "replaced"))
+	child.End()
+	child.End()
+	parent.End()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	if !exporter.shutdown {
+		t.Error("expected exporter to be shut down")
+	}
+	if len(exporter.spans) != 2 {
+		t.Fatalf("expected 2 exported spans, got %d", len(exporter.spans))
+	}
+
+	childData, parentData := exporter.spans[0], exporter.spans[1]
+	if childData.SpanContext.TraceID != parentData.SpanContext.TraceID {
+		t.Error("expected child to share the parent's trace ID")
+	}
+	if childData.ParentSpanID != parentData.SpanContext.SpanID {
+		t.Error("expected child's parent span ID to be the parent's span ID")
+	}
+	if parentData.ParentSpanID.IsValid() {
+		t.Error("expected root span to have no parent")
+	}
+	if len(childData.Attributes) != 2 || childData.Attributes[0].Value != "replaced" {
+		t.Errorf("unexpected child attributes %+v", childData.Attributes)
+	}
+}
+
+func TestTracerRemoteParent(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter)
+
+	remote, _ := ParseTraceparent(validTraceparent)
+	remote.TraceState = "congo=t61rcWkgMzE"
+	_, span := tracer.Start(ContextWithRemoteSpanContext(context.Background(), remote), "server", SpanKindServer)
+	span.End()
+	tracer.Shutdown(context.Background())
+
+	if len(exporter.spans) != 1 {
+		t.Fatalf("expected 1 exported span, got %d", len(exporter.spans))
+	}
+	got := exporter.spans[0]
+	if got.SpanContext.TraceID != remote.TraceID || got.ParentSpanID != remote.SpanID {
+		t.Errorf("expected span to continue remote trace, got %+v", got.SpanContext)
+	}
+	if got.SpanContext.TraceState != remote.TraceState {
+		t.Errorf("expected tracestate to propagate, got %q", got.SpanContext.TraceState)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	tests := []struct {
+		name     string
+		ratio    float64
+		parent   string
+		exported int
+	}{
+		{name: "ratio zero drops new traces", ratio: 0, exported: 0},
+		{name: "ratio one keeps new traces", ratio: 1, exported: 1},
+		{name: "sampled parent overrides ratio", ratio: 0, parent: validTraceparent, exported: 1},
+		{name: "unsampled parent overrides ratio", ratio: 1, parent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", exported: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := &recordingExporter{}
+			tracer := NewTracer(exporter, WithSampleRatio(tt.ratio))
+
+			ctx := context.Background()
+			if tt.parent != "" {
+				remote, _ := ParseTraceparent(tt.parent)
+				ctx = ContextWithRemoteSpanContext(ctx, remote)
+			}
+			_, span := tracer.Start(ctx, "span", SpanKindServer)
+			if !span.SpanContext().IsValid() {
+				t.Error("expected unsampled spans to still carry valid IDs")
+			}
+			span.End()
+			tracer.Shutdown(context.Background())
+
+			if len(exporter.spans) != tt.exported {
+				t.Errorf("expected %d exported spans, got %d", tt.exported, len(exporter.spans))
+			}
+		})
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(NewStdoutExporter(&buf))
+
+	_, span := tracer.Start(context.Background(), "GET /hello", SpanKindServer, String("http.route", "/hello"))
+	span.SetStatus(StatusError, "boom")
+	span.End()
+	tracer.Shutdown(context.Background())
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", buf.String(), err)
+	}
+	if out["name"] != "GET /hello" || out["status_message"] != "boom" {
+		t.Errorf("unexpected span output %v", out)
+	}
+	if attrs, _ := out["attributes"].(map[string]interface{}); attrs["http.route"] != "/hello" {
+		t.Errorf("expected http.route attribute, got %v", out["attributes"])
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter)
+
+	var seen SpanContext
+	handler := Middleware(tracer, "/hello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/hello?name=x", nil)
+	req.Header.Set(TraceparentHeader, validTraceparent)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	tracer.Shutdown(context.Background())
+
+	if len(exporter.spans) != 1 {
+		t.Fatalf("expected 1 exported span, got %d", len(exporter.spans))
+	}
+	span := exporter.spans[0]
+	if span.Name != "GET /hello" || span.Kind != SpanKindServer {
+		t.Errorf("unexpected span name/kind %q/%d", span.Name, span.Kind)
+	}
+	if span.SpanContext != seen {
+		t.Error("expected handler to see the server span in its context")
+	}
+	if span.SpanContext.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected incoming trace to be continued, got %s", span.SpanContext.TraceID)
+	}
+	if span.StatusCode != StatusError {
+		t.Errorf("expected error status for 500 response, got %d", span.StatusCode)
+	}
+
+	attrs := make(map[string]interface{})
+	for _, attr := range span.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	if attrs["http.route"] != "/hello" || attrs["http.response.status_code"] != int64(500) || attrs["url.path"] != "/hello" {
+		t.Errorf("unexpected span attributes %v", attrs)
+	}
+}
@@ -0,0 +1,201 @@
+// Package validation sanitizes and validates user-supplied names before they
+// are echoed back to clients.
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Error codes reported in FieldError.Code.
+const (
+	CodeInvalidEncoding   = "invalid_encoding"
+	CodeTooLong           = "too_long"
+	CodeInvalidCharacters = "invalid_characters"
+)
+
+// Character classes that can be allowed in a name.
+const (
+	ClassLetter      = "letter"
+	ClassMark        = "mark"
+	ClassNumber      = "number"
+	ClassSpace       = "space"
+	ClassPunctuation = "punctuation"
+	ClassSymbol      = "symbol"
+)
+
+var classTables = map[string]*unicode.RangeTable{
+	ClassLetter:      unicode.L,
+	ClassMark:        unicode.M,
+	ClassNumber:      unicode.N,
+	ClassSpace:       unicode.Zs,
+	ClassPunctuation: unicode.P,
+	ClassSymbol:      unicode.S,
+}
+
+// Normalization forms accepted by Rules.Normalization.
+const (
+	NormalizationNone = "none"
+	NormalizationNFC  = "nfc"
+	NormalizationNFKC = "nfkc"
+)
+
+// FieldError describes why a single field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors is a list of field errors returned by Validate.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Rules configures how names are sanitized and validated.
+type Rules struct {
+	// MaxLength is the maximum number of characters after sanitizing.
+	MaxLength int
+	// Normalization is one of none, nfc or nfkc.
+	Normalization string
+	// AllowedClasses lists the character classes a name may contain.
+	AllowedClasses []string
+	// StripControl removes control and bidirectional override characters
+	// instead of rejecting them.
+	StripControl bool
+}
+
+// DefaultRules allows names in any script with ordinary punctuation.
+func DefaultRules() Rules {
+	return Rules{
+		MaxLength:      100,
+		Normalization:  NormalizationNFC,
+		AllowedClasses: []string{ClassLetter, ClassMark, ClassNumber, ClassSpace, ClassPunctuation},
+		StripControl:   true,
+	}
+}
+
+// Check reports configuration mistakes in r.
+func (r Rules) Check() error {
+	if r.MaxLength <= 0 {
+		return fmt.Errorf("max length must be positive, got %d", r.MaxLength)
+	}
+	switch r.Normalization {
+	case NormalizationNone, NormalizationNFC, NormalizationNFKC:
+	default:
+		return fmt.Errorf("unknown normalization %q", r.Normalization)
+	}
+	if len(r.AllowedClasses) == 0 {
+		return fmt.Errorf("at least one character class must be allowed")
+	}
+	for _, class := range r.AllowedClasses {
+		if _, ok := classTables[class]; !ok {
+			return fmt.Errorf("unknown character class %q", class)
+		}
+	}
+	return nil
+}
+
+// Name sanitizes value and validates it against r. It returns the cleaned
+// value, or a FieldError for field when the value is unacceptable.
+func (r Rules) Name(field, value string) (string, *FieldError) {
+	if !utf8.ValidString(value) {
+		return "", &FieldError{Field: field, Code: CodeInvalidEncoding, Message: "must be valid UTF-8"}
+	}
+
+	if r.StripControl {
+		value = strings.Map(func(c rune) rune {
+			if isControl(c) {
+				return -1
+			}
+			return c
+		}, value)
+	}
+
+	switch r.Normalization {
+	case NormalizationNFC:
+		value = norm.NFC.String(value)
+	case NormalizationNFKC:
+		value = norm.NFKC.String(value)
+	}
+
+	value = strings.TrimSpace(value)
+
+	if n := utf8.RuneCountInString(value); n > r.MaxLength {
+		return "", &FieldError{
+			Field:   field,
+			Code:    CodeTooLong,
+			Message: fmt.Sprintf("must be at most %d characters, got %d", r.MaxLength, n),
+		}
+	}
+
+	for _, c := range value {
+		if !r.allowed(c) {
+			return "", &FieldError{
+				Field:   field,
+				Code:    CodeInvalidCharacters,
+				Message: fmt.Sprintf("contains disallowed character %q", c),
+			}
+		}
+	}
+
+	return value, nil
+}
+
+// Names validates every value, naming fields field[i] when there is more
+// than one, and collects all errors.
+func (r Rules) Names(field string, values []string) ([]string, error) {
+	var errs Errors
+	cleaned := make([]string, 0, len(values))
+
+	for i, v := range values {
+		name := field
+		if len(values) > 1 {
+			name = fmt.Sprintf("%s[%d]", field, i)
+		}
+		c, fe := r.Name(name, v)
+		if fe != nil {
+			errs = append(errs, *fe)
+			continue
+		}
+		if c != "" {
+			cleaned = append(cleaned, c)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return cleaned, nil
+}
+
+func (r Rules) allowed(c rune) bool {
+	for _, class := range r.AllowedClasses {
+		if table, ok := classTables[class]; ok && unicode.Is(table, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// isControl reports C0/C1 controls and the bidirectional formatting
+// characters that can be used to disguise text (Trojan Source).
+func isControl(c rune) bool {
+	switch {
+	case unicode.IsControl(c):
+		return true
+	case c >= 0x202A && c <= 0x202E, c >= 0x2066 && c <= 0x2069, c == 0x200E, c == 0x200F, c == 0x061C:
+		return true
+	}
+	return false
+}
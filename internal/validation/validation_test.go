@@ -0,0 +1,135 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		name         string
+		rules        func(r *Rules)
+		value        string
+		expected     string
+		expectedCode string
+	}{
+		{name: "plain name", value: "Alice", expected: "Alice"},
+		{name: "unicode letters and marks", value: "José Müller-Ñúñez", expected: "José Müller-Ñúñez"},
+		{name: "non-latin script", value: "田中 太郎", expected: "田中 太郎"},
+		{name: "surrounding whitespace trimmed", value: "  Bob  ", expected: "Bob"},
+		{name: "control characters stripped", value: "Bo\x00b\x1b[31m", expected: "Bob[31m"},
+		{name: "bidi overrides stripped", value: "evil‮gnp.exe", expected: "evilgnp.exe"},
+		{name: "NFC composes combining marks", value: "é", expected: "é"},
+		{
+			name:     "NFKC folds compatibility characters",
+			rules:    func(r *Rules) { r.Normalization = NormalizationNFKC },
+			value:    "Ｆｕｌｌ",
+			expected: "Full",
+		},
+		{
+			name:     "normalization disabled",
+			rules:    func(r *Rules) { r.Normalization = NormalizationNone },
+			value:    "é",
+			expected: "é",
+		},
+		{name: "too long", value: strings.Repeat("a", 101), expectedCode: CodeTooLong},
+		{name: "length counts characters not bytes", value: strings.Repeat("é", 100), expected: strings.Repeat("é", 100)},
+		{name: "symbols rejected by default", value: "<script>", expectedCode: CodeInvalidCharacters},
+		{
+			name:     "symbols allowed when configured",
+			rules:    func(r *Rules) { r.AllowedClasses = append(r.AllowedClasses, ClassSymbol) },
+			value:    "<b>",
+			expected: "<b>",
+		},
+		{
+			name:         "control characters rejected when not stripped",
+			rules:        func(r *Rules) { r.StripControl = false },
+			value:        "Bo\x00b",
+			expectedCode: CodeInvalidCharacters,
+		},
+		{name: "invalid UTF-8", value: "Bob\xff", expectedCode: CodeInvalidEncoding},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := DefaultRules()
+			if tt.rules != nil {
+				tt.rules(&rules)
+			}
+
+			got, fe := rules.Name("name", tt.value)
+			if tt.expectedCode != "" {
+				if fe == nil {
+					t.Fatalf("expected error %s, got value %q", tt.expectedCode, got)
+				}
+				if fe.Code != tt.expectedCode || fe.Field != "name" || fe.Message == "" {
+					t.Errorf("unexpected field error %+v", fe)
+				}
+				return
+			}
+			if fe != nil {
+				t.Fatalf("unexpected error %+v", fe)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNames(t *testing.T) {
+	rules := DefaultRules()
+
+	cleaned, err := rules.Names("name", []string{" Alice ", "", "\x00", "Bob"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(cleaned, "|") != "Alice|Bob" {
+		t.Errorf("expected empty names to be dropped, got %q", cleaned)
+	}
+
+	_, err = rules.Names("name", []string{"Alice", "<x>", strings.Repeat("a", 101)})
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected Errors, got %v", err)
+	}
+	if len(errs) != 2 || errs[0].Field != "name[1]" || errs[1].Field != "name[2]" {
+		t.Errorf("expected indexed field errors, got %+v", errs)
+	}
+	if !strings.Contains(err.Error(), "name[1]: contains disallowed character") {
+		t.Errorf("unexpected error text %q", err.Error())
+	}
+}
+
+func TestRulesCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(r *Rules)
+		expectedErr string
+	}{
+		{name: "defaults are valid", modify: func(r *Rules) {}},
+		{name: "non-positive max length", modify: func(r *Rules) { r.MaxLength = 0 }, expectedErr: "max length"},
+		{name: "unknown normalization", modify: func(r *Rules) { r.Normalization = "nfd" }, expectedErr: "normalization"},
+		{name: "no classes", modify: func(r *Rules) { r.AllowedClasses = nil }, expectedErr: "at least one"},
+		{name: "unknown class", modify: func(r *Rules) { r.AllowedClasses = []string{"emoji"} }, expectedErr: "emoji"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := DefaultRules()
+			tt.modify(&rules)
+
+			err := rules.Check()
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("expected error containing '%s', got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Opcode identifies the type of a frame.
+type Opcode byte
+
+// Opcodes defined by RFC 6455 section 5.2.
+const (
+	OpContinuation Opcode = 0x0
+	OpText         Opcode = 0x1
+	OpBinary       Opcode = 0x2
+	OpClose        Opcode = 0x8
+	OpPing         Opcode = 0x9
+	OpPong         Opcode = 0xA
+)
+
+func (op Opcode) isControl() bool { return op&0x8 != 0 }
+
+// Close status codes defined by RFC 6455 section 7.4.1.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+)
+
+// maxControlPayload is the largest payload a control frame may carry.
+const maxControlPayload = 125
+
+// CloseError ends a connection. It is returned by ReadMessage both when the
+// client closes the connection and when the server closes it because the
+// client broke the protocol.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: close %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: close %d: %s", e.Code, e.Reason)
+}
+
+// Conn is an upgraded WebSocket connection. ReadMessage must only be called
+// from one goroutine; the write methods may be called concurrently with it
+// and with each other.
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	opts Options
+
+	wmu       sync.Mutex
+	closeSent bool
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, opts Options) *Conn {
+	return &Conn{conn: conn, br: br, opts: opts}
+}
+
+// RemoteAddr returns the client's network address.
+func (c *Conn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+// ReadMessage returns the next text or binary message, reassembling
+// fragments. Pings are answered and pongs discarded along the way. When the
+// client sends a close frame it is echoed, completing the closing
+// handshake, and a *CloseError is returned. Protocol violations close the
+// connection with the matching status code and also return a *CloseError.
+func (c *Conn) ReadMessage() (Opcode, []byte, error) {
+	var (
+		msgOp Opcode
+		msg   []byte
+	)
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case OpPing:
+			if err := c.writeFrame(OpPong, payload); err != nil && err != ErrClosed {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			continue
+		case OpClose:
+			return 0, nil, c.handleClose(payload)
+		case OpText, OpBinary:
+			if msgOp != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected continuation frame")
+			}
+			msgOp = op
+		case OpContinuation:
+			if msgOp == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			return 0, nil, c.fail(CloseProtocolError, fmt.Sprintf("reserved opcode %#x", byte(op)))
+		}
+
+		if c.opts.MaxMessageSize > 0 && int64(len(msg)+len(payload)) > c.opts.MaxMessageSize {
+			return 0, nil, c.fail(CloseMessageTooBig, fmt.Sprintf("message exceeds %d bytes", c.opts.MaxMessageSize))
+		}
+		msg = append(msg, payload...)
+
+		if fin {
+			if msgOp == OpText && !utf8.Valid(msg) {
+				return 0, nil, c.fail(CloseInvalidPayload, "text message is not valid UTF-8")
+			}
+			return msgOp, msg, nil
+		}
+	}
+}
+
+// readFrame reads and unmasks a single frame.
+func (c *Conn) readFrame() (fin bool, op Opcode, payload []byte, err error) {
+	if c.opts.IdleTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.opts.IdleTimeout))
+	}
+
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = head[0]&0x80 != 0
+	op = Opcode(head[0] & 0x0f)
+	masked := head[1]&0x80 != 0
+
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "reserved bits set without a negotiated extension")
+	}
+	if !masked {
+		return false, 0, nil, c.fail(CloseProtocolError, "client frames must be masked")
+	}
+
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+		if length>>63 != 0 {
+			return false, 0, nil, c.fail(CloseProtocolError, "invalid payload length")
+		}
+	}
+
+	if op.isControl() && (!fin || length > maxControlPayload) {
+		return false, 0, nil, c.fail(CloseProtocolError, "control frames must not be fragmented or exceed 125 bytes")
+	}
+	if c.opts.MaxMessageSize > 0 && length > uint64(c.opts.MaxMessageSize) {
+		return false, 0, nil, c.fail(CloseMessageTooBig, fmt.Sprintf("message exceeds %d bytes", c.opts.MaxMessageSize))
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// handleClose answers a close frame from the client and returns the error
+// that ends the read loop.
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatus}
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close frame")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Reason = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.Valid(payload[2:]) {
+			return c.fail(CloseInvalidPayload, "close reason is not valid UTF-8")
+		}
+	}
+
+	// Echo the status code, as RFC 6455 section 5.5.1 suggests.
+	reply := CloseNormal
+	if closeErr.Code != CloseNoStatus {
+		reply = closeErr.Code
+	}
+	c.WriteClose(reply, "")
+	return closeErr
+}
+
+// fail starts the closing handshake for a protocol violation.
+func (c *Conn) fail(code int, reason string) error {
+	c.WriteClose(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// validCloseCode reports whether code may be sent in a close frame.
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code < 1000 || code > 1014:
+		return false
+	}
+	switch code {
+	case 1004, CloseNoStatus, 1006:
+		return false
+	}
+	return true
+}
+
+// WriteMessage sends data as a single text or binary message.
+func (c *Conn) WriteMessage(op Opcode, data []byte) error {
+	if op != OpText && op != OpBinary {
+		return fmt.Errorf("websocket: invalid message opcode %#x", byte(op))
+	}
+	return c.writeFrame(op, data)
+}
+
+// Ping sends a ping frame. The client answers with a pong, which resets the
+// idle timeout.
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > maxControlPayload {
+		return fmt.Errorf("websocket: ping payload exceeds %d bytes", maxControlPayload)
+	}
+	return c.writeFrame(OpPing, data)
+}
+
+// WriteClose starts the closing handshake with code and reason. Later
+// writes fail with ErrClosed; the connection stays open so ReadMessage can
+// receive the client's reply.
+func (c *Conn) WriteClose(code int, reason string) error {
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, reason...)
+	if len(payload) > maxControlPayload {
+		payload = payload[:maxControlPayload]
+	}
+	return c.writeFrame(OpClose, payload)
+}
+
+// Close closes the underlying network connection without a closing
+// handshake.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Conn) writeFrame(op Opcode, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return ErrClosed
+	}
+	if op == OpClose {
+		c.closeSent = true
+	}
+
+	// Server frames are never masked.
+	head := make([]byte, 2, 10)
+	head[0] = 0x80 | byte(op)
+	switch n := len(payload); {
+	case n <= 125:
+		head[1] = byte(n)
+	case n <= 0xffff:
+		head[1] = 126
+		head = binary.BigEndian.AppendUint16(head, uint16(n))
+	default:
+		head[1] = 127
+		head = binary.BigEndian.AppendUint64(head, uint64(n))
+	}
+
+	if c.opts.WriteTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.opts.WriteTimeout))
+	}
+	_, err := (&net.Buffers{head, payload}).WriteTo(c.conn)
+	return err
+}
@@ -0,0 +1,86 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+)
+
+// Tracker keeps the set of open connections so they can be closed cleanly
+// on shutdown. http.Server.Shutdown neither waits for nor closes hijacked
+// connections.
+type Tracker struct {
+	mu       sync.Mutex
+	conns    map[*Conn]struct{}
+	closing  bool
+	finished sync.WaitGroup
+}
+
+// Add starts tracking c. It returns false once Shutdown has been called, in
+// which case the caller should close c instead of serving it.
+func (t *Tracker) Add(c *Conn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closing {
+		return false
+	}
+	if t.conns == nil {
+		t.conns = make(map[*Conn]struct{})
+	}
+	t.conns[c] = struct{}{}
+	t.finished.Add(1)
+	return true
+}
+
+// Remove stops tracking c. Handlers call it when they are done with c.
+func (t *Tracker) Remove(c *Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.conns[c]; ok {
+		delete(t.conns, c)
+		t.finished.Done()
+	}
+}
+
+// Len returns the number of tracked connections.
+func (t *Tracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.conns)
+}
+
+// Shutdown sends a CloseGoingAway frame on every connection and waits for
+// their handlers to finish the closing handshake and Remove them. When ctx
+// expires first, the remaining connections are closed abruptly and ctx's
+// error is returned.
+func (t *Tracker) Shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	t.closing = true
+	conns := make([]*Conn, 0, len(t.conns))
+	for c := range t.conns {
+		conns = append(conns, c)
+	}
+	t.mu.Unlock()
+
+	// A stalled client must not hold up the others.
+	for _, c := range conns {
+		go c.WriteClose(CloseGoingAway, "server shutting down")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		t.finished.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		for c := range t.conns {
+			c.Close()
+		}
+		t.mu.Unlock()
+		return ctx.Err()
+	}
+}
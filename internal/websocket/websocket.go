@@ -0,0 +1,112 @@
+// Package websocket implements the server side of the WebSocket protocol
+// (RFC 6455) on top of net/http: the opening handshake, message framing,
+// ping/pong and the closing handshake. Extensions and subprotocols are not
+// supported.
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// acceptGUID is appended to the client's key to compute Sec-WebSocket-Accept.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Version is the only protocol version the server speaks.
+const Version = "13"
+
+// Options configure upgraded connections.
+type Options struct {
+	// MaxMessageSize is the largest message, in bytes, a client may send.
+	// Larger messages close the connection with CloseMessageTooBig.
+	MaxMessageSize int64
+	// IdleTimeout closes connections that send nothing, not even a pong,
+	// for this long. Zero means no limit.
+	IdleTimeout time.Duration
+	// WriteTimeout bounds each frame write. Zero means no limit.
+	WriteTimeout time.Duration
+}
+
+// HandshakeError reports why a request could not be upgraded. Upgrade does
+// not write a response for it, so callers can report it in their own format.
+type HandshakeError struct {
+	Status  int
+	Message string
+}
+
+func (e *HandshakeError) Error() string { return "websocket: " + e.Message }
+
+// IsUpgrade reports whether r asks to switch to the WebSocket protocol.
+func IsUpgrade(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && headerContains(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade completes the opening handshake and takes over the connection.
+// Headers already set on w, such as X-Request-ID, are sent with the 101
+// response. On failure no response has been written; a *HandshakeError
+// describes what the client got wrong.
+func Upgrade(w http.ResponseWriter, r *http.Request, opts Options) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, &HandshakeError{Status: http.StatusMethodNotAllowed, Message: "handshake must use GET"}
+	}
+	if !IsUpgrade(r) {
+		return nil, &HandshakeError{Status: http.StatusUpgradeRequired, Message: "missing Upgrade: websocket header"}
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != Version {
+		w.Header().Set("Sec-WebSocket-Version", Version)
+		return nil, &HandshakeError{Status: http.StatusUpgradeRequired, Message: "unsupported Sec-WebSocket-Version"}
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, &HandshakeError{Status: http.StatusBadRequest, Message: "invalid Sec-WebSocket-Key"}
+	}
+
+	h := w.Header().Clone()
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, err
+	}
+	// The server's read and write timeouts were meant for the HTTP request.
+	netConn.SetDeadline(time.Time{})
+
+	h.Set("Upgrade", "websocket")
+	h.Set("Connection", "Upgrade")
+	h.Set("Sec-WebSocket-Accept", AcceptKey(key))
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	h.Write(brw)
+	brw.WriteString("\r\n")
+	if err := brw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	return newConn(netConn, brw.Reader, opts), nil
+}
+
+// AcceptKey returns the Sec-WebSocket-Accept value for a client's
+// Sec-WebSocket-Key.
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerContains reports whether the comma-separated header name lists
+// token, ignoring case.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ErrClosed is returned when writing to a connection after the closing
+// handshake has started.
+var ErrClosed = errors.New("websocket: connection closed")
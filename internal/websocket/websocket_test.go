@@ -0,0 +1,297 @@
+package websocket_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hello-api/internal/websocket"
+	"hello-api/internal/websocket/websockettest"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// The example from RFC 6455 section 1.3.
+	if got := websocket.AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("expected accept key 's3pPLMBiTxaQ9kYGzzhZRbK+xOo=', got '%s'", got)
+	}
+}
+
+func TestUpgradeRejects(t *testing.T) {
+	valid := http.Header{
+		"Connection":            {"keep-alive, Upgrade"},
+		"Upgrade":               {"websocket"},
+		"Sec-Websocket-Version": {"13"},
+		"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		override       http.Header
+		expectedStatus int
+	}{
+		{name: "POST", method: http.MethodPost, expectedStatus: http.StatusMethodNotAllowed},
+		{name: "plain HTTP", override: http.Header{"Upgrade": nil}, expectedStatus: http.StatusUpgradeRequired},
+		{name: "old version", override: http.Header{"Sec-Websocket-Version": {"8"}}, expectedStatus: http.StatusUpgradeRequired},
+		{name: "short key", override: http.Header{"Sec-Websocket-Key": {"c2hvcnQ="}}, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/ws", nil)
+			req.Header = valid.Clone()
+			for name, values := range tt.override {
+				req.Header[name] = values
+			}
+
+			_, err := websocket.Upgrade(httptest.NewRecorder(), req, websocket.Options{})
+			var handshakeErr *websocket.HandshakeError
+			if !errors.As(err, &handshakeErr) {
+				t.Fatalf("expected a HandshakeError, got %v", err)
+			}
+			if handshakeErr.Status != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, handshakeErr.Status)
+			}
+		})
+	}
+}
+
+// echoServer echoes every message back and reports how the read loop ended.
+func echoServer(t *testing.T, opts websocket.Options) (string, <-chan error) {
+	t.Helper()
+	ended := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "req-1")
+		conn, err := websocket.Upgrade(w, r, opts)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		for {
+			op, msg, err := conn.ReadMessage()
+			if err != nil {
+				ended <- err
+				return
+			}
+			conn.WriteMessage(op, msg)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, ended
+}
+
+func dial(t *testing.T, url string) *websockettest.Client {
+	t.Helper()
+	client, err := websockettest.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if client.Response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status %d, got %d", http.StatusSwitchingProtocols, client.Response.StatusCode)
+	}
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	return client
+}
+
+func TestHandshake(t *testing.T) {
+	url, _ := echoServer(t, websocket.Options{})
+	client := dial(t, url)
+
+	key := client.Response.Request.Header.Get("Sec-WebSocket-Key")
+	for header, want := range map[string]string{
+		"Upgrade":              "websocket",
+		"Connection":           "Upgrade",
+		"Sec-WebSocket-Accept": websocket.AcceptKey(key),
+		"X-Request-ID":         "req-1",
+	} {
+		if got := client.Response.Header.Get(header); got != want {
+			t.Errorf("expected %s '%s', got '%s'", header, want, got)
+		}
+	}
+}
+
+func TestMessages(t *testing.T) {
+	url, _ := echoServer(t, websocket.Options{})
+	client := dial(t, url)
+
+	expectFrame := func(wantOp websocket.Opcode, want string) {
+		t.Helper()
+		op, payload, err := client.ReadFrame()
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if op != wantOp || string(payload) != want {
+			t.Errorf("expected frame %#x %q, got %#x %q", byte(wantOp), want, byte(op), payload)
+		}
+	}
+
+	client.WriteMessage(websocket.OpText, []byte("hello"))
+	expectFrame(websocket.OpText, "hello")
+
+	big := bytes.Repeat([]byte("x"), 70000)
+	client.WriteMessage(websocket.OpBinary, big)
+	expectFrame(websocket.OpBinary, string(big))
+
+	// Control frames may arrive between the fragments of a message.
+	client.WriteFrame(false, websocket.OpText, []byte("frag"))
+	client.WriteFrame(true, websocket.OpPing, []byte("are you there"))
+	client.WriteFrame(false, websocket.OpContinuation, []byte("men"))
+	client.WriteFrame(true, websocket.OpContinuation, []byte("ted"))
+	expectFrame(websocket.OpPong, "are you there")
+	expectFrame(websocket.OpText, "fragmented")
+}
+
+func TestClose(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         websocket.Options
+		send         func(c *websockettest.Client)
+		expectedCode int
+	}{
+		{
+			name:         "client closes",
+			send:         func(c *websockettest.Client) { c.WriteClose(websocket.CloseGoingAway, "bye") },
+			expectedCode: websocket.CloseGoingAway,
+		},
+		{
+			name:         "close without status",
+			send:         func(c *websockettest.Client) { c.WriteFrame(true, websocket.OpClose, nil) },
+			expectedCode: websocket.CloseNormal,
+		},
+		{
+			name:         "unmasked frame",
+			send:         func(c *websockettest.Client) { c.WriteRaw([]byte{0x81, 0x02, 'h', 'i'}) },
+			expectedCode: websocket.CloseProtocolError,
+		},
+		{
+			name:         "reserved opcode",
+			send:         func(c *websockettest.Client) { c.WriteFrame(true, websocket.Opcode(0x3), nil) },
+			expectedCode: websocket.CloseProtocolError,
+		},
+		{
+			name:         "fragmented ping",
+			send:         func(c *websockettest.Client) { c.WriteFrame(false, websocket.OpPing, nil) },
+			expectedCode: websocket.CloseProtocolError,
+		},
+		{
+			name:         "orphan continuation",
+			send:         func(c *websockettest.Client) { c.WriteFrame(true, websocket.OpContinuation, []byte("x")) },
+			expectedCode: websocket.CloseProtocolError,
+		},
+		{
+			name:         "invalid close code",
+			send:         func(c *websockettest.Client) { c.WriteClose(1005, "") },
+			expectedCode: websocket.CloseProtocolError,
+		},
+		{
+			name:         "invalid UTF-8",
+			send:         func(c *websockettest.Client) { c.WriteMessage(websocket.OpText, []byte{0xff, 0xfe}) },
+			expectedCode: websocket.CloseInvalidPayload,
+		},
+		{
+			name: "message too big",
+			opts: websocket.Options{MaxMessageSize: 8},
+			send: func(c *websockettest.Client) {
+				c.WriteFrame(false, websocket.OpText, []byte("12345"))
+				c.WriteFrame(true, websocket.OpContinuation, []byte("6789"))
+			},
+			expectedCode: websocket.CloseMessageTooBig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, ended := echoServer(t, tt.opts)
+			client := dial(t, url)
+
+			tt.send(client)
+
+			code, err := client.ReadClose()
+			if err != nil {
+				t.Fatalf("expected a close frame, got %v", err)
+			}
+			if code != tt.expectedCode {
+				t.Errorf("expected close code %d, got %d", tt.expectedCode, code)
+			}
+
+			var closeErr *websocket.CloseError
+			if err := <-ended; !errors.As(err, &closeErr) {
+				t.Errorf("expected ReadMessage to return a CloseError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	url, ended := echoServer(t, websocket.Options{IdleTimeout: 50 * time.Millisecond})
+	dial(t, url)
+
+	select {
+	case err := <-ended:
+		if !strings.Contains(err.Error(), "timeout") {
+			t.Errorf("expected a timeout, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the idle connection to be closed")
+	}
+}
+
+func TestTrackerShutdown(t *testing.T) {
+	var tracker websocket.Tracker
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, websocket.Options{})
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if !tracker.Add(conn) {
+			return
+		}
+		defer tracker.Remove(conn)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	polite := dial(t, srv.URL)
+	stubborn := dial(t, srv.URL)
+	for tracker.Len() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	go func() {
+		if code, _ := polite.ReadClose(); code == websocket.CloseGoingAway {
+			polite.WriteClose(code, "")
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := tracker.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the unanswered connection to exceed the deadline, got %v", err)
+	}
+	if code, err := stubborn.ReadClose(); err != nil || code != websocket.CloseGoingAway {
+		t.Errorf("expected close code %d, got %d (%v)", websocket.CloseGoingAway, code, err)
+	}
+
+	for tracker.Len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if tracker.Add(nil) {
+		t.Error("expected Add to fail after Shutdown")
+	}
+}
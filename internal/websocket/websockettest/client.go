@@ -0,0 +1,173 @@
+// Package websockettest provides a minimal WebSocket client for testing
+// servers built on package websocket. It writes frames exactly as asked, so
+// tests can also send malformed ones.
+package websockettest
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"hello-api/internal/websocket"
+)
+
+// Client is the client end of a WebSocket connection.
+type Client struct {
+	// Response is the server's answer to the opening handshake.
+	Response *http.Response
+
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// Dial sends an opening handshake for rawURL, an http:// URL, with the
+// standard upgrade headers plus header, which may override them. The
+// returned Client holds the response even when the server refused the
+// upgrade; only a 101 response leaves the connection usable.
+func Dial(rawURL string, header http.Header) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	var key [16]byte
+	rand.Read(key[:])
+	req, _ := http.NewRequest(http.MethodGet, rawURL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", websocket.Version)
+	req.Header.Set("Sec-WebSocket-Key", base64.StdEncoding.EncodeToString(key[:]))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c := &Client{Response: resp, conn: conn, br: br}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+	}
+	return c, nil
+}
+
+// WriteFrame sends a single masked frame.
+func (c *Client) WriteFrame(fin bool, op websocket.Opcode, payload []byte) error {
+	head := []byte{byte(op), 0x80}
+	if fin {
+		head[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		head[1] |= byte(n)
+	case n <= 0xffff:
+		head[1] |= 126
+		head = binary.BigEndian.AppendUint16(head, uint16(n))
+	default:
+		head[1] |= 127
+		head = binary.BigEndian.AppendUint64(head, uint64(n))
+	}
+
+	var mask [4]byte
+	rand.Read(mask[:])
+	head = append(head, mask[:]...)
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+	return c.WriteRaw(append(head, masked...))
+}
+
+// WriteMessage sends payload as a single unfragmented message.
+func (c *Client) WriteMessage(op websocket.Opcode, payload []byte) error {
+	return c.WriteFrame(true, op, payload)
+}
+
+// WriteClose sends a close frame with code and reason.
+func (c *Client) WriteClose(code int, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	return c.WriteFrame(true, websocket.OpClose, append(payload, reason...))
+}
+
+// WriteRaw writes b to the connection as is.
+func (c *Client) WriteRaw(b []byte) error {
+	_, err := c.conn.Write(b)
+	return err
+}
+
+// ReadFrame reads the next frame from the server. Server frames are never
+// fragmented by package websocket, so fin is not reported.
+func (c *Client) ReadFrame() (websocket.Opcode, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return 0, nil, err
+	}
+	if head[1]&0x80 != 0 {
+		return 0, nil, fmt.Errorf("websockettest: server frame is masked")
+	}
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return 0, nil, err
+	}
+	return websocket.Opcode(head[0] & 0x0f), payload, nil
+}
+
+// ReadClose reads frames until a close frame arrives and returns its code.
+func (c *Client) ReadClose() (int, error) {
+	for {
+		op, payload, err := c.ReadFrame()
+		if err != nil {
+			return 0, err
+		}
+		if op != websocket.OpClose {
+			continue
+		}
+		if len(payload) < 2 {
+			return websocket.CloseNoStatus, nil
+		}
+		return int(binary.BigEndian.Uint16(payload)), nil
+	}
+}
+
+// SetDeadline bounds subsequent reads and writes.
+func (c *Client) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// Close closes the connection without a closing handshake.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
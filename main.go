@@ -1,68 +1,380 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"sync/atomic"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+
+	"hello-api/internal/auth"
+	"hello-api/internal/compress"
+	"hello-api/internal/config"
+	"hello-api/internal/cors"
+	"hello-api/internal/health"
+	"hello-api/internal/i18n"
+	"hello-api/internal/logging"
+	"hello-api/internal/metrics"
+	"hello-api/internal/ratelimit"
+	"hello-api/internal/render"
+	"hello-api/internal/requestid"
+	"hello-api/internal/tracing"
+	"hello-api/internal/validation"
+	"hello-api/internal/websocket"
 )
 
 func main() {
-	logger := log.New(os.Stdout, "[hello-api] ", log.LstdFlags|log.Lmicroseconds)
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintf(os.Stderr, "hello-api: %v\n", err)
+		os.Exit(2)
+	}
 
-	mux := http.NewServeMux()
-	mux.Handle("/hello", loggingMiddleware(logger, http.HandlerFunc(helloHandler)))
-	mux.Handle("/health", loggingMiddleware(logger, http.HandlerFunc(healthHandler)))
-	mux.Handle("/ping", loggingMiddleware(logger, http.HandlerFunc(pingHandler)))
-	mux.Handle("/info", loggingMiddleware(logger, http.HandlerFunc(infoHandler)))
+	logger := logging.New(os.Stdout, cfg.Log)
+	slog.SetDefault(logger)
+	app, err := newApp(cfg, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hello-api: %v\n", err)
+		os.Exit(2)
+	}
 
 	server := &http.Server{
-		Addr:         ":8080",
-		Handler:      mux,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		Addr:         cfg.Server.Addr,
+		Handler:      app.routes(),
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
 	}
+	server.RegisterOnShutdown(app.stop)
 
 	go func() {
-		logger.Println("INFO: Starting server on :8080")
+		logger.Info("starting server", slog.String("addr", cfg.Server.Addr), slog.String("log_level", cfg.Log.Level))
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatalf("ERROR: Server failed: %v", err)
+			logger.Error("server failed", slog.Any("error", err))
+			os.Exit(1)
 		}
 	}()
 
+	var grpcServer *grpc.Server
+	var grpcHealth *grpchealth.Server
+	if cfg.GRPC.Enabled {
+		grpcServer, grpcHealth = app.newGRPCServer()
+		listener, err := net.Listen("tcp", cfg.GRPC.Addr)
+		if err != nil {
+			logger.Error("grpc server failed", slog.Any("error", err))
+			os.Exit(1)
+		}
+		go func() {
+			logger.Info("starting grpc server", slog.String("addr", cfg.GRPC.Addr))
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Error("grpc server failed", slog.Any("error", err))
+				os.Exit(1)
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	logger.Println("INFO: Server is shutting down...")
+	app.shutdownGate.Drain()
+	if grpcHealth != nil {
+		grpcHealth.Shutdown()
+	}
+	logger.Info("server is draining", slog.Duration("drain_delay", cfg.Server.DrainDelay))
+	time.Sleep(cfg.Server.DrainDelay)
+
+	logger.Info("server is shutting down", slog.Duration("timeout", cfg.Server.ShutdownTimeout))
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	err = app.shutdown(ctx, server, grpcServer)
+	cancel()
+	if err != nil {
+		logger.Error("server forced to shutdown", slog.Any("error", err))
+	}
+
+	// Traces get their own deadline, so a slow shutdown cannot lose them.
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	if err := app.tracer.Shutdown(flushCtx); err != nil {
+		logger.Error("failed to flush traces", slog.Any("error", err))
+	}
+	cancelFlush()
+
+	if err != nil {
+		os.Exit(1)
+	}
+	logger.Info("server exited")
+}
+
+// shutdown stops the HTTP server, the gRPC server when there is one and the
+// WebSocket connections together, so each may use all of ctx's time. It
+// returns the HTTP server's error; the others are logged.
+func (a *app) shutdown(ctx context.Context, server *http.Server, grpcServer *grpc.Server) error {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := a.websockets.Shutdown(ctx); err != nil {
+			a.logger.Error("websocket connections forced to close", slog.Any("error", err))
+		}
+	}()
+	if grpcServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := stopGRPC(ctx, grpcServer); err != nil {
+				a.logger.Error("grpc server forced to stop", slog.Any("error", err))
+			}
+		}()
+	}
+
+	err := server.Shutdown(ctx)
+	wg.Wait()
+	return err
+}
+
+// app holds the dependencies shared by every handler.
+type app struct {
+	cfg         *config.Config
+	logger      *slog.Logger
+	registry    *metrics.Registry
+	httpMetrics *httpMetrics
+	batch       *batchMetrics
+	tracer      *tracing.Tracer
+	catalog     *i18n.Catalog
+	limiter     *ratelimit.Limiter
+	trusted     []*net.IPNet
+	auth        *auth.Authenticator
+	cors        *cors.Policy
+	compression *compress.Options
+
+	liveness     *health.Registry
+	readiness    *health.Registry
+	shutdownGate *health.ShutdownGate
+
+	// done is closed when the server starts shutting down, ending
+	// long-lived responses that server.Shutdown would otherwise wait for.
+	done     chan struct{}
+	stopOnce sync.Once
+
+	// websockets tracks /ws connections, which server.Shutdown ignores.
+	websockets websocket.Tracker
+}
+
+// newApp builds the application for a validated cfg. It fails only when
+// credentials referenced by cfg can no longer be loaded.
+func newApp(cfg *config.Config, logger *slog.Logger) (*app, error) {
+	registry := metrics.NewRegistry()
+	metrics.RegisterRuntime(registry)
+
+	shutdownGate := &health.ShutdownGate{}
+	readiness := health.NewRegistry()
+	readiness.Register("shutdown", shutdownGate)
+
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		limiter = ratelimit.New(cfg.RateLimit.Rate, cfg.RateLimit.Burst, cfg.RateLimit.IdleTTL, cfg.RateLimit.MaxKeys)
+	}
+	// Validated by config.Validate.
+	trusted, _ := ratelimit.ParseCIDRs(cfg.RateLimit.TrustedProxies)
+
+	var authenticator *auth.Authenticator
+	if cfg.Auth.Enabled {
+		var err error
+		if authenticator, err = newAuthenticator(cfg.Auth); err != nil {
+			return nil, err
+		}
+	}
+
+	var corsPolicy *cors.Policy
+	if cfg.CORS.Enabled {
+		// Validated by config.Validate.
+		corsPolicy, _ = cors.New(cfg.CORS.Options())
+	}
+
+	var compression *compress.Options
+	if cfg.Compression.Enabled {
+		// Validated by config.Validate.
+		encoders, _ := cfg.Compression.Encoders()
+		compression = &compress.Options{Encoders: encoders, MinSize: cfg.Compression.MinSize}
+	}
+
+	return &app{
+		cfg:          cfg,
+		logger:       logger,
+		registry:     registry,
+		httpMetrics:  newHTTPMetrics(registry),
+		batch:        newBatchMetrics(registry),
+		tracer:       newTracer(cfg.Tracing, logger),
+		catalog:      i18n.Default(),
+		limiter:      limiter,
+		trusted:      trusted,
+		auth:         authenticator,
+		cors:         corsPolicy,
+		compression:  compression,
+		liveness:     health.NewRegistry(),
+		readiness:    readiness,
+		shutdownGate: shutdownGate,
+		done:         make(chan struct{}),
+	}, nil
+}
+
+// stop closes a.done. It is safe to call more than once.
+func (a *app) stop() {
+	a.stopOnce.Do(func() { close(a.done) })
+}
+
+func (a *app) routes() http.Handler {
+	mux := http.NewServeMux()
+	a.handle(mux, "/hello", http.HandlerFunc(a.helloHandler), http.MethodGet, http.MethodPost)
+	a.handle(mux, "/hello/batch", http.HandlerFunc(a.helloBatchHandler), http.MethodPost)
+	a.handle(mux, "/hello/stream", http.HandlerFunc(a.helloStreamHandler), http.MethodGet)
+	a.handle(mux, "/ws", http.HandlerFunc(a.wsHandler), http.MethodGet)
+	a.handle(mux, "/health", http.HandlerFunc(a.healthHandler), http.MethodGet)
+	a.handle(mux, "/livez", a.liveness.Handler(), http.MethodGet)
+	a.handle(mux, "/readyz", a.readiness.Handler(), http.MethodGet)
+	a.handle(mux, "/ping", http.HandlerFunc(a.pingHandler), http.MethodGet)
+	a.handle(mux, "/info", http.HandlerFunc(a.infoHandler), http.MethodGet)
+	a.handle(mux, "/metrics", a.registry.Handler(), http.MethodGet)
+	a.handle(mux, "/openapi.json", http.HandlerFunc(openAPIHandler), http.MethodGet)
+	return mux
+}
+
+// handle registers h on mux for the given methods, wrapped in the middleware
+// chain shared by every route.
+func (a *app) handle(mux *http.ServeMux, route string, h http.Handler, methods ...string) {
+	h = validationMiddleware(spec.Paths[route], a.maxBodyBytes(route), h)
+	if a.auth != nil && slices.Contains(a.cfg.Auth.Routes, route) {
+		h = auth.Middleware(a.auth, authErrorHandler, h)
+	}
+	if a.limiter != nil && slices.Contains(a.cfg.RateLimit.Routes, route) {
+		h = ratelimit.Middleware(a.limiter, a.rateLimitKey(route), http.HandlerFunc(rateLimitedHandler), h)
+	}
+	h = methodMiddleware(methods, h)
+	if a.cors != nil {
+		h = cors.Middleware(a.cors, h)
+	}
+	if a.compression != nil {
+		h = compress.Middleware(*a.compression, h)
+	}
+	h = loggingMiddleware(a.logger, h)
+	h = metricsMiddleware(a.httpMetrics, route, h)
+	h = tracing.Middleware(a.tracer, route, h)
+	h = errorFormatMiddleware(a.cfg.Errors.Format, h)
+	h = requestid.Middleware(h)
+	mux.Handle(route, h)
+}
+
+// rateLimitKey returns the function deriving the rate limit bucket of a
+// request to route.
+func (a *app) rateLimitKey(route string) func(*http.Request) string {
+	var keys *auth.APIKeys
+	if a.auth != nil {
+		keys = a.auth.APIKeys
+	}
+	return ratelimit.KeyFunc(a.cfg.RateLimit.Key, route, a.trusted, keys)
+}
+
+// newAuthenticator builds the authenticator for cfg. config.Validate has
+// checked the key files, but they are read again here, so any error is
+// returned rather than starting with fewer credentials than configured.
+func newAuthenticator(cfg config.AuthConfig) (*auth.Authenticator, error) {
+	entries := cfg.APIKeys
+	if cfg.APIKeysFile != "" {
+		fromFile, err := auth.ReadAPIKeysFile(cfg.APIKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth.api_keys_file: %w", err)
+		}
+		entries = append(slices.Clip(entries), fromFile...)
+	}
+	keys, err := auth.ParseAPIKeys(entries)
+	if err != nil {
+		return nil, fmt.Errorf("auth.api_keys: %w", err)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	a := &auth.Authenticator{RequiredScope: cfg.RequiredScope}
+	if keys.Len() > 0 {
+		a.APIKeys = keys
+	}
+	if cfg.JWT.Enabled() {
+		opts := auth.JWTOptions{
+			HMACSecret: []byte(cfg.JWT.HMACSecret),
+			Issuer:     cfg.JWT.Issuer,
+			Audience:   cfg.JWT.Audience,
+			Leeway:     cfg.JWT.Leeway,
+		}
+		if cfg.JWT.RSAPublicKeyFile != "" {
+			if opts.RSAPublicKey, err = auth.ReadRSAPublicKey(cfg.JWT.RSAPublicKeyFile); err != nil {
+				return nil, fmt.Errorf("auth.jwt.rsa_public_key_file: %w", err)
+			}
+		}
+		if a.JWT, err = auth.NewJWTVerifier(opts); err != nil {
+			return nil, fmt.Errorf("auth.jwt: %w", err)
+		}
+	}
+	return a, nil
+}
 
-	if err := server.Shutdown(ctx); err != nil {
-		logger.Fatalf("ERROR: Server forced to shutdown: %v", err)
+// newTracer builds the tracer for the configured exporter. Export failures
+// are logged rather than surfaced to requests.
+func newTracer(cfg config.TracingConfig, logger *slog.Logger) *tracing.Tracer {
+	var exporter tracing.Exporter
+	switch cfg.Exporter {
+	case "stdout":
+		exporter = tracing.NewStdoutExporter(os.Stdout)
+	case "otlp":
+		otlp, err := tracing.NewOTLPExporter(cfg.OTLPEndpoint, "hello-api", nil)
+		if err != nil {
+			logger.Error("tracing disabled", slog.Any("error", err))
+			break
+		}
+		exporter = otlp
 	}
 
-	logger.Println("INFO: Server exited")
+	return tracing.NewTracer(exporter,
+		tracing.WithSampleRatio(cfg.SampleRatio),
+		tracing.WithErrorHandler(func(err error) {
+			logger.Warn("failed to export spans", slog.Any("error", err))
+		}),
+	)
 }
 
 type Response struct {
 	Message string `json:"message"`
+	Locale  string `json:"locale,omitempty"`
 }
 
 type Request struct {
-	Name string `json:"name"`
+	Name      string `json:"name"`
+	Lang      string `json:"lang,omitempty"`
+	Formality string `json:"formality,omitempty"`
 }
 
 type ErrorResponse struct {
-	Error string `json:"error"`
-	Code  string `json:"code"`
+	Error     string                  `json:"error"`
+	Code      string                  `json:"code"`
+	RequestID string                  `json:"request_id,omitempty"`
+	Details   []validation.FieldError `json:"details,omitempty"`
 }
 
 type HealthResponse struct {
@@ -83,83 +395,167 @@ type InfoResponse struct {
 	QueryParams map[string]string `json:"query_params"`
 }
 
-var requestCounter uint64
-
-func helloHandler(w http.ResponseWriter, r *http.Request) {
-	var name string
+func (a *app) helloHandler(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	var lang, formality string
 
 	switch r.Method {
 	case http.MethodPost:
-		contentType := r.Header.Get("Content-Type")
-		if contentType != "application/json" {
-			respondWithError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json", "INVALID_CONTENT_TYPE")
+		var ok bool
+		if names, lang, formality, ok = a.readHelloBody(w, r); !ok {
 			return
 		}
+	default:
+		query := r.URL.Query()
+		names = query["name"]
+		lang, formality = query.Get("lang"), query.Get("formality")
+	}
 
-		r.Body = http.MaxBytesReader(w, r.Body, 1048576) // 1MB limit
+	resp, err := a.greet(r.Context(), r.Header.Get("Accept-Language"), names, lang, formality)
+	if err != nil {
+		var fieldErrs validation.Errors
+		errors.As(err, &fieldErrs)
+		respondWithErrorDetails(w, r, http.StatusBadRequest, "Invalid name", "VALIDATION_FAILED", fieldErrs)
+		return
+	}
+
+	w.Header().Set("Content-Language", resp.Locale)
+	w.Header().Add("Vary", "Accept-Language")
+	respond(w, r, http.StatusOK, resp)
+}
+
+// greet validates names and builds the greeting in the locale negotiated
+// from lang and acceptLanguage. Without names it greets the principal
+// authenticated in ctx, the configured default name or the localized
+// "World", in that order. Invalid names are reported as validation.Errors;
+// a principal whose display name breaks the same rules is not greeted by it.
+func (a *app) greet(ctx context.Context, acceptLanguage string, names []string, lang, formality string) (Response, error) {
+	names, err := a.cfg.Hello.Name.Names("name", names)
+	if err != nil {
+		return Response{}, err
+	}
+
+	locale := a.catalog.Negotiate(lang, acceptLanguage)
+
+	if len(names) == 0 {
+		name := a.cfg.Hello.DefaultName
+		if p := auth.FromContext(ctx); p != nil {
+			if display, fieldErr := a.cfg.Hello.Name.Name("name", p.DisplayName()); fieldErr == nil && display != "" {
+				name = display
+			}
+		}
+		if name == "" {
+			name = a.catalog.World(locale)
+		}
+		names = append(names, name)
+	}
+
+	message := a.catalog.Greeting(locale, names, i18n.ParseFormality(formality))
+	return Response{Message: message, Locale: locale}, nil
+}
+
+// readHelloBody reads the name, lang and formality fields of a POST /hello
+// body sent as JSON, a URL-encoded form or a multipart form. It writes an
+// error response and returns false when the body cannot be read.
+func (a *app) readHelloBody(w http.ResponseWriter, r *http.Request) (names []string, lang, formality string, ok bool) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+
+	if !decodeRequestBody(w, r, a.cfg.Hello.MaxBodyBytes) {
+		return nil, "", "", false
+	}
+
+	switch mediaType {
+	case "application/json":
+		if charset, set := params["charset"]; set && !strings.EqualFold(charset, "utf-8") {
+			respondWithError(w, r, http.StatusUnsupportedMediaType, "JSON bodies must be UTF-8 encoded", "INVALID_CONTENT_TYPE")
+			return nil, "", "", false
+		}
 
 		var req Request
 		decoder := json.NewDecoder(r.Body)
 		decoder.DisallowUnknownFields()
 
 		if err := decoder.Decode(&req); err != nil {
-			respondWithError(w, http.StatusBadRequest, "Invalid JSON", "INVALID_JSON")
-			return
+			if !respondBodyTooLarge(w, r, err) {
+				respondWithError(w, r, http.StatusBadRequest, "Invalid JSON", "INVALID_JSON")
+			}
+			return nil, "", "", false
+		}
+		return []string{req.Name}, req.Lang, req.Formality, true
+	case "application/x-www-form-urlencoded":
+		err = r.ParseForm()
+	case "multipart/form-data":
+		err = r.ParseMultipartForm(a.cfg.Hello.MaxBodyBytes)
+		if r.MultipartForm != nil {
+			defer r.MultipartForm.RemoveAll()
 		}
-
-		name = req.Name
 	default:
-		name = r.URL.Query().Get("name")
+		respondWithError(w, r, http.StatusUnsupportedMediaType,
+			"Content-Type must be application/json, application/x-www-form-urlencoded or multipart/form-data", "INVALID_CONTENT_TYPE")
+		return nil, "", "", false
 	}
-
-	if name == "" {
-		name = "World"
+	if err != nil {
+		if !respondBodyTooLarge(w, r, err) {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid form data", "INVALID_FORM")
+		}
+		return nil, "", "", false
 	}
 
-	message := "Hello, " + name + "!"
-	resp := Response{Message: message}
+	// PostForm holds only body fields, so query parameters cannot leak in.
+	form := r.PostForm
+	return form["name"], form.Get("lang"), form.Get("formality"), true
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+// decodeRequestBody undoes any Content-Encoding applied to r's body and caps
+// the decoded size at limit bytes. It writes an error response and returns
+// false when the encoding is unsupported or malformed.
+func decodeRequestBody(w http.ResponseWriter, r *http.Request, limit int64) bool {
+	if err := compress.DecodeRequest(r); err != nil {
+		if errors.Is(err, compress.ErrUnsupportedEncoding) {
+			w.Header().Set("Accept-Encoding", compress.RequestEncodings)
+			respondWithError(w, r, http.StatusUnsupportedMediaType, "Content-Encoding must be a single gzip or deflate coding", "UNSUPPORTED_ENCODING")
+		} else {
+			respondWithError(w, r, http.StatusBadRequest, "Invalid compressed body", "INVALID_ENCODING")
+		}
+		return false
+	}
+	// Applied after decoding so the limit bounds the decompressed size.
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	return true
+}
 
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.Printf("ERROR: Failed to encode response: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Internal Server Error", "ENCODING_ERROR")
-		return
+// respondBodyTooLarge reports a body that exceeded its http.MaxBytesReader
+// limit with 413 Content Too Large and returns true, or returns false for
+// any other error.
+func respondBodyTooLarge(w http.ResponseWriter, r *http.Request, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
 	}
+	respondWithError(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit), "BODY_TOO_LARGE")
+	return true
 }
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
+func (a *app) healthHandler(w http.ResponseWriter, r *http.Request) {
 	resp := HealthResponse{Status: "healthy"}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.Printf("ERROR: Failed to encode health response: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Internal Server Error", "ENCODING_ERROR")
-		return
-	}
+	respond(w, r, http.StatusOK, resp)
 }
 
-func pingHandler(w http.ResponseWriter, r *http.Request) {
+func (a *app) pingHandler(w http.ResponseWriter, r *http.Request) {
 	resp := PingResponse{Pong: "pong"}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.Printf("ERROR: Failed to encode ping response: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Internal Server Error", "ENCODING_ERROR")
-		return
-	}
+	respond(w, r, http.StatusOK, resp)
 }
 
-func infoHandler(w http.ResponseWriter, r *http.Request) {
+func (a *app) infoHandler(w http.ResponseWriter, r *http.Request) {
 	headers := make(map[string]string)
 	for key, values := range r.Header {
 		if len(values) > 0 {
-			headers[key] = values[0]
+			headers[key] = redactCredential(key, values[0])
 		}
 	}
 
@@ -180,35 +576,150 @@ func infoHandler(w http.ResponseWriter, r *http.Request) {
 		QueryParams: queryParams,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	respond(w, r, http.StatusOK, resp)
+}
+
+// credentialHeaders are the headers whose values /info and the Info RPC
+// replace with redacted, so credentials are never echoed back.
+var credentialHeaders = []string{"Authorization", auth.APIKeyHeader, "Cookie", "Proxy-Authorization"}
+
+const redacted = "[REDACTED]"
+
+// redactCredential returns value, or redacted when name is a credential
+// header. gRPC metadata keys are lowercase, so names are matched
+// case-insensitively.
+func redactCredential(name, value string) string {
+	for _, h := range credentialHeaders {
+		if strings.EqualFold(name, h) {
+			return redacted
+		}
+	}
+	return value
+}
+
+func rateLimitedHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithError(w, r, http.StatusTooManyRequests, "Too many requests", "RATE_LIMITED")
+}
 
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.Printf("ERROR: Failed to encode info response: %v", err)
-		respondWithError(w, http.StatusInternalServerError, "Internal Server Error", "ENCODING_ERROR")
+func authErrorHandler(w http.ResponseWriter, r *http.Request, err *auth.Error) {
+	respondWithError(w, r, err.Status, err.Message, err.Code)
+}
+
+func respondWithError(w http.ResponseWriter, r *http.Request, code int, message string, errorCode string) {
+	respondWithErrorDetails(w, r, code, message, errorCode, nil)
+}
+
+// respondWithErrorDetails writes an ErrorResponse carrying field-level
+// validation errors, or the equivalent Problem when problem details are
+// configured or requested.
+func respondWithErrorDetails(w http.ResponseWriter, r *http.Request, code int, message string, errorCode string, details []validation.FieldError) {
+	accept := r.Header.Get("Accept")
+
+	// Errors are still reported when the client accepts none of our types.
+	enc, ok := encoders.Negotiate(accept)
+	if !ok {
+		enc = encoders.Fallback()
+	}
+
+	if problemDetails(r.Context()) || render.Mentions(accept, problemJSON.Type) {
+		if _, isJSON := enc.(render.JSON); isJSON {
+			enc = problemJSON
+		}
+		writeResponse(w, r, enc, code, newProblem(r, code, message, errorCode, details))
 		return
 	}
+
+	writeResponse(w, r, enc, code, ErrorResponse{
+		Error:     message,
+		Code:      errorCode,
+		RequestID: requestid.FromContext(r.Context()),
+		Details:   details,
+	})
 }
 
-func respondWithError(w http.ResponseWriter, code int, message string, errorCode string) {
-	errResp := ErrorResponse{
-		Error: message,
-		Code:  errorCode,
+// problemTypeBase prefixes error codes to form problem type URIs, for
+// example urn:hello-api:problem:validation-failed.
+const problemTypeBase = "urn:hello-api:problem:"
+
+var problemJSON = render.JSON{Type: "application/problem+json"}
+
+// Problem is an RFC 9457 problem details object. Code, RequestID and Errors
+// are extension members carrying the same data as ErrorResponse.
+type Problem struct {
+	Type      string                  `json:"type"`
+	Title     string                  `json:"title"`
+	Status    int                     `json:"status"`
+	Detail    string                  `json:"detail,omitempty"`
+	Instance  string                  `json:"instance,omitempty"`
+	Code      string                  `json:"code"`
+	RequestID string                  `json:"request_id,omitempty"`
+	Errors    []validation.FieldError `json:"errors,omitempty"`
+}
+
+func newProblem(r *http.Request, code int, message string, errorCode string, details []validation.FieldError) Problem {
+	return Problem{
+		Type:      problemTypeBase + strings.ToLower(strings.ReplaceAll(errorCode, "_", "-")),
+		Title:     http.StatusText(code),
+		Status:    code,
+		Detail:    message,
+		Instance:  r.URL.Path,
+		Code:      errorCode,
+		RequestID: requestid.FromContext(r.Context()),
+		Errors:    details,
 	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
+type problemDetailsKey struct{}
+
+// errorFormatMiddleware records whether errors.format selects problem
+// details, so error responses written anywhere in the chain agree.
+func errorFormatMiddleware(format string, next http.Handler) http.Handler {
+	if format != "problem" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), problemDetailsKey{}, true)))
+	})
+}
+
+func problemDetails(ctx context.Context) bool {
+	enabled, _ := ctx.Value(problemDetailsKey{}).(bool)
+	return enabled
+}
+
+// encoders renders response bodies in the media types clients may ask for.
+var encoders = render.Default()
+
+// respond writes v in the media type negotiated from the request's Accept
+// header, or a 406 Not Acceptable error when none of ours is acceptable.
+func respond(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	enc, ok := encoders.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		respondWithError(w, r, http.StatusNotAcceptable, "Not acceptable", "NOT_ACCEPTABLE")
+		return
+	}
+	writeResponse(w, r, enc, code, v)
+}
+
+func writeResponse(w http.ResponseWriter, r *http.Request, enc render.Encoder, code int, v interface{}) {
+	w.Header().Set("Content-Type", enc.ContentType())
+	w.Header().Add("Vary", "Accept")
 	w.WriteHeader(code)
 
-	if err := json.NewEncoder(w).Encode(errResp); err != nil {
-		log.Printf("ERROR: Failed to encode error response: %v", err)
-		http.Error(w, message, code)
+	if err := enc.Encode(w, v); err != nil {
+		logging.FromContext(r.Context()).Error("failed to encode response", slog.Any("error", err))
 	}
 }
 
-func loggingMiddleware(logger *log.Logger, next http.Handler) http.Handler {
+func loggingMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		requestID := atomic.AddUint64(&requestCounter, 1)
+
+		reqLogger := logger.With(slog.String("request_id", requestid.FromContext(r.Context())))
+		if sc := tracing.SpanContextFromContext(r.Context()); sc.IsValid() {
+			reqLogger = reqLogger.With(slog.String("trace_id", sc.TraceID.String()), slog.String("span_id", sc.SpanID.String()))
+		}
+		r = r.WithContext(logging.WithLogger(r.Context(), reqLogger))
 
 		wrapped := &responseWriter{
 			ResponseWriter: w,
@@ -217,24 +728,148 @@ func loggingMiddleware(logger *log.Logger, next http.Handler) http.Handler {
 
 		defer func() {
 			if err := recover(); err != nil {
-				logger.Printf("ERROR: Panic recovered: %v", err)
-				respondWithError(w, http.StatusInternalServerError, "Internal Server Error", "PANIC_RECOVERY")
+				reqLogger.Error("panic recovered", slog.Any("panic", err), slog.String("method", r.Method), slog.String("path", r.URL.Path))
+				respondWithError(w, r, http.StatusInternalServerError, "Internal Server Error", "PANIC_RECOVERY")
 			}
 		}()
 
 		next.ServeHTTP(wrapped, r)
 
-		duration := time.Since(start)
-		logger.Printf("INFO: [%d] %s %s %d %v", requestID, r.Method, r.URL.Path, wrapped.statusCode, duration)
+		level := slog.LevelInfo
+		switch {
+		case wrapped.statusCode >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case wrapped.statusCode >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		reqLogger.LogAttrs(r.Context(), level, "request completed",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", wrapped.statusCode),
+			slog.Int64("bytes", wrapped.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote_addr", r.RemoteAddr),
+		)
+	})
+}
+
+// methodMiddleware rejects requests whose method is not in methods with 405
+// Method Not Allowed. HEAD is served by the GET handler with the body
+// discarded, and OPTIONS is answered with the Allow header.
+func methodMiddleware(methods []string, next http.Handler) http.Handler {
+	allowed := slices.Clone(methods)
+	if slices.Contains(allowed, http.MethodGet) && !slices.Contains(allowed, http.MethodHead) {
+		allowed = append(allowed, http.MethodHead)
+	}
+	allowed = append(allowed, http.MethodOptions)
+	allow := strings.Join(allowed, ", ")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodOptions:
+			w.Header().Set("Allow", allow)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodHead && !slices.Contains(methods, http.MethodHead):
+			next.ServeHTTP(headResponseWriter{w}, r)
+		case slices.Contains(allowed, r.Method):
+			next.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Allow", allow)
+			respondWithError(w, r, http.StatusMethodNotAllowed, "Method not allowed", "METHOD_NOT_ALLOWED")
+		}
+	})
+}
+
+// headResponseWriter drops the body a GET handler writes in response to a
+// HEAD request.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (headResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+
+func (w headResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// httpMetrics are the per-route request metrics recorded by metricsMiddleware.
+type httpMetrics struct {
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+	inFlight *metrics.GaugeVec
+}
+
+func newHTTPMetrics(reg *metrics.Registry) *httpMetrics {
+	return &httpMetrics{
+		requests: metrics.NewCounterVec(reg, "http_requests_total",
+			"Total number of HTTP requests processed.", "route", "method", "code"),
+		duration: metrics.NewHistogramVec(reg, "http_request_duration_seconds",
+			"HTTP request latency in seconds.", metrics.DefBuckets, "route", "method", "code"),
+		inFlight: metrics.NewGaugeVec(reg, "http_requests_in_flight",
+			"Number of HTTP requests currently being served.", "route", "method"),
+	}
+}
+
+func metricsMiddleware(m *httpMetrics, route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		method := methodLabel(r.Method)
+		m.inFlight.Inc(route, method)
+		defer m.inFlight.Dec(route, method)
+
+		wrapped := &responseWriter{
+			ResponseWriter: w,
+			statusCode:     http.StatusOK,
+		}
+
+		next.ServeHTTP(wrapped, r)
+
+		code := strconv.Itoa(wrapped.statusCode)
+		m.requests.Inc(route, method, code)
+		m.duration.Observe(time.Since(start).Seconds(), route, method, code)
 	})
 }
 
+// methodLabel returns method for the standard HTTP methods and "other" for
+// anything else, so clients cannot create new series by inventing methods.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
+
+// responseWriter captures the status code and body size written by a handler.
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int64
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, so
+// streaming handlers can flush through the middleware chain.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Hijack records the switch to another protocol, such as WebSocket, before
+// handing over the connection.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil {
+		rw.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
@@ -1,15 +1,72 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
-	"log"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"hello-api/api"
+	hellov1 "hello-api/api/hello/v1"
+	"hello-api/internal/auth"
+	"hello-api/internal/compress"
+	"hello-api/internal/config"
+	"hello-api/internal/grpcserver"
+	"hello-api/internal/logging"
+	"hello-api/internal/openapi"
+	"hello-api/internal/requestid"
+	"hello-api/internal/sse"
+	"hello-api/internal/validation"
+	"hello-api/internal/websocket"
+	"hello-api/internal/websocket/websockettest"
+	"hello-api/pkg/client"
 )
 
+func testApp() *app {
+	a, err := newApp(config.Default(), logging.Discard())
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// mustNewApp is newApp for tests with a custom configuration.
+func mustNewApp(t testing.TB, cfg *config.Config, logger *slog.Logger) *app {
+	t.Helper()
+	a, err := newApp(cfg, logger)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return a
+}
+
 func TestHelloHandler(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -79,7 +136,7 @@ func TestHelloHandler(t *testing.T) {
 			}
 			rec := httptest.NewRecorder()
 
-			helloHandler(rec, req)
+			testApp().helloHandler(rec, req)
 
 			if rec.Code != tt.expectedStatus {
 				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
@@ -102,13 +159,184 @@ func TestHelloHandler(t *testing.T) {
 	}
 }
 
+func TestHelloLocalization(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		url             string
+		body            string
+		acceptLanguage  string
+		expectedMessage string
+		expectedLocale  string
+	}{
+		{
+			name:            "default locale",
+			method:          http.MethodGet,
+			url:             "/hello",
+			expectedMessage: "Hello, World!",
+			expectedLocale:  "en",
+		},
+		{
+			name:            "Accept-Language negotiation",
+			method:          http.MethodGet,
+			url:             "/hello",
+			acceptLanguage:  "fr-CH, de;q=0.9",
+			expectedMessage: "Salut, Monde !",
+			expectedLocale:  "fr",
+		},
+		{
+			name:            "lang query parameter overrides header",
+			method:          http.MethodGet,
+			url:             "/hello?name=Ana&lang=es",
+			acceptLanguage:  "de",
+			expectedMessage: "¡Hola, Ana!",
+			expectedLocale:  "es",
+		},
+		{
+			name:            "formal plural greeting",
+			method:          http.MethodGet,
+			url:             "/hello?name=Anna&name=Ben&lang=de&formality=formal",
+			expectedMessage: "Guten Tag Ihnen allen, Anna und Ben.",
+			expectedLocale:  "de",
+		},
+		{
+			name:            "lang and formality in JSON body",
+			method:          http.MethodPost,
+			url:             "/hello",
+			body:            `{"name":"田中","lang":"ja","formality":"formal"}`,
+			expectedMessage: "田中様、こんにちは。",
+			expectedLocale:  "ja",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			rec := httptest.NewRecorder()
+
+			testApp().helloHandler(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Language"); got != tt.expectedLocale {
+				t.Errorf("expected Content-Language '%s', got '%s'", tt.expectedLocale, got)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Language" {
+				t.Errorf("expected Vary 'Accept-Language', got '%s'", got)
+			}
+
+			var resp Response
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Message != tt.expectedMessage {
+				t.Errorf("expected message '%s', got '%s'", tt.expectedMessage, resp.Message)
+			}
+			if resp.Locale != tt.expectedLocale {
+				t.Errorf("expected locale '%s', got '%s'", tt.expectedLocale, resp.Locale)
+			}
+		})
+	}
+}
+
+func TestHelloValidation(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		url             string
+		body            string
+		expectedStatus  int
+		expectedMessage string
+		expectedDetails []validation.FieldError
+	}{
+		{
+			name:            "control characters are stripped",
+			method:          http.MethodGet,
+			url:             "/hello?name=Bo%00b",
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hello, Bob!",
+		},
+		{
+			name:            "name is normalized and trimmed",
+			method:          http.MethodPost,
+			url:             "/hello",
+			body:            `{"name":"  Jose\u0301 "}`,
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hello, Jos\u00e9!",
+		},
+		{
+			name:           "overlong name is rejected",
+			method:         http.MethodPost,
+			url:            "/hello",
+			body:           `{"name":"` + strings.Repeat("a", 101) + `"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedDetails: []validation.FieldError{
+				{Field: "name", Code: "too_long", Message: "must be at most 100 characters, got 101"},
+			},
+		},
+		{
+			name:           "markup is rejected per repeated name",
+			method:         http.MethodGet,
+			url:            "/hello?name=Alice&name=%3Cscript%3E",
+			expectedStatus: http.StatusBadRequest,
+			expectedDetails: []validation.FieldError{
+				{Field: "name[1]", Code: "invalid_characters", Message: `contains disallowed character '<'`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+
+			testApp().helloHandler(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				var resp Response
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Message != tt.expectedMessage {
+					t.Errorf("expected message %q, got %q", tt.expectedMessage, resp.Message)
+				}
+				return
+			}
+
+			var resp ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Code != "VALIDATION_FAILED" {
+				t.Errorf("expected code VALIDATION_FAILED, got %s", resp.Code)
+			}
+			if !reflect.DeepEqual(resp.Details, tt.expectedDetails) {
+				t.Errorf("expected details %+v, got %+v", tt.expectedDetails, resp.Details)
+			}
+		})
+	}
+}
+
 func TestServerConfiguration(t *testing.T) {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/hello", helloHandler)
+	app := testApp()
 
 	server := &http.Server{
-		Addr:    ":8080",
-		Handler: mux,
+		Addr:    app.cfg.Server.Addr,
+		Handler: app.routes(),
 	}
 
 	if server.Addr != ":8080" {
@@ -123,10 +351,12 @@ func TestServerConfiguration(t *testing.T) {
 func BenchmarkHelloHandler(b *testing.B) {
 	req := httptest.NewRequest(http.MethodGet, "/hello", nil)
 
+	app := testApp()
+
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		rec := httptest.NewRecorder()
-		helloHandler(rec, req)
+		app.helloHandler(rec, req)
 	}
 }
 
@@ -175,7 +405,7 @@ func TestPOSTHandler(t *testing.T) {
 			body:           Request{Name: "Bob"},
 			contentType:    "",
 			expectedStatus: http.StatusUnsupportedMediaType,
-			expectedBody:   `{"error":"Content-Type must be application/json","code":"INVALID_CONTENT_TYPE"}`,
+			expectedBody:   `{"error":"Content-Type must be application/json, application/x-www-form-urlencoded or multipart/form-data","code":"INVALID_CONTENT_TYPE"}`,
 		},
 		{
 			name:           "POST with invalid JSON",
@@ -214,7 +444,7 @@ func TestPOSTHandler(t *testing.T) {
 			}
 			rec := httptest.NewRecorder()
 
-			helloHandler(rec, req)
+			testApp().helloHandler(rec, req)
 
 			if rec.Code != tt.expectedStatus {
 				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
@@ -246,192 +476,2548 @@ func TestPOSTHandler(t *testing.T) {
 	}
 }
 
-func TestHealthHandler(t *testing.T) {
-	req := httptest.NewRequest(http.MethodGet, "/health", nil)
-	rec := httptest.NewRecorder()
-
-	healthHandler(rec, req)
-
-	if rec.Code != http.StatusOK {
-		t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
-	}
-
-	contentType := rec.Header().Get("Content-Type")
-	if contentType != "application/json" {
-		t.Errorf("expected Content-Type 'application/json', got '%s'", contentType)
-	}
-
-	var resp HealthResponse
-	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
-	}
-
-	if resp.Status != "healthy" {
-		t.Errorf("expected status 'healthy', got '%s'", resp.Status)
+func TestPOSTBodyFormats(t *testing.T) {
+	multipartBody := func(fields ...[2]string) (string, string) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for _, f := range fields {
+			mw.WriteField(f[0], f[1])
+		}
+		mw.Close()
+		return buf.String(), mw.FormDataContentType()
 	}
-}
+	single, singleType := multipartBody([2]string{"name", "Grace"})
+	plural, pluralType := multipartBody([2]string{"name", "Anna"}, [2]string{"name", "Ben"}, [2]string{"lang", "de"})
 
-func TestPingHandler(t *testing.T) {
 	tests := []struct {
-		name           string
-		method         string
-		expectedStatus int
-		expectedPong   string
+		name            string
+		url             string
+		body            string
+		contentType     string
+		expectedStatus  int
+		expectedMessage string
+		expectedCode    string
 	}{
 		{
-			name:           "GET ping returns pong",
-			method:         http.MethodGet,
-			expectedStatus: http.StatusOK,
-			expectedPong:   "pong",
+			name:            "JSON with charset",
+			body:            `{"name":"Alice"}`,
+			contentType:     "application/json; charset=utf-8",
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hello, Alice!",
 		},
 		{
-			name:           "POST ping returns pong",
-			method:         http.MethodPost,
-			expectedStatus: http.StatusOK,
-			expectedPong:   "pong",
+			name:            "JSON media type is case insensitive",
+			body:            `{"name":"Alice"}`,
+			contentType:     "Application/JSON",
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hello, Alice!",
 		},
 		{
-			name:           "PUT ping returns pong",
-			method:         http.MethodPut,
-			expectedStatus: http.StatusOK,
-			expectedPong:   "pong",
+			name:           "JSON with other charset",
+			body:           `{"name":"Alice"}`,
+			contentType:    "application/json; charset=latin1",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   "INVALID_CONTENT_TYPE",
+		},
+		{
+			name:            "URL-encoded form",
+			body:            "name=Linus&formality=formal",
+			contentType:     "application/x-www-form-urlencoded",
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Good day, Linus.",
+		},
+		{
+			name:            "URL-encoded form ignores query parameters",
+			url:             "/hello?name=Mallory",
+			body:            "submit=Send",
+			contentType:     "application/x-www-form-urlencoded",
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hello, World!",
+		},
+		{
+			name:           "malformed URL-encoded form",
+			body:           "name=%zz",
+			contentType:    "application/x-www-form-urlencoded",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_FORM",
+		},
+		{
+			name:            "multipart form",
+			body:            single,
+			contentType:     singleType,
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hello, Grace!",
+		},
+		{
+			name:            "multipart form with repeated names",
+			body:            plural,
+			contentType:     pluralType,
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hallo zusammen, Anna und Ben!",
+		},
+		{
+			name:           "multipart form without boundary",
+			body:           single,
+			contentType:    "multipart/form-data",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_FORM",
+		},
+		{
+			name:           "form field fails validation",
+			body:           "name=%3Cscript%3E",
+			contentType:    "application/x-www-form-urlencoded",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "VALIDATION_FAILED",
+		},
+		{
+			name:           "unsupported media type",
+			body:           "name: Alice",
+			contentType:    "text/yaml",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   "INVALID_CONTENT_TYPE",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(tt.method, "/ping", nil)
+			url := tt.url
+			if url == "" {
+				url = "/hello"
+			}
+			req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
 			rec := httptest.NewRecorder()
 
-			pingHandler(rec, req)
+			testApp().helloHandler(rec, req)
 
 			if rec.Code != tt.expectedStatus {
-				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
 			}
 
-			contentType := rec.Header().Get("Content-Type")
-			if contentType != "application/json" {
-				t.Errorf("expected Content-Type 'application/json', got '%s'", contentType)
+			if tt.expectedCode != "" {
+				var resp ErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Code != tt.expectedCode {
+					t.Errorf("expected code '%s', got '%s'", tt.expectedCode, resp.Code)
+				}
+				return
 			}
 
-			var resp PingResponse
+			var resp Response
 			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
 				t.Fatalf("failed to decode response: %v", err)
 			}
-
-			if resp.Pong != tt.expectedPong {
-				t.Errorf("expected pong '%s', got '%s'", tt.expectedPong, resp.Pong)
+			if resp.Message != tt.expectedMessage {
+				t.Errorf("expected message '%s', got '%s'", tt.expectedMessage, resp.Message)
 			}
 		})
 	}
 }
 
-func TestInfoHandler(t *testing.T) {
+func TestCompressedRequestBodies(t *testing.T) {
+	compressed := func(data string) string {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(data))
+		zw.Close()
+		return buf.String()
+	}
+	// Over 1MB once decompressed, but only a few kilobytes on the wire.
+	bomb := compressed(`{"name":"` + strings.Repeat("a", 2<<20) + `"}`)
+
 	tests := []struct {
-		name           string
-		method         string
-		url            string
-		headers        map[string]string
-		expectedStatus int
-		validate       func(t *testing.T, resp InfoResponse)
+		name                   string
+		body                   string
+		contentType            string
+		contentEncoding        string
+		expectedStatus         int
+		expectedMessage        string
+		expectedCode           string
+		expectedAcceptEncoding string
 	}{
 		{
-			name:           "GET info with basic request",
-			method:         http.MethodGet,
-			url:            "/info",
-			expectedStatus: http.StatusOK,
-			validate: func(t *testing.T, resp InfoResponse) {
-				if resp.Method != http.MethodGet {
-					t.Errorf("expected method %s, got %s", http.MethodGet, resp.Method)
-				}
-				if resp.URL != "/info" {
-					t.Errorf("expected URL /info, got %s", resp.URL)
-				}
-			},
+			name:            "gzip JSON",
+			body:            compressed(`{"name":"Alice"}`),
+			contentType:     "application/json",
+			contentEncoding: "gzip",
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hello, Alice!",
 		},
 		{
-			name:           "GET info with query parameters",
-			method:         http.MethodGet,
-			url:            "/info?foo=bar&test=123",
-			expectedStatus: http.StatusOK,
-			validate: func(t *testing.T, resp InfoResponse) {
-				if resp.QueryParams["foo"] != "bar" {
-					t.Errorf("expected query param foo=bar, got foo=%s", resp.QueryParams["foo"])
-				}
-				if resp.QueryParams["test"] != "123" {
-					t.Errorf("expected query param test=123, got test=%s", resp.QueryParams["test"])
-				}
-			},
+			name:            "gzip form",
+			body:            compressed("name=Bob"),
+			contentType:     "application/x-www-form-urlencoded",
+			contentEncoding: "gzip",
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hello, Bob!",
 		},
 		{
-			name:   "POST info with custom headers",
-			method: http.MethodPost,
-			url:    "/info",
-			headers: map[string]string{
-				"X-Custom-Header": "custom-value",
-				"User-Agent":      "test-agent",
-			},
-			expectedStatus: http.StatusOK,
-			validate: func(t *testing.T, resp InfoResponse) {
-				if resp.Method != http.MethodPost {
-					t.Errorf("expected method %s, got %s", http.MethodPost, resp.Method)
-				}
-				if resp.Headers["X-Custom-Header"] != "custom-value" {
-					t.Errorf("expected header X-Custom-Header=custom-value, got %s", resp.Headers["X-Custom-Header"])
-				}
-				if resp.UserAgent != "test-agent" {
-					t.Errorf("expected user agent test-agent, got %s", resp.UserAgent)
-				}
-			},
+			name:            "decompressed size over the limit",
+			body:            bomb,
+			contentType:     "application/json",
+			contentEncoding: "gzip",
+			expectedStatus:  http.StatusRequestEntityTooLarge,
+			expectedCode:    "BODY_TOO_LARGE",
+		},
+		{
+			name:            "corrupt gzip",
+			body:            `{"name":"Alice"}`,
+			contentType:     "application/json",
+			contentEncoding: "gzip",
+			expectedStatus:  http.StatusBadRequest,
+			expectedCode:    "INVALID_ENCODING",
+		},
+		{
+			name:                   "unsupported encoding",
+			body:                   `{"name":"Alice"}`,
+			contentType:            "application/json",
+			contentEncoding:        "br",
+			expectedStatus:         http.StatusUnsupportedMediaType,
+			expectedCode:           "UNSUPPORTED_ENCODING",
+			expectedAcceptEncoding: "gzip, deflate",
+		},
+		{
+			name:                   "stacked encodings",
+			body:                   compressed(compressed(`{"name":"Alice"}`)),
+			contentType:            "application/json",
+			contentEncoding:        "gzip, gzip",
+			expectedStatus:         http.StatusUnsupportedMediaType,
+			expectedCode:           "UNSUPPORTED_ENCODING",
+			expectedAcceptEncoding: "gzip, deflate",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(tt.method, tt.url, nil)
-
-			// Add custom headers if provided
-			for key, value := range tt.headers {
-				req.Header.Set(key, value)
-			}
-
-			// Set a default host if not provided
-			if req.Host == "" {
-				req.Host = "example.com"
-			}
-
-			// Set RemoteAddr for testing
-			req.RemoteAddr = "192.168.1.1:12345"
-
+			req := httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Content-Encoding", tt.contentEncoding)
 			rec := httptest.NewRecorder()
 
-			infoHandler(rec, req)
+			testApp().helloHandler(rec, req)
 
 			if rec.Code != tt.expectedStatus {
-				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Accept-Encoding"); got != tt.expectedAcceptEncoding {
+				t.Errorf("expected Accept-Encoding '%s', got '%s'", tt.expectedAcceptEncoding, got)
 			}
 
-			contentType := rec.Header().Get("Content-Type")
-			if contentType != "application/json" {
-				t.Errorf("expected Content-Type 'application/json', got '%s'", contentType)
+			if tt.expectedCode != "" {
+				var resp ErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Code != tt.expectedCode {
+					t.Errorf("expected code '%s', got '%s'", tt.expectedCode, resp.Code)
+				}
+				return
 			}
 
-			var resp InfoResponse
+			var resp Response
 			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
 				t.Fatalf("failed to decode response: %v", err)
 			}
-
-			// Basic validations that apply to all tests
-			if resp.Host != req.Host {
-				t.Errorf("expected host %s, got %s", req.Host, resp.Host)
-			}
-			if resp.RemoteAddr != req.RemoteAddr {
-				t.Errorf("expected remote addr %s, got %s", req.RemoteAddr, resp.RemoteAddr)
-			}
-
-			// Run test-specific validations
-			if tt.validate != nil {
-				tt.validate(t, resp)
+			if resp.Message != tt.expectedMessage {
+				t.Errorf("expected message '%s', got '%s'", tt.expectedMessage, resp.Message)
+			}
+		})
+	}
+}
+
+func TestHelloBatch(t *testing.T) {
+	tooLong := strings.Repeat("a", 101)
+
+	tests := []struct {
+		name                string
+		body                string
+		contentType         string
+		accept              string
+		expectedStatus      int
+		expectedContentType string
+		expectedCode        string
+		expectedResults     []BatchResult
+	}{
+		{
+			name:                "JSON array",
+			body:                `[{"name":"Alice"},{"name":"Ana","lang":"es"},{}]`,
+			contentType:         "application/json",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedResults: []BatchResult{
+				{Index: 0, Message: "Hello, Alice!", Locale: "en"},
+				{Index: 1, Message: "¡Hola, Ana!", Locale: "es"},
+				{Index: 2, Message: "Hello, World!", Locale: "en"},
+			},
+		},
+		{
+			name:                "per-item errors",
+			body:                `[{"name":"Alice"},{"name":"` + tooLong + `"},{"nickname":"Bob"},42]`,
+			contentType:         "application/json",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedResults: []BatchResult{
+				{Index: 0, Message: "Hello, Alice!", Locale: "en"},
+				{Index: 1, Error: &ErrorResponse{Error: "Invalid name", Code: "VALIDATION_FAILED", RequestID: "req-batch", Details: []validation.FieldError{
+					{Field: "name", Code: "too_long", Message: "must be at most 100 characters, got 101"},
+				}}},
+				{Index: 2, Error: &ErrorResponse{Error: "Invalid request", Code: "VALIDATION_FAILED", RequestID: "req-batch", Details: []validation.FieldError{
+					{Field: "nickname", Code: "unknown_field", Message: "is not allowed"},
+				}}},
+				{Index: 3, Error: &ErrorResponse{Error: "Invalid request", Code: "VALIDATION_FAILED", RequestID: "req-batch", Details: []validation.FieldError{
+					{Field: "item", Code: "invalid_type", Message: "must be an object"},
+				}}},
+			},
+		},
+		{
+			name:                "NDJSON in and out",
+			body:                "{\"name\":\"Alice\"}\n\n{\"name\":\n{\"name\":\"Bob\"}",
+			contentType:         "application/x-ndjson",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedResults: []BatchResult{
+				{Index: 0, Message: "Hello, Alice!", Locale: "en"},
+				{Index: 1, Error: &ErrorResponse{Error: "Invalid JSON", Code: "INVALID_JSON", RequestID: "req-batch"}},
+				{Index: 2, Message: "Hello, Bob!", Locale: "en"},
+			},
+		},
+		{
+			name:                "NDJSON in, JSON out",
+			body:                `{"name":"Alice"}`,
+			contentType:         "application/x-ndjson",
+			accept:              "application/json",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedResults:     []BatchResult{{Index: 0, Message: "Hello, Alice!", Locale: "en"}},
+		},
+		{
+			name:                "JSON in, NDJSON out",
+			body:                `[{"name":"Alice"}]`,
+			contentType:         "application/json",
+			accept:              "application/x-ndjson",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedResults:     []BatchResult{{Index: 0, Message: "Hello, Alice!", Locale: "en"}},
+		},
+		{
+			name:           "not an array",
+			body:           `{"name":"Alice"}`,
+			contentType:    "application/json",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "VALIDATION_FAILED",
+		},
+		{
+			name:           "truncated array",
+			body:           `[{"name":"Alice"},`,
+			contentType:    "application/json",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "INVALID_JSON",
+		},
+		{
+			name:           "empty batch",
+			body:           `[]`,
+			contentType:    "application/json",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "EMPTY_BATCH",
+		},
+		{
+			name:           "too many items",
+			body:           `[{},{},{},{},{}]`,
+			contentType:    "application/json",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   "BATCH_TOO_LARGE",
+		},
+		{
+			name:           "too many NDJSON items",
+			body:           "{}\n{}\n{}\n{}\n{}\n",
+			contentType:    "application/x-ndjson",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   "BATCH_TOO_LARGE",
+		},
+		{
+			name:           "unsupported content type",
+			body:           "name=Alice",
+			contentType:    "application/x-www-form-urlencoded",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   "INVALID_CONTENT_TYPE",
+		},
+	}
+
+	cfg := config.Default()
+	cfg.Hello.Batch.MaxItems = 4
+	app := mustNewApp(t, cfg, logging.Discard())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/hello/batch", strings.NewReader(tt.body))
+			req = req.WithContext(requestid.WithID(req.Context(), "req-batch"))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+
+			app.helloBatchHandler(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+
+			if tt.expectedCode != "" {
+				var resp ErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Code != tt.expectedCode {
+					t.Errorf("expected code '%s', got '%s'", tt.expectedCode, resp.Code)
+				}
+				return
+			}
+
+			if got := rec.Header().Get("Content-Type"); got != tt.expectedContentType {
+				t.Errorf("expected Content-Type '%s', got '%s'", tt.expectedContentType, got)
+			}
+
+			var results []BatchResult
+			if tt.expectedContentType == "application/x-ndjson" {
+				decoder := json.NewDecoder(rec.Body)
+				for decoder.More() {
+					var result BatchResult
+					if err := decoder.Decode(&result); err != nil {
+						t.Fatalf("failed to decode result: %v", err)
+					}
+					results = append(results, result)
+				}
+			} else {
+				var resp BatchResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				results = resp.Results
+
+				var failed int
+				for _, result := range tt.expectedResults {
+					if result.Error != nil {
+						failed++
+					}
+				}
+				if resp.Succeeded != len(tt.expectedResults)-failed || resp.Failed != failed {
+					t.Errorf("expected %d succeeded and %d failed, got %d and %d",
+						len(tt.expectedResults)-failed, failed, resp.Succeeded, resp.Failed)
+				}
+			}
+
+			if !reflect.DeepEqual(results, tt.expectedResults) {
+				t.Errorf("expected results %+v, got %+v", tt.expectedResults, results)
+			}
+		})
+	}
+}
+
+func TestHelloBatchMetrics(t *testing.T) {
+	handler := testApp().routes()
+
+	req := httptest.NewRequest(http.MethodPost, "/hello/batch", strings.NewReader(`[{"name":"Alice"},{"name":"<b>"}]`))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`hello_batch_size_bucket{le="1"} 0`,
+		`hello_batch_size_bucket{le="5"} 1`,
+		"hello_batch_size_sum 2",
+		`hello_batch_items_total{result="succeeded"} 1`,
+		`hello_batch_items_total{result="failed"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics output to contain %q", want)
+		}
+	}
+	if strings.Contains(body, "FOOXXX") {
+		t.Error("expected unknown methods not to be used as labels")
+	}
+}
+
+func TestHelloStream(t *testing.T) {
+	cfg := config.Default()
+	cfg.Hello.Stream.Interval = 10 * time.Millisecond
+	cfg.Hello.Stream.Heartbeat = 25 * time.Millisecond
+	app := mustNewApp(t, cfg, logging.Discard())
+	srv := httptest.NewServer(app.routes())
+	defer srv.Close()
+
+	open := func(t *testing.T, url string, header http.Header) (*http.Response, *bufio.Reader) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+url, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp, bufio.NewReader(resp.Body)
+	}
+
+	// readEvent returns the fields of the next event, skipping comments.
+	readEvent := func(t *testing.T, br *bufio.Reader) map[string]string {
+		t.Helper()
+		fields := make(map[string]string)
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				t.Fatalf("failed to read event: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "" && len(fields) > 0:
+				return fields
+			case line == "" || strings.HasPrefix(line, ":"):
+				continue
+			}
+			name, value, _ := strings.Cut(line, ": ")
+			fields[name] = value
+		}
+	}
+
+	t.Run("greetings", func(t *testing.T) {
+		resp, br := open(t, "/hello/stream?name=Ana&lang=es", http.Header{"Accept-Encoding": {"gzip"}})
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		for header, want := range map[string]string{
+			"Content-Type":     "text/event-stream",
+			"Cache-Control":    "no-cache",
+			"Content-Language": "es",
+			"Content-Encoding": "",
+		} {
+			if got := resp.Header.Get(header); got != want {
+				t.Errorf("expected %s '%s', got '%s'", header, want, got)
+			}
+		}
+
+		for i := 1; i <= 3; i++ {
+			event := readEvent(t, br)
+			if event["id"] != strconv.Itoa(i) {
+				t.Errorf("expected id %d, got '%s'", i, event["id"])
+			}
+			if event["event"] != "greeting" {
+				t.Errorf("expected event 'greeting', got '%s'", event["event"])
+			}
+			expectedRetry := ""
+			if i == 1 {
+				expectedRetry = "3000"
+			}
+			if event["retry"] != expectedRetry {
+				t.Errorf("expected retry '%s' on event %d, got '%s'", expectedRetry, i, event["retry"])
+			}
+			var greeting Response
+			if err := json.Unmarshal([]byte(event["data"]), &greeting); err != nil {
+				t.Fatalf("failed to decode data %q: %v", event["data"], err)
+			}
+			if greeting.Message != "¡Hola, Ana!" {
+				t.Errorf("expected message '¡Hola, Ana!', got '%s'", greeting.Message)
+			}
+		}
+	})
+
+	t.Run("heartbeats", func(t *testing.T) {
+		_, br := open(t, "/hello/stream", nil)
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil {
+				t.Fatalf("failed to read stream: %v", err)
+			}
+			if line == ": heartbeat\n" {
+				return
+			}
+		}
+	})
+
+	t.Run("resume from Last-Event-ID", func(t *testing.T) {
+		_, br := open(t, "/hello/stream", http.Header{"Last-Event-ID": {"41"}})
+		if event := readEvent(t, br); event["id"] != "42" {
+			t.Errorf("expected id 42, got '%s'", event["id"])
+		}
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		resp, _ := open(t, "/hello/stream?name=%3Cb%3E", nil)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, resp.StatusCode)
+		}
+	})
+
+	t.Run("shutdown ends the stream", func(t *testing.T) {
+		_, br := open(t, "/hello/stream", nil)
+		readEvent(t, br)
+
+		app.stop()
+		if _, err := io.ReadAll(br); err != nil {
+			t.Errorf("expected the stream to end cleanly, got %v", err)
+		}
+	})
+}
+
+func TestWebSocket(t *testing.T) {
+	app := testApp()
+	srv := httptest.NewServer(app.routes())
+	defer srv.Close()
+
+	dial := func(t *testing.T, header http.Header) *websockettest.Client {
+		t.Helper()
+		client, err := websockettest.Dial(srv.URL+"/ws", header)
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+		client.SetDeadline(time.Now().Add(5 * time.Second))
+		return client
+	}
+
+	t.Run("messages", func(t *testing.T) {
+		// Compression must not get in the way of the upgrade.
+		client := dial(t, http.Header{"Accept-Encoding": {"gzip"}})
+		if client.Response.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("expected status %d, got %d", http.StatusSwitchingProtocols, client.Response.StatusCode)
+		}
+		if client.Response.Header.Get("X-Request-ID") == "" {
+			t.Error("expected the handshake response to carry X-Request-ID")
+		}
+
+		tests := []struct {
+			name            string
+			op              websocket.Opcode
+			message         string
+			expectedMessage string
+			expectedCode    string
+		}{
+			{name: "greeting", op: websocket.OpText, message: `{"name":"Ana","lang":"es"}`, expectedMessage: "¡Hola, Ana!"},
+			{name: "default name", op: websocket.OpText, message: `{}`, expectedMessage: "Hello, World!"},
+			{name: "invalid JSON", op: websocket.OpText, message: `{"name":`, expectedCode: "INVALID_JSON"},
+			{name: "unknown field", op: websocket.OpText, message: `{"nickname":"Bob"}`, expectedCode: "INVALID_JSON"},
+			{name: "invalid name", op: websocket.OpText, message: `{"name":"<b>"}`, expectedCode: "VALIDATION_FAILED"},
+			{name: "binary echo", op: websocket.OpBinary, message: "\x00\x01\x02"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if err := client.WriteMessage(tt.op, []byte(tt.message)); err != nil {
+					t.Fatalf("write failed: %v", err)
+				}
+				op, payload, err := client.ReadFrame()
+				if err != nil {
+					t.Fatalf("read failed: %v", err)
+				}
+				if op != tt.op {
+					t.Fatalf("expected opcode %#x, got %#x", byte(tt.op), byte(op))
+				}
+
+				switch {
+				case tt.op == websocket.OpBinary:
+					if string(payload) != tt.message {
+						t.Errorf("expected echo %q, got %q", tt.message, payload)
+					}
+				case tt.expectedCode != "":
+					var resp ErrorResponse
+					if err := json.Unmarshal(payload, &resp); err != nil {
+						t.Fatalf("failed to decode error: %v", err)
+					}
+					if resp.Code != tt.expectedCode {
+						t.Errorf("expected code '%s', got '%s'", tt.expectedCode, resp.Code)
+					}
+					if resp.RequestID != client.Response.Header.Get("X-Request-ID") {
+						t.Errorf("expected request ID '%s', got '%s'", client.Response.Header.Get("X-Request-ID"), resp.RequestID)
+					}
+				default:
+					var resp Response
+					if err := json.Unmarshal(payload, &resp); err != nil {
+						t.Fatalf("failed to decode response: %v", err)
+					}
+					if resp.Message != tt.expectedMessage {
+						t.Errorf("expected message '%s', got '%s'", tt.expectedMessage, resp.Message)
+					}
+				}
+			})
+		}
+
+		client.WriteClose(websocket.CloseNormal, "")
+		if code, err := client.ReadClose(); err != nil || code != websocket.CloseNormal {
+			t.Errorf("expected close code %d, got %d (%v)", websocket.CloseNormal, code, err)
+		}
+	})
+
+	t.Run("plain GET", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		app.wsHandler(rec, httptest.NewRequest(http.MethodGet, "/ws", nil))
+		if rec.Code != http.StatusUpgradeRequired {
+			t.Errorf("expected status %d, got %d", http.StatusUpgradeRequired, rec.Code)
+		}
+		if got := rec.Header().Get("Upgrade"); got != "websocket" {
+			t.Errorf("expected Upgrade 'websocket', got '%s'", got)
+		}
+	})
+
+	t.Run("origins", func(t *testing.T) {
+		tests := []struct {
+			origin         string
+			expectedStatus int
+		}{
+			{origin: srv.URL, expectedStatus: http.StatusSwitchingProtocols},
+			{origin: "https://evil.example", expectedStatus: http.StatusForbidden},
+		}
+		for _, tt := range tests {
+			client := dial(t, http.Header{"Origin": {tt.origin}})
+			if client.Response.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %d for origin %s, got %d", tt.expectedStatus, tt.origin, client.Response.StatusCode)
+			}
+		}
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		client := dial(t, nil)
+		for app.websockets.Len() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		go func() {
+			if code, _ := client.ReadClose(); code == websocket.CloseGoingAway {
+				client.WriteClose(code, "")
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := app.websockets.Shutdown(ctx); err != nil {
+			t.Errorf("expected a clean shutdown, got %v", err)
+		}
+	})
+
+	// Handlers record their metrics just after the connection is released.
+	want := `http_requests_total{route="/ws",method="GET",code="101"}`
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		rec := httptest.NewRecorder()
+		app.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		if strings.Contains(rec.Body.String(), want) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected metrics output to contain %q", want)
+		}
+	}
+}
+
+func TestWebSocketAuthentication(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Enabled = true
+	cfg.Auth.APIKeys = []string{"ci-bot:" + auth.HashAPIKey("ci-secret")}
+	srv := httptest.NewServer(mustNewApp(t, cfg, logging.Discard()).routes())
+	defer srv.Close()
+
+	t.Run("missing credentials", func(t *testing.T) {
+		client, err := websockettest.Dial(srv.URL+"/ws", nil)
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer client.Close()
+		if client.Response.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, client.Response.StatusCode)
+		}
+		var resp ErrorResponse
+		if err := json.NewDecoder(client.Response.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Code != "UNAUTHORIZED" {
+			t.Errorf("expected code 'UNAUTHORIZED', got '%s'", resp.Code)
+		}
+	})
+
+	t.Run("API key", func(t *testing.T) {
+		client, err := websockettest.Dial(srv.URL+"/ws", http.Header{auth.APIKeyHeader: {"ci-secret"}})
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		defer client.Close()
+		if client.Response.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("expected status %d, got %d", http.StatusSwitchingProtocols, client.Response.StatusCode)
+		}
+		client.SetDeadline(time.Now().Add(5 * time.Second))
+		if err := client.WriteMessage(websocket.OpText, []byte(`{}`)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		_, payload, err := client.ReadFrame()
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		var resp Response
+		if err := json.Unmarshal(payload, &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Message != "Hello, ci-bot!" {
+			t.Errorf("expected message 'Hello, ci-bot!', got '%s'", resp.Message)
+		}
+	})
+}
+
+func TestGRPC(t *testing.T) {
+	dial := func(t *testing.T, app *app) *grpc.ClientConn {
+		t.Helper()
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen failed: %v", err)
+		}
+		server, _ := app.newGRPCServer()
+		go server.Serve(lis)
+		t.Cleanup(server.Stop)
+
+		conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatalf("dial failed: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	app := testApp()
+	conn := dial(t, app)
+	client := hellov1.NewHelloServiceClient(conn)
+
+	t.Run("hello", func(t *testing.T) {
+		tests := []struct {
+			name            string
+			req             *hellov1.HelloRequest
+			acceptLanguage  string
+			expectedMessage string
+			expectedLocale  string
+		}{
+			{name: "default name", req: &hellov1.HelloRequest{}, expectedMessage: "Hello, World!", expectedLocale: "en"},
+			{name: "lang field", req: &hellov1.HelloRequest{Name: "Ana", Lang: "es"}, expectedMessage: "¡Hola, Ana!", expectedLocale: "es"},
+			{name: "accept-language metadata", req: &hellov1.HelloRequest{}, acceptLanguage: "fr-CH, de;q=0.9", expectedMessage: "Salut, Monde !", expectedLocale: "fr"},
+			{name: "formality", req: &hellov1.HelloRequest{Name: "田中", Lang: "ja", Formality: "formal"}, expectedMessage: "田中様、こんにちは。", expectedLocale: "ja"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ctx := ctx
+				if tt.acceptLanguage != "" {
+					ctx = metadata.AppendToOutgoingContext(ctx, "accept-language", tt.acceptLanguage)
+				}
+				resp, err := client.Hello(ctx, tt.req)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if resp.GetMessage() != tt.expectedMessage {
+					t.Errorf("expected message '%s', got '%s'", tt.expectedMessage, resp.GetMessage())
+				}
+				if resp.GetLocale() != tt.expectedLocale {
+					t.Errorf("expected locale '%s', got '%s'", tt.expectedLocale, resp.GetLocale())
+				}
+			})
+		}
+	})
+
+	t.Run("validation error details", func(t *testing.T) {
+		_, err := client.Hello(ctx, &hellov1.HelloRequest{Name: "<b>"})
+		st := status.Convert(err)
+		if st.Code() != codes.InvalidArgument {
+			t.Fatalf("expected code %s, got %s", codes.InvalidArgument, st.Code())
+		}
+
+		var info *errdetails.ErrorInfo
+		var badRequest *errdetails.BadRequest
+		for _, d := range st.Details() {
+			switch d := d.(type) {
+			case *errdetails.ErrorInfo:
+				info = d
+			case *errdetails.BadRequest:
+				badRequest = d
+			}
+		}
+		if info == nil || info.GetReason() != "VALIDATION_FAILED" || info.GetDomain() != errorDomain {
+			t.Errorf("expected ErrorInfo reason 'VALIDATION_FAILED' in domain '%s', got %v", errorDomain, info)
+		}
+		if badRequest == nil || len(badRequest.GetFieldViolations()) == 0 {
+			t.Fatalf("expected BadRequest field violations, got %v", badRequest)
+		}
+		if field := badRequest.GetFieldViolations()[0].GetField(); field != "name" {
+			t.Errorf("expected field 'name', got '%s'", field)
+		}
+	})
+
+	t.Run("health ping and info", func(t *testing.T) {
+		health, err := client.Health(ctx, &hellov1.HealthRequest{})
+		if err != nil || health.GetStatus() != "healthy" {
+			t.Errorf("expected status 'healthy', got %v (%v)", health, err)
+		}
+		ping, err := client.Ping(ctx, &hellov1.PingRequest{})
+		if err != nil || ping.GetPong() != "pong" {
+			t.Errorf("expected pong 'pong', got %v (%v)", ping, err)
+		}
+
+		info, err := client.Info(metadata.AppendToOutgoingContext(ctx, "x-custom", "value", "x-api-key", "secret-key", "authorization", "Bearer secret-token"), &hellov1.InfoRequest{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if info.GetMethod() != hellov1.HelloService_Info_FullMethodName {
+			t.Errorf("expected method '%s', got '%s'", hellov1.HelloService_Info_FullMethodName, info.GetMethod())
+		}
+		if !strings.HasPrefix(info.GetRemoteAddr(), "127.0.0.1:") {
+			t.Errorf("expected a loopback remote address, got '%s'", info.GetRemoteAddr())
+		}
+		if !strings.HasPrefix(info.GetUserAgent(), "grpc-go/") {
+			t.Errorf("expected a grpc-go user agent, got '%s'", info.GetUserAgent())
+		}
+		if info.GetMetadata()["x-custom"] != "value" {
+			t.Errorf("expected metadata x-custom 'value', got '%s'", info.GetMetadata()["x-custom"])
+		}
+		for _, key := range []string{"x-api-key", "authorization"} {
+			if info.GetMetadata()[key] != "[REDACTED]" {
+				t.Errorf("expected metadata %s to be redacted, got '%s'", key, info.GetMetadata()[key])
+			}
+		}
+	})
+
+	t.Run("request ID", func(t *testing.T) {
+		var header metadata.MD
+		ctx := metadata.AppendToOutgoingContext(ctx, grpcserver.RequestIDKey, "client-abc")
+		if _, err := client.Ping(ctx, &hellov1.PingRequest{}, grpc.Header(&header)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := header.Get(grpcserver.RequestIDKey); len(got) != 1 || got[0] != "client-abc" {
+			t.Errorf("expected request ID 'client-abc', got %v", got)
+		}
+	})
+
+	t.Run("standard health service", func(t *testing.T) {
+		healthClient := healthpb.NewHealthClient(conn)
+		for _, service := range []string{"", hellov1.HelloService_ServiceDesc.ServiceName} {
+			resp, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+			if err != nil {
+				t.Fatalf("unexpected error for service '%s': %v", service, err)
+			}
+			if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+				t.Errorf("expected service '%s' SERVING, got %s", service, resp.GetStatus())
+			}
+		}
+	})
+
+	t.Run("reflection", func(t *testing.T) {
+		server, _ := app.newGRPCServer()
+		services := server.GetServiceInfo()
+		for _, name := range []string{
+			hellov1.HelloService_ServiceDesc.ServiceName,
+			"grpc.health.v1.Health",
+			"grpc.reflection.v1.ServerReflection",
+		} {
+			if _, ok := services[name]; !ok {
+				t.Errorf("expected service '%s' to be registered", name)
+			}
+		}
+	})
+
+	t.Run("authentication", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.Auth.Enabled = true
+		cfg.Auth.APIKeys = []string{"ci-bot:" + auth.HashAPIKey("ci-secret")}
+		client := hellov1.NewHelloServiceClient(dial(t, mustNewApp(t, cfg, logging.Discard())))
+
+		tests := []struct {
+			name         string
+			key          string
+			call         func(ctx context.Context) error
+			expectedCode codes.Code
+		}{
+			{
+				name:         "missing credentials",
+				call:         func(ctx context.Context) error { _, err := client.Hello(ctx, &hellov1.HelloRequest{}); return err },
+				expectedCode: codes.Unauthenticated,
+			},
+			{
+				name:         "unknown API key",
+				key:          "guess",
+				call:         func(ctx context.Context) error { _, err := client.Hello(ctx, &hellov1.HelloRequest{}); return err },
+				expectedCode: codes.Unauthenticated,
+			},
+			{
+				name:         "valid API key",
+				key:          "ci-secret",
+				call:         func(ctx context.Context) error { _, err := client.Hello(ctx, &hellov1.HelloRequest{}); return err },
+				expectedCode: codes.OK,
+			},
+			{
+				name:         "unprotected RPC",
+				call:         func(ctx context.Context) error { _, err := client.Ping(ctx, &hellov1.PingRequest{}); return err },
+				expectedCode: codes.OK,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ctx := ctx
+				if tt.key != "" {
+					ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(auth.APIKeyHeader), tt.key)
+				}
+				if code := status.Code(tt.call(ctx)); code != tt.expectedCode {
+					t.Errorf("expected code %s, got %s", tt.expectedCode, code)
+				}
+			})
+		}
+	})
+
+	t.Run("rate limiting", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.RateLimit.Rate = 0.001
+		cfg.RateLimit.Burst = 1
+		client := hellov1.NewHelloServiceClient(dial(t, mustNewApp(t, cfg, logging.Discard())))
+
+		if _, err := client.Hello(ctx, &hellov1.HelloRequest{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, err := client.Hello(ctx, &hellov1.HelloRequest{})
+		st := status.Convert(err)
+		if st.Code() != codes.ResourceExhausted {
+			t.Fatalf("expected code %s, got %s", codes.ResourceExhausted, st.Code())
+		}
+		var info *errdetails.ErrorInfo
+		if details := st.Details(); len(details) > 0 {
+			info, _ = details[0].(*errdetails.ErrorInfo)
+		}
+		if info.GetReason() != "RATE_LIMITED" {
+			t.Errorf("expected ErrorInfo reason 'RATE_LIMITED', got %v", st.Details())
+		}
+		if _, err := client.Ping(ctx, &hellov1.PingRequest{}); err != nil {
+			t.Errorf("expected unlimited RPC to succeed, got %v", err)
+		}
+	})
+}
+
+// TestShutdown checks that the HTTP and gRPC servers are stopped together:
+// an HTTP request that finishes soon after shutdown starts is drained even
+// while a stuck RPC holds the gRPC server for the whole timeout.
+func TestShutdown(t *testing.T) {
+	const timeout = 300 * time.Millisecond
+	entered := make(chan struct{}, 2)
+	finish := make(chan struct{})
+
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-finish
+	}))
+	defer httpSrv.Close()
+	httpSrv.Config.RegisterOnShutdown(func() {
+		time.AfterFunc(timeout/3, func() { close(finish) })
+	})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	grpcServer := grpc.NewServer(grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		entered <- struct{}{}
+		<-stream.Context().Done()
+		return nil
+	}))
+	go grpcServer.Serve(lis)
+	defer grpcServer.Stop()
+
+	go http.Get(httpSrv.URL)
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	go conn.Invoke(context.Background(), "/test.Stuck/Call", &hellov1.HelloRequest{}, &hellov1.HelloResponse{})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-entered:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the requests to start")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	if err := testApp().shutdown(ctx, httpSrv.Config, grpcServer); err != nil {
+		t.Errorf("expected the HTTP server to drain while gRPC was stopping, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 2*timeout {
+		t.Errorf("expected shutdown to finish within %s, took %s", 2*timeout, elapsed)
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	handler := testApp().routes()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("expected content type 'application/json', got '%s'", contentType)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), api.OpenAPI) {
+		t.Error("expected the embedded OpenAPI document")
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag header")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("expected status %d for a matching ETag, got %d", http.StatusNotModified, rec.Code)
+	}
+}
+
+// TestOpenAPISpec fails when the handlers and the published OpenAPI document
+// disagree: every documented path must allow exactly the documented methods,
+// and every response below must have a documented status, media type and
+// schema.
+func TestOpenAPISpec(t *testing.T) {
+	doc, err := openapi.Parse(api.OpenAPI)
+	if err != nil {
+		t.Fatalf("failed to parse the OpenAPI document: %v", err)
+	}
+
+	app := testApp()
+	// Stopped, so /hello/stream ends after its first event.
+	app.stop()
+	handler := app.routes()
+
+	restrictedCfg := config.Default()
+	restrictedCfg.Auth.Enabled = true
+	restrictedCfg.Auth.APIKeys = []string{"ci-bot:" + auth.HashAPIKey("ci-secret")}
+	restrictedCfg.RateLimit.Rate = 0.001
+	restrictedCfg.RateLimit.Burst = 1
+	restricted := mustNewApp(t, restrictedCfg, logging.Discard()).routes()
+
+	t.Run("allowed methods", func(t *testing.T) {
+		for path, item := range doc.Paths {
+			var want []string
+			for method := range item.Operations() {
+				want = append(want, method)
+				if method == http.MethodGet {
+					want = append(want, http.MethodHead)
+				}
+			}
+			want = append(want, http.MethodOptions)
+			slices.Sort(want)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, path, nil))
+			got := strings.Split(rec.Header().Get("Allow"), ", ")
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Errorf("%s: expected methods %v, got %v", path, want, got)
+			}
+		}
+	})
+
+	wsHandshake := map[string]string{
+		"Connection":            "Upgrade",
+		"Upgrade":               "websocket",
+		"Sec-WebSocket-Version": "13",
+		"Sec-WebSocket-Key":     "dGhlIHNhbXBsZSBub25jZQ==",
+	}
+
+	tests := []struct {
+		name           string
+		restricted     bool
+		method         string
+		url            string
+		headers        map[string]string
+		body           string
+		expectedStatus int
+	}{
+		{name: "hello", method: http.MethodGet, url: "/hello", expectedStatus: http.StatusOK},
+		{name: "hello with parameters", method: http.MethodGet, url: "/hello?name=Anna&name=Ben&lang=de&formality=formal", expectedStatus: http.StatusOK},
+		{name: "hello invalid name", method: http.MethodGet, url: "/hello?name=%3Cb%3E", expectedStatus: http.StatusBadRequest},
+		{
+			name:           "hello problem details",
+			method:         http.MethodGet,
+			url:            "/hello?name=%3Cb%3E",
+			headers:        map[string]string{"Accept": "application/problem+json"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{name: "hello not acceptable", method: http.MethodGet, url: "/hello", headers: map[string]string{"Accept": "image/png"}, expectedStatus: http.StatusNotAcceptable},
+		{
+			name:           "post hello JSON",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `{"name":"Ana","lang":"es"}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "post hello form",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			body:           "name=Ana",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "post hello invalid JSON",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `{"name":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "post hello invalid content type",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "text/plain"},
+			body:           "Ana",
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "post hello unsupported encoding",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "application/json", "Content-Encoding": "br"},
+			body:           `{}`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "post hello body too large",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `{"name":"` + strings.Repeat("a", 1<<20) + `"}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "batch JSON",
+			method:         http.MethodPost,
+			url:            "/hello/batch",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `[{"name":"Ana"},{"name":"<b>"},{"nickname":"Bob"}]`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "batch NDJSON",
+			method:         http.MethodPost,
+			url:            "/hello/batch",
+			headers:        map[string]string{"Content-Type": "application/x-ndjson"},
+			body:           "{\"name\":\"Ana\"}\n{\"name\":\n",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "empty batch",
+			method:         http.MethodPost,
+			url:            "/hello/batch",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `[]`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "batch invalid content type",
+			method:         http.MethodPost,
+			url:            "/hello/batch",
+			headers:        map[string]string{"Content-Type": "text/plain"},
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{name: "stream", method: http.MethodGet, url: "/hello/stream?name=Ana", expectedStatus: http.StatusOK},
+		{name: "stream invalid name", method: http.MethodGet, url: "/hello/stream?name=%3Cb%3E", expectedStatus: http.StatusBadRequest},
+		{name: "websocket without upgrade", method: http.MethodGet, url: "/ws", expectedStatus: http.StatusUpgradeRequired},
+		{
+			name:           "websocket foreign origin",
+			method:         http.MethodGet,
+			url:            "/ws",
+			headers:        map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Origin": "https://evil.example"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "websocket invalid handshake",
+			method:         http.MethodGet,
+			url:            "/ws",
+			headers:        map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "13"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "websocket unsupported version",
+			method:         http.MethodGet,
+			url:            "/ws",
+			headers:        map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "8"},
+			expectedStatus: http.StatusUpgradeRequired,
+		},
+		{
+			// httptest.ResponseRecorder cannot be hijacked.
+			name:           "websocket upgrade failure",
+			method:         http.MethodGet,
+			url:            "/ws",
+			headers:        wsHandshake,
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{name: "health", method: http.MethodGet, url: "/health", expectedStatus: http.StatusOK},
+		{name: "health not acceptable", method: http.MethodGet, url: "/health", headers: map[string]string{"Accept": "image/png"}, expectedStatus: http.StatusNotAcceptable},
+		{name: "liveness", method: http.MethodGet, url: "/livez", expectedStatus: http.StatusOK},
+		{name: "readiness", method: http.MethodGet, url: "/readyz", expectedStatus: http.StatusOK},
+		{name: "ping", method: http.MethodGet, url: "/ping", expectedStatus: http.StatusOK},
+		{name: "info", method: http.MethodGet, url: "/info?q=1", headers: map[string]string{"X-Custom": "value"}, expectedStatus: http.StatusOK},
+		{name: "metrics", method: http.MethodGet, url: "/metrics", expectedStatus: http.StatusOK},
+		{name: "openapi", method: http.MethodGet, url: "/openapi.json", expectedStatus: http.StatusOK},
+		{name: "unauthorized", restricted: true, method: http.MethodGet, url: "/info", expectedStatus: http.StatusUnauthorized},
+		{name: "rate limited", restricted: true, method: http.MethodGet, url: "/info", expectedStatus: http.StatusTooManyRequests},
+		{name: "method not allowed", method: http.MethodDelete, url: "/hello", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	covered := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			if tt.restricted {
+				restricted.ServeHTTP(rec, req)
+			} else {
+				handler.ServeHTTP(rec, req)
+			}
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+
+			contentType := rec.Header().Get("Content-Type")
+			var schema *openapi.Schema
+			if op := doc.Operation(req.URL.Path, tt.method); op != nil {
+				covered[tt.method+" "+req.URL.Path] = true
+				resp := op.Response(rec.Code)
+				if resp == nil {
+					t.Fatalf("status %d is not documented for %s %s", rec.Code, tt.method, req.URL.Path)
+				}
+				mt, ok := resp.Content.Lookup(contentType)
+				if !ok {
+					t.Fatalf("content type '%s' is not documented for status %d", contentType, rec.Code)
+				}
+				schema = mt.Schema
+				if strings.HasPrefix(contentType, sse.ContentType) {
+					// Event data is documented as a Response object.
+					schema = doc.Components.Schemas["Response"]
+				}
+			} else {
+				// Undocumented methods share the documented error format.
+				schema = doc.Components.Schemas["ErrorResponse"]
+			}
+
+			for i, value := range decodeDocumented(t, contentType, rec.Body.Bytes()) {
+				if errs := schema.Validate("", value); len(errs) > 0 {
+					t.Errorf("value %d does not match the documented schema: %+v", i, errs)
+				}
+			}
+		})
+	}
+
+	for path, item := range doc.Paths {
+		for method := range item.Operations() {
+			if !covered[method+" "+path] {
+				t.Errorf("%s %s is documented but not exercised by this test", method, path)
+			}
+		}
+	}
+}
+
+// decodeDocumented decodes the JSON values in a response body of the given
+// content type: the body itself for JSON, each line for NDJSON and each data
+// field for Server-Sent Events. Other media types have no values to check.
+func decodeDocumented(t *testing.T, contentType string, body []byte) []interface{} {
+	t.Helper()
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	var raw [][]byte
+	switch mediaType {
+	case "application/json", "application/problem+json":
+		raw = [][]byte{body}
+	case "application/x-ndjson":
+		raw = bytes.Split(bytes.TrimSpace(body), []byte("\n"))
+	case "text/event-stream":
+		for _, line := range bytes.Split(body, []byte("\n")) {
+			if data, ok := bytes.CutPrefix(line, []byte("data: ")); ok {
+				raw = append(raw, data)
+			}
+		}
+	}
+
+	values := make([]interface{}, len(raw))
+	for i, b := range raw {
+		if err := json.Unmarshal(b, &values[i]); err != nil {
+			t.Fatalf("failed to decode %s value: %v", mediaType, err)
+		}
+	}
+	return values
+}
+
+func TestRequestValidation(t *testing.T) {
+	compressed := func(data string) string {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write([]byte(data))
+		zw.Close()
+		return buf.String()
+	}
+
+	tests := []struct {
+		name            string
+		method          string
+		url             string
+		headers         map[string]string
+		body            string
+		expectedStatus  int
+		expectedError   string
+		expectedCode    string
+		expectedDetails []validation.FieldError
+	}{
+		{
+			name:           "formality matched like every transport",
+			method:         http.MethodGet,
+			url:            "/hello?name=Ana&formality=Formal",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown formality greets informally",
+			method:         http.MethodHead,
+			url:            "/hello?formality=casual",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "undocumented query parameters ignored",
+			method:         http.MethodGet,
+			url:            "/info?anything=goes",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "every field reported",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `{"name":1,"formality":"casual","nickname":"Bob"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Invalid request",
+			expectedCode:   "VALIDATION_FAILED",
+			expectedDetails: []validation.FieldError{
+				{Field: "name", Code: "invalid_type", Message: "must be a string"},
+				{Field: "nickname", Code: "unknown_field", Message: "is not allowed"},
+			},
+		},
+		{
+			name:           "body of the wrong type",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `["Ana"]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Invalid request",
+			expectedCode:   "VALIDATION_FAILED",
+			expectedDetails: []validation.FieldError{
+				{Field: "body", Code: "invalid_type", Message: "must be an object"},
+			},
+		},
+		{
+			name:           "malformed JSON",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `{"name":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Invalid JSON",
+			expectedCode:   "INVALID_JSON",
+			expectedDetails: []validation.FieldError{
+				{Field: "body", Code: "invalid_syntax", Message: "unexpected end of JSON input at offset 8"},
+			},
+		},
+		{
+			name:           "undocumented content type",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "text/plain"},
+			body:           "Ana",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedError:  "Content-Type must be application/json, application/x-www-form-urlencoded or multipart/form-data",
+			expectedCode:   "INVALID_CONTENT_TYPE",
+		},
+		{
+			name:           "form bodies left to the handler",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			body:           "name=Ana",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "compressed body validated after decoding",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "application/json", "Content-Encoding": "gzip"},
+			body:           compressed(`{"nickname":"Bob"}`),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Invalid request",
+			expectedCode:   "VALIDATION_FAILED",
+			expectedDetails: []validation.FieldError{
+				{Field: "nickname", Code: "unknown_field", Message: "is not allowed"},
+			},
+		},
+		{
+			name:           "compressed body passed on",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "application/json", "Content-Encoding": "gzip"},
+			body:           compressed(`{"name":"Ana"}`),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "body too large",
+			method:         http.MethodPost,
+			url:            "/hello",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `{"name":"` + strings.Repeat("a", 1<<20) + `"}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   "BODY_TOO_LARGE",
+		},
+		{
+			name:           "batch that is not an array",
+			method:         http.MethodPost,
+			url:            "/hello/batch",
+			headers:        map[string]string{"Content-Type": "application/json"},
+			body:           `{"name":"Ana"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Invalid request",
+			expectedCode:   "VALIDATION_FAILED",
+			expectedDetails: []validation.FieldError{
+				{Field: "body", Code: "invalid_type", Message: "must be an array"},
+			},
+		},
+		{
+			name:           "batch NDJSON alias",
+			method:         http.MethodPost,
+			url:            "/hello/batch",
+			headers:        map[string]string{"Content-Type": "application/ndjson"},
+			body:           "{\"name\":\"Ana\"}\n",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	handler := testApp().routes()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, rec.Code, rec.Body.String())
+			}
+			if tt.expectedCode == "" {
+				return
+			}
+
+			var resp ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Code != tt.expectedCode {
+				t.Errorf("expected code '%s', got '%s'", tt.expectedCode, resp.Code)
+			}
+			if tt.expectedError != "" && resp.Error != tt.expectedError {
+				t.Errorf("expected error '%s', got '%s'", tt.expectedError, resp.Error)
+			}
+			if resp.RequestID == "" {
+				t.Error("expected a request ID")
+			}
+			if !reflect.DeepEqual(resp.Details, tt.expectedDetails) {
+				t.Errorf("expected details %+v, got %+v", tt.expectedDetails, resp.Details)
+			}
+		})
+	}
+
+	t.Run("problem details", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader(`{"nickname":"Bob"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/problem+json")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		var problem Problem
+		if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if problem.Code != "VALIDATION_FAILED" || len(problem.Errors) != 1 || problem.Errors[0].Field != "nickname" {
+			t.Errorf("expected a VALIDATION_FAILED problem for nickname, got %+v", problem)
+		}
+	})
+}
+
+// TestClient exercises pkg/client against the real routes, so the SDK's
+// types and error mapping stay in step with the server.
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(testApp().routes())
+	defer srv.Close()
+
+	c, err := client.New(srv.URL, client.WithRetries(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := client.WithRequestID(context.Background(), "client-test")
+
+	hello, err := c.Hello(ctx, "Ada")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hello.Message != "Hello, Ada!" {
+		t.Errorf("expected message 'Hello, Ada!', got '%s'", hello.Message)
+	}
+
+	hello, err = c.HelloPOST(ctx, client.Request{Name: "Ada", Lang: "fr"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hello.Locale != "fr" {
+		t.Errorf("expected locale 'fr', got '%s'", hello.Locale)
+	}
+
+	if health, err := c.Health(ctx); err != nil || health.Status != "healthy" {
+		t.Errorf("expected status 'healthy', got %+v, %v", health, err)
+	}
+	if ping, err := c.Ping(ctx); err != nil || ping.Pong != "pong" {
+		t.Errorf("expected pong 'pong', got %+v, %v", ping, err)
+	}
+
+	info, err := c.Info(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Method != http.MethodGet || info.Headers["X-Request-Id"] != "client-test" {
+		t.Errorf("expected a GET with request ID 'client-test', got %+v", info)
+	}
+
+	_, err = c.Hello(ctx, "<b>")
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, client.ErrValidation) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if apiErr.RequestID != "client-test" || len(apiErr.Details) != 1 || apiErr.Details[0].Field != "name" {
+		t.Errorf("expected request ID and details for name, got %+v", apiErr)
+	}
+
+	problemCfg := config.Default()
+	problemCfg.Errors.Format = "problem"
+	problemCfg.Auth.Enabled = true
+	problemCfg.Auth.APIKeys = []string{"ci-bot:" + auth.HashAPIKey("ci-secret")}
+	problemSrv := httptest.NewServer(mustNewApp(t, problemCfg, logging.Discard()).routes())
+	defer problemSrv.Close()
+
+	c, err = client.New(problemSrv.URL, client.WithRetries(0), client.WithAPIKey("guess"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = c.Hello(ctx, "Ada")
+	if !errors.As(err, &apiErr) || !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("expected an unauthorized error, got %v", err)
+	}
+	if apiErr.Code != "INVALID_API_KEY" || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 INVALID_API_KEY, got %+v", apiErr)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	rec := httptest.NewRecorder()
+
+	testApp().healthHandler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	contentType := rec.Header().Get("Content-Type")
+	if contentType != "application/json" {
+		t.Errorf("expected Content-Type 'application/json', got '%s'", contentType)
+	}
+
+	var resp HealthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Status != "healthy" {
+		t.Errorf("expected status 'healthy', got '%s'", resp.Status)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	app := testApp()
+	handler := app.routes()
+
+	probe := func(path string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	if code := probe("/livez"); code != http.StatusOK {
+		t.Errorf("expected /livez %d, got %d", http.StatusOK, code)
+	}
+	if code := probe("/readyz"); code != http.StatusOK {
+		t.Errorf("expected /readyz %d, got %d", http.StatusOK, code)
+	}
+
+	app.shutdownGate.Drain()
+
+	if code := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected /readyz %d while draining, got %d", http.StatusServiceUnavailable, code)
+	}
+	if code := probe("/livez"); code != http.StatusOK {
+		t.Errorf("expected /livez to stay %d while draining, got %d", http.StatusOK, code)
+	}
+}
+
+func TestRateLimiting(t *testing.T) {
+	cfg := config.Default()
+	cfg.RateLimit.Rate = 0.001
+	cfg.RateLimit.Burst = 2
+	handler := mustNewApp(t, cfg, logging.Discard()).routes()
+
+	request := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := request("/hello", "203.0.113.7:1234"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusOK, rec.Code)
+		}
+	}
+
+	rec := request("/hello", "203.0.113.7:1234")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != "RATE_LIMITED" || resp.RequestID == "" {
+		t.Errorf("expected RATE_LIMITED error with request ID, got %+v", resp)
+	}
+
+	if rec := request("/hello", "198.51.100.1:1234"); rec.Code != http.StatusOK {
+		t.Errorf("expected other clients to be unaffected, got %d", rec.Code)
+	}
+	if rec := request("/info", "203.0.113.7:1234"); rec.Code != http.StatusOK {
+		t.Errorf("expected routes to have separate limits, got %d", rec.Code)
+	}
+	if rec := request("/ping", "203.0.113.7:1234"); rec.Header().Get("RateLimit-Limit") != "" {
+		t.Error("expected /ping not to be rate limited")
+	}
+}
+
+func TestRateLimitingByAPIKey(t *testing.T) {
+	cfg := config.Default()
+	cfg.RateLimit.Rate = 0.001
+	cfg.RateLimit.Burst = 2
+	cfg.RateLimit.Key = "api_key"
+	cfg.Auth.Enabled = true
+	cfg.Auth.APIKeys = []string{"ci-bot:" + auth.HashAPIKey("ci-secret")}
+	handler := mustNewApp(t, cfg, logging.Discard()).routes()
+
+	request := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		req.Header.Set(auth.APIKeyHeader, key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Made-up keys share the client's IP bucket instead of each getting
+	// their own.
+	for i := 0; i < 2; i++ {
+		if code := request("guess-" + strconv.Itoa(i)); code != http.StatusUnauthorized {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusUnauthorized, code)
+		}
+	}
+	if code := request("guess-2"); code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d for another made-up key, got %d", http.StatusTooManyRequests, code)
+	}
+
+	if code := request("ci-secret"); code != http.StatusOK {
+		t.Errorf("expected a valid key to have its own bucket, got %d", code)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.Enabled = true
+	cfg.Auth.APIKeys = []string{"ci-bot:" + auth.HashAPIKey("ci-secret")}
+	cfg.Auth.RequiredScope = "hello"
+	cfg.Auth.JWT.HMACSecret = "jwt-secret"
+	cfg.Auth.JWT.Audience = "hello-api"
+	handler := mustNewApp(t, cfg, logging.Discard()).routes()
+
+	token := func(claims string) string {
+		input := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+			base64.RawURLEncoding.EncodeToString([]byte(claims))
+		mac := hmac.New(sha256.New, []byte("jwt-secret"))
+		mac.Write([]byte(input))
+		return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name            string
+		path            string
+		header          string
+		value           string
+		expectedStatus  int
+		expectedCode    string
+		expectedMessage string
+	}{
+		{
+			name:           "missing credentials",
+			path:           "/hello",
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "UNAUTHORIZED",
+		},
+		{
+			name:           "stream without credentials",
+			path:           "/hello/stream",
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "UNAUTHORIZED",
+		},
+		{
+			name:           "unknown API key",
+			path:           "/hello",
+			header:         auth.APIKeyHeader,
+			value:          "guess",
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "INVALID_API_KEY",
+		},
+		{
+			name:            "API key greets its name",
+			path:            "/hello",
+			header:          auth.APIKeyHeader,
+			value:           "ci-secret",
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hello, ci-bot!",
+		},
+		{
+			name:            "explicit name wins over principal",
+			path:            "/hello?name=Alice",
+			header:          auth.APIKeyHeader,
+			value:           "ci-secret",
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hello, Alice!",
+		},
+		{
+			name:            "bearer token greets its name claim",
+			path:            "/hello",
+			header:          "Authorization",
+			value:           "Bearer " + token(`{"sub":"u1","name":"Ada","aud":"hello-api","exp":4102444800,"scope":"hello"}`),
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hello, Ada!",
+		},
+		{
+			name:            "name claim is validated like names",
+			path:            "/hello",
+			header:          "Authorization",
+			value:           "Bearer " + token(`{"sub":"u1","name":"<b>Ada</b>","aud":"hello-api","exp":4102444800,"scope":"hello"}`),
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hello, World!",
+		},
+		{
+			name:            "name claim is sanitized like names",
+			path:            "/hello",
+			header:          "Authorization",
+			value:           "Bearer " + token(`{"sub":"u1","name":" Ada\u0007 ","aud":"hello-api","exp":4102444800,"scope":"hello"}`),
+			expectedStatus:  http.StatusOK,
+			expectedMessage: "Hello, Ada!",
+		},
+		{
+			name:           "expired bearer token",
+			path:           "/hello",
+			header:         "Authorization",
+			value:          "Bearer " + token(`{"sub":"u1","aud":"hello-api","exp":946684800,"scope":"hello"}`),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   "INVALID_TOKEN",
+		},
+		{
+			name:           "bearer token without scope",
+			path:           "/info",
+			header:         "Authorization",
+			value:          "Bearer " + token(`{"sub":"u1","aud":"hello-api","exp":4102444800}`),
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   "INSUFFICIENT_SCOPE",
+		},
+		{
+			name:           "unprotected route",
+			path:           "/ping",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+
+			if tt.expectedCode != "" {
+				var resp ErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Code != tt.expectedCode || resp.RequestID == "" {
+					t.Errorf("expected %s error with request ID, got %+v", tt.expectedCode, resp)
+				}
+				if rec.Header().Get("WWW-Authenticate") == "" {
+					t.Error("expected WWW-Authenticate header")
+				}
+			}
+
+			if tt.expectedMessage != "" {
+				var resp Response
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Message != tt.expectedMessage {
+					t.Errorf("expected message '%s', got '%s'", tt.expectedMessage, resp.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestNewAppCredentialErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	tests := []struct {
+		name        string
+		configure   func(*config.AuthConfig)
+		expectedErr string
+	}{
+		{
+			name:        "API key file",
+			configure:   func(c *config.AuthConfig) { c.APIKeysFile = missing },
+			expectedErr: "auth.api_keys_file",
+		},
+		{
+			name:        "invalid API key entry",
+			configure:   func(c *config.AuthConfig) { c.APIKeys = []string{"no-hash"} },
+			expectedErr: "auth.api_keys",
+		},
+		{
+			name:        "RSA public key file",
+			configure:   func(c *config.AuthConfig) { c.JWT.RSAPublicKeyFile = missing },
+			expectedErr: "auth.jwt.rsa_public_key_file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Auth.Enabled = true
+			tt.configure(&cfg.Auth)
+
+			_, err := newApp(cfg, logging.Discard())
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("expected error containing '%s', got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	cfg := config.Default()
+	cfg.CORS.Enabled = true
+	cfg.CORS.AllowedOrigins = []string{"https://*.example.com"}
+	cfg.Auth.Enabled = true
+	cfg.Auth.APIKeys = []string{"ci:" + auth.HashAPIKey("secret")}
+	handler := mustNewApp(t, cfg, logging.Discard()).routes()
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/hello", nil)
+	preflight.Header.Set("Origin", "https://app.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "POST")
+	preflight.Header.Set("Access-Control-Request-Headers", "Content-Type, X-API-Key")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, preflight)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected preflight to be answered with %d before authentication, got %d", http.StatusNoContent, rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("expected allowed origin 'https://app.example.com', got '%s'", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET, HEAD, POST" {
+		t.Errorf("expected allowed methods 'GET, HEAD, POST', got '%s'", got)
+	}
+	if rec.Body.Len() != 0 {
+		t.Errorf("expected empty preflight body, got '%s'", rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("expected error responses to carry CORS headers, got '%s'", got)
+	}
+	if !strings.Contains(rec.Header().Get("Access-Control-Expose-Headers"), "X-Request-Id") {
+		t.Errorf("expected X-Request-Id to be exposed, got '%s'", rec.Header().Get("Access-Control-Expose-Headers"))
+	}
+}
+
+func TestMethodEnforcement(t *testing.T) {
+	handler := testApp().routes()
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedAllow  string
+		expectBody     bool
+	}{
+		{name: "GET hello", method: http.MethodGet, path: "/hello", expectedStatus: http.StatusOK, expectBody: true},
+		{name: "PUT hello", method: http.MethodPut, path: "/hello", expectedStatus: http.StatusMethodNotAllowed, expectedAllow: "GET, POST, HEAD, OPTIONS", expectBody: true},
+		{name: "DELETE hello", method: http.MethodDelete, path: "/hello", expectedStatus: http.StatusMethodNotAllowed, expectedAllow: "GET, POST, HEAD, OPTIONS", expectBody: true},
+		{name: "PATCH hello", method: http.MethodPatch, path: "/hello", expectedStatus: http.StatusMethodNotAllowed, expectedAllow: "GET, POST, HEAD, OPTIONS", expectBody: true},
+		{name: "HEAD hello", method: http.MethodHead, path: "/hello", expectedStatus: http.StatusOK},
+		{name: "OPTIONS hello", method: http.MethodOptions, path: "/hello", expectedStatus: http.StatusNoContent, expectedAllow: "GET, POST, HEAD, OPTIONS"},
+		{name: "POST ping", method: http.MethodPost, path: "/ping", expectedStatus: http.StatusMethodNotAllowed, expectedAllow: "GET, HEAD, OPTIONS", expectBody: true},
+		{name: "POST health", method: http.MethodPost, path: "/health", expectedStatus: http.StatusMethodNotAllowed, expectedAllow: "GET, HEAD, OPTIONS", expectBody: true},
+		{name: "DELETE info", method: http.MethodDelete, path: "/info", expectedStatus: http.StatusMethodNotAllowed, expectedAllow: "GET, HEAD, OPTIONS", expectBody: true},
+		{name: "HEAD readyz", method: http.MethodHead, path: "/readyz", expectedStatus: http.StatusOK},
+		{name: "OPTIONS metrics", method: http.MethodOptions, path: "/metrics", expectedStatus: http.StatusNoContent, expectedAllow: "GET, HEAD, OPTIONS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.expectedAllow {
+				t.Errorf("expected Allow '%s', got '%s'", tt.expectedAllow, got)
+			}
+			if (rec.Body.Len() > 0) != tt.expectBody {
+				t.Errorf("expected body present %v, got '%s'", tt.expectBody, rec.Body.String())
+			}
+
+			if tt.expectedStatus == http.StatusMethodNotAllowed {
+				var resp ErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Code != "METHOD_NOT_ALLOWED" {
+					t.Errorf("expected code 'METHOD_NOT_ALLOWED', got '%s'", resp.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	handler := testApp().routes()
+
+	tests := []struct {
+		name                string
+		path                string
+		accept              string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "JSON by default",
+			path:                "/ping",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/json",
+			expectedBody:        "{\"pong\":\"pong\"}\n",
+		},
+		{
+			name:                "XML",
+			path:                "/hello?name=Ada",
+			accept:              "application/xml",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/xml; charset=utf-8",
+			expectedBody:        "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<Response>\n  <message>Hello, Ada!</message>\n  <locale>en</locale>\n</Response>\n",
+		},
+		{
+			name:                "YAML",
+			path:                "/health",
+			accept:              "application/yaml",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/yaml",
+			expectedBody:        "status: healthy\n",
+		},
+		{
+			name:                "MessagePack",
+			path:                "/ping",
+			accept:              "application/msgpack",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/msgpack",
+			expectedBody:        "\x81\xa4pong\xa4pong",
+		},
+		{
+			name:                "plain text",
+			path:                "/hello",
+			accept:              "text/plain",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "message: Hello, World!\nlocale: en\n",
+		},
+		{
+			name:                "error in negotiated format",
+			path:                "/hello?name=%3Cb%3E",
+			accept:              "application/yaml",
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: "application/yaml",
+		},
+		{
+			name:                "not acceptable",
+			path:                "/info",
+			accept:              "image/png",
+			expectedStatus:      http.StatusNotAcceptable,
+			expectedContentType: "application/json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.expectedContentType {
+				t.Errorf("expected Content-Type '%s', got '%s'", tt.expectedContentType, got)
+			}
+			if !slices.Contains(rec.Header().Values("Vary"), "Accept") {
+				t.Errorf("expected Vary to include Accept, got %v", rec.Header().Values("Vary"))
+			}
+			if tt.expectedBody != "" && rec.Body.String() != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, rec.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/info", nil)
+	req.Header.Set("Accept", "image/png")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != "NOT_ACCEPTABLE" {
+		t.Errorf("expected code 'NOT_ACCEPTABLE', got '%s'", resp.Code)
+	}
+}
+
+func TestProblemDetails(t *testing.T) {
+	problemCfg := config.Default()
+	problemCfg.Errors.Format = "problem"
+
+	tests := []struct {
+		name                string
+		cfg                 *config.Config
+		method              string
+		path                string
+		accept              string
+		expectedContentType string
+		expected            Problem
+	}{
+		{
+			name:                "requested with Accept",
+			cfg:                 config.Default(),
+			method:              http.MethodGet,
+			path:                "/hello?name=%3Cb%3E",
+			accept:              "application/json, application/problem+json",
+			expectedContentType: "application/problem+json",
+			expected: Problem{
+				Type:     "urn:hello-api:problem:validation-failed",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "Invalid name",
+				Instance: "/hello",
+				Code:     "VALIDATION_FAILED",
+				Errors:   []validation.FieldError{{Field: "name", Code: "invalid_characters", Message: "contains disallowed character '<'"}},
+			},
+		},
+		{
+			name:                "configured globally",
+			cfg:                 problemCfg,
+			method:              http.MethodDelete,
+			path:                "/ping",
+			expectedContentType: "application/problem+json",
+			expected: Problem{
+				Type:     "urn:hello-api:problem:method-not-allowed",
+				Title:    "Method Not Allowed",
+				Status:   http.StatusMethodNotAllowed,
+				Detail:   "Method not allowed",
+				Instance: "/ping",
+				Code:     "METHOD_NOT_ALLOWED",
+			},
+		},
+		{
+			name:                "not acceptable",
+			cfg:                 config.Default(),
+			method:              http.MethodGet,
+			path:                "/info",
+			accept:              "application/problem+json",
+			expectedContentType: "application/problem+json",
+			expected: Problem{
+				Type:     "urn:hello-api:problem:not-acceptable",
+				Title:    "Not Acceptable",
+				Status:   http.StatusNotAcceptable,
+				Detail:   "Not acceptable",
+				Instance: "/info",
+				Code:     "NOT_ACCEPTABLE",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			mustNewApp(t, tt.cfg, logging.Discard()).routes().ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Type"); got != tt.expectedContentType {
+				t.Errorf("expected Content-Type '%s', got '%s'", tt.expectedContentType, got)
+			}
+
+			var got Problem
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got.RequestID == "" {
+				t.Error("expected a request_id extension member")
+			}
+			got.RequestID = ""
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("legacy shape by default", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/ping", nil)
+		req.Header.Set("Accept", "*/*")
+		rec := httptest.NewRecorder()
+		testApp().routes().ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Content-Type"); got != "application/json" {
+			t.Errorf("expected Content-Type 'application/json', got '%s'", got)
+		}
+		if !strings.Contains(rec.Body.String(), `"error":"Method not allowed"`) {
+			t.Errorf("expected legacy error body, got '%s'", rec.Body.String())
+		}
+	})
+
+	t.Run("configured globally in another format", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/hello?name=%3Cb%3E", nil)
+		req.Header.Set("Accept", "application/yaml")
+		rec := httptest.NewRecorder()
+		mustNewApp(t, problemCfg, logging.Discard()).routes().ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Content-Type"); got != "application/yaml" {
+			t.Errorf("expected Content-Type 'application/yaml', got '%s'", got)
+		}
+		if !strings.Contains(rec.Body.String(), "type: urn:hello-api:problem:validation-failed\n") {
+			t.Errorf("expected YAML problem details, got '%s'", rec.Body.String())
+		}
+	})
+}
+
+func TestCompression(t *testing.T) {
+	var logs bytes.Buffer
+	cfg := config.Default()
+	handler := mustNewApp(t, cfg, logging.New(&logs, cfg.Log)).routes()
+
+	req := httptest.NewRequest(http.MethodGet, "/info", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("X-Forwarded-For", strings.Repeat("203.0.113.7, ", 100))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected Content-Encoding 'gzip', got '%s'", got)
+	}
+	if !slices.Contains(rec.Header().Values("Vary"), "Accept-Encoding") {
+		t.Errorf("expected Vary to include Accept-Encoding, got %v", rec.Header().Values("Vary"))
+	}
+
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("failed to open gzip body: %v", err)
+	}
+	var resp InfoResponse
+	if err := json.NewDecoder(zr).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Method != http.MethodGet {
+		t.Errorf("expected method 'GET', got '%s'", resp.Method)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse log entry: %v", err)
+	}
+	if entry["status"] != float64(http.StatusOK) {
+		t.Errorf("expected logged status 200, got %v", entry["status"])
+	}
+	if n := entry["bytes"].(float64); n <= 0 || n >= 1024 {
+		t.Errorf("expected logged bytes to be the compressed size, got %v", n)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("expected small responses to be uncompressed, got Content-Encoding '%s'", got)
+	}
+}
+
+func TestPingHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		expectedStatus int
+		expectedPong   string
+	}{
+		{
+			name:           "GET ping returns pong",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedPong:   "pong",
+		},
+		{
+			name:           "POST ping returns pong",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusOK,
+			expectedPong:   "pong",
+		},
+		{
+			name:           "PUT ping returns pong",
+			method:         http.MethodPut,
+			expectedStatus: http.StatusOK,
+			expectedPong:   "pong",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/ping", nil)
+			rec := httptest.NewRecorder()
+
+			testApp().pingHandler(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+
+			contentType := rec.Header().Get("Content-Type")
+			if contentType != "application/json" {
+				t.Errorf("expected Content-Type 'application/json', got '%s'", contentType)
+			}
+
+			var resp PingResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if resp.Pong != tt.expectedPong {
+				t.Errorf("expected pong '%s', got '%s'", tt.expectedPong, resp.Pong)
+			}
+		})
+	}
+}
+
+func TestInfoHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		headers        map[string]string
+		expectedStatus int
+		validate       func(t *testing.T, resp InfoResponse)
+	}{
+		{
+			name:           "GET info with basic request",
+			method:         http.MethodGet,
+			url:            "/info",
+			expectedStatus: http.StatusOK,
+			validate: func(t *testing.T, resp InfoResponse) {
+				if resp.Method != http.MethodGet {
+					t.Errorf("expected method %s, got %s", http.MethodGet, resp.Method)
+				}
+				if resp.URL != "/info" {
+					t.Errorf("expected URL /info, got %s", resp.URL)
+				}
+			},
+		},
+		{
+			name:           "GET info with query parameters",
+			method:         http.MethodGet,
+			url:            "/info?foo=bar&test=123",
+			expectedStatus: http.StatusOK,
+			validate: func(t *testing.T, resp InfoResponse) {
+				if resp.QueryParams["foo"] != "bar" {
+					t.Errorf("expected query param foo=bar, got foo=%s", resp.QueryParams["foo"])
+				}
+				if resp.QueryParams["test"] != "123" {
+					t.Errorf("expected query param test=123, got test=%s", resp.QueryParams["test"])
+				}
+			},
+		},
+		{
+			name:   "POST info with custom headers",
+			method: http.MethodPost,
+			url:    "/info",
+			headers: map[string]string{
+				"X-Custom-Header": "custom-value",
+				"User-Agent":      "test-agent",
+			},
+			expectedStatus: http.StatusOK,
+			validate: func(t *testing.T, resp InfoResponse) {
+				if resp.Method != http.MethodPost {
+					t.Errorf("expected method %s, got %s", http.MethodPost, resp.Method)
+				}
+				if resp.Headers["X-Custom-Header"] != "custom-value" {
+					t.Errorf("expected header X-Custom-Header=custom-value, got %s", resp.Headers["X-Custom-Header"])
+				}
+				if resp.UserAgent != "test-agent" {
+					t.Errorf("expected user agent test-agent, got %s", resp.UserAgent)
+				}
+			},
+		},
+		{
+			name:   "GET info redacts credentials",
+			method: http.MethodGet,
+			url:    "/info",
+			headers: map[string]string{
+				"Authorization": "Bearer secret-token",
+				"X-API-Key":     "secret-key",
+				"Cookie":        "session=secret",
+			},
+			expectedStatus: http.StatusOK,
+			validate: func(t *testing.T, resp InfoResponse) {
+				for _, key := range []string{"Authorization", "X-Api-Key", "Cookie"} {
+					if resp.Headers[key] != "[REDACTED]" {
+						t.Errorf("expected header %s to be redacted, got %s", key, resp.Headers[key])
+					}
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+
+			// Add custom headers if provided
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			// Set a default host if not provided
+			if req.Host == "" {
+				req.Host = "example.com"
+			}
+
+			// Set RemoteAddr for testing
+			req.RemoteAddr = "192.168.1.1:12345"
+
+			rec := httptest.NewRecorder()
+
+			testApp().infoHandler(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+
+			contentType := rec.Header().Get("Content-Type")
+			if contentType != "application/json" {
+				t.Errorf("expected Content-Type 'application/json', got '%s'", contentType)
+			}
+
+			var resp InfoResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			// Basic validations that apply to all tests
+			if resp.Host != req.Host {
+				t.Errorf("expected host %s, got %s", req.Host, resp.Host)
+			}
+			if resp.RemoteAddr != req.RemoteAddr {
+				t.Errorf("expected remote addr %s, got %s", req.RemoteAddr, resp.RemoteAddr)
+			}
+
+			// Run test-specific validations
+			if tt.validate != nil {
+				tt.validate(t, resp)
 			}
 		})
 	}
@@ -439,7 +3025,7 @@ func TestInfoHandler(t *testing.T) {
 
 func TestLoggingMiddleware(t *testing.T) {
 	var logBuffer bytes.Buffer
-	logger := log.New(&logBuffer, "[test] ", log.LstdFlags)
+	logger := logging.New(&logBuffer, config.LogConfig{Level: "info", Format: "json"})
 
 	handler := loggingMiddleware(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -447,6 +3033,7 @@ func TestLoggingMiddleware(t *testing.T) {
 	}))
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
 	rec := httptest.NewRecorder()
 
 	handler.ServeHTTP(rec, req)
@@ -455,18 +3042,142 @@ func TestLoggingMiddleware(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
 	}
 
-	logOutput := logBuffer.String()
-	if !strings.Contains(logOutput, "INFO:") {
-		t.Error("expected log output to contain 'INFO:'")
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logBuffer.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a single JSON log line, got %q: %v", logBuffer.String(), err)
+	}
+
+	expected := map[string]interface{}{
+		"level":       "INFO",
+		"msg":         "request completed",
+		"method":      "GET",
+		"path":        "/test",
+		"status":      float64(http.StatusOK),
+		"bytes":       float64(4),
+		"remote_addr": "192.168.1.1:12345",
+	}
+	for key, want := range expected {
+		if entry[key] != want {
+			t.Errorf("expected log field %s=%v, got %v", key, want, entry[key])
+		}
+	}
+	for _, key := range []string{"request_id", "duration", "time"} {
+		if _, ok := entry[key]; !ok {
+			t.Errorf("expected log field %s to be present", key)
+		}
+	}
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	handler := testApp().routes()
+
+	t.Run("incoming ID is echoed and included in errors", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader(`{"name":"Bob"}`))
+		req.Header.Set("X-Request-ID", "client-abc-123")
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("X-Request-ID"); got != "client-abc-123" {
+			t.Errorf("expected X-Request-ID 'client-abc-123', got '%s'", got)
+		}
+
+		var resp ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.RequestID != "client-abc-123" {
+			t.Errorf("expected request_id 'client-abc-123' in error body, got '%s'", resp.RequestID)
+		}
+	})
+
+	t.Run("ID is generated when missing", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+		if got := rec.Header().Get("X-Request-ID"); len(got) != 26 {
+			t.Errorf("expected generated 26 character X-Request-ID, got '%s'", got)
+		}
+	})
+
+	t.Run("ID is logged", func(t *testing.T) {
+		var logBuffer bytes.Buffer
+		app := mustNewApp(t, config.Default(), logging.New(&logBuffer, config.LogConfig{Level: "info", Format: "json"}))
+
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.Header.Set("X-Request-ID", "trace-me")
+		app.routes().ServeHTTP(httptest.NewRecorder(), req)
+
+		if !strings.Contains(logBuffer.String(), `"request_id":"trace-me"`) {
+			t.Errorf("expected request ID in log output, got %q", logBuffer.String())
+		}
+	})
+}
+
+func TestTracePropagation(t *testing.T) {
+	var logBuffer bytes.Buffer
+	app := mustNewApp(t, config.Default(), logging.New(&logBuffer, config.LogConfig{Level: "info", Format: "json"}))
+
+	req := httptest.NewRequest(http.MethodGet, "/hello", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	app.routes().ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logBuffer.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to decode log line %q: %v", logBuffer.String(), err)
+	}
+	if entry["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected incoming trace ID in log output, got %v", entry["trace_id"])
+	}
+	if spanID, _ := entry["span_id"].(string); len(spanID) != 16 || spanID == "00f067aa0ba902b7" {
+		t.Errorf("expected a new server span ID in log output, got %v", entry["span_id"])
+	}
+}
+
+func TestLoggingMiddlewareLevels(t *testing.T) {
+	tests := []struct {
+		name          string
+		logLevel      string
+		status        int
+		expectedLevel string
+	}{
+		{name: "success logged at info", logLevel: "info", status: http.StatusOK, expectedLevel: "INFO"},
+		{name: "client error logged at warn", logLevel: "info", status: http.StatusBadRequest, expectedLevel: "WARN"},
+		{name: "server error logged at error", logLevel: "info", status: http.StatusInternalServerError, expectedLevel: "ERROR"},
+		{name: "success suppressed at warn level", logLevel: "warn", status: http.StatusOK, expectedLevel: ""},
 	}
-	if !strings.Contains(logOutput, "GET /test 200") {
-		t.Error("expected log output to contain request details")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logBuffer bytes.Buffer
+			logger := logging.New(&logBuffer, config.LogConfig{Level: tt.logLevel, Format: "json"})
+
+			handler := loggingMiddleware(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if tt.expectedLevel == "" {
+				if logBuffer.Len() != 0 {
+					t.Errorf("expected no log output, got %q", logBuffer.String())
+				}
+				return
+			}
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(logBuffer.Bytes(), &entry); err != nil {
+				t.Fatalf("failed to decode log line %q: %v", logBuffer.String(), err)
+			}
+			if entry["level"] != tt.expectedLevel {
+				t.Errorf("expected level %s, got %v", tt.expectedLevel, entry["level"])
+			}
+		})
 	}
 }
 
 func TestPanicRecovery(t *testing.T) {
 	var logBuffer bytes.Buffer
-	logger := log.New(&logBuffer, "[test] ", log.LstdFlags)
+	logger := logging.New(&logBuffer, config.LogConfig{Level: "info", Format: "text"})
 
 	handler := loggingMiddleware(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		panic("test panic")
@@ -477,19 +3188,102 @@ func TestPanicRecovery(t *testing.T) {
 
 	handler.ServeHTTP(rec, req)
 
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+
 	logOutput := logBuffer.String()
-	if !strings.Contains(logOutput, "ERROR: Panic recovered") {
-		t.Error("expected panic to be recovered and logged")
+	if !strings.Contains(logOutput, "level=ERROR") || !strings.Contains(logOutput, `msg="panic recovered"`) {
+		t.Errorf("expected panic to be recovered and logged, got %q", logOutput)
+	}
+	if !strings.Contains(logOutput, `panic="test panic"`) {
+		t.Errorf("expected panic value in log output, got %q", logOutput)
+	}
+}
+
+func TestPanicRecoveryWithCompression(t *testing.T) {
+	var logBuffer bytes.Buffer
+	logger := logging.New(&logBuffer, config.LogConfig{Level: "info", Format: "text"})
+
+	gz, err := compress.Gzip(gzip.DefaultCompression)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := loggingMiddleware(logger, compress.Middleware(compress.Options{Encoders: []compress.Encoder{gz}, MinSize: 1024},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("partial"))
+			panic("test panic")
+		})))
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	if encoding := rec.Header().Get("Content-Encoding"); encoding != "" {
+		t.Errorf("expected an uncompressed error body, got Content-Encoding '%s'", encoding)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Code != "PANIC_RECOVERY" {
+		t.Errorf("expected a PANIC_RECOVERY error body, got %+v, %v", resp, err)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	handler := testApp().routes()
+
+	for _, url := range []string{"/hello", "/hello?name=Alice", "/ping"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+	}
+	req := httptest.NewRequest(http.MethodPost, "/hello", strings.NewReader("{}"))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("FOOXXX", "/ping", nil))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	contentType := rec.Header().Get("Content-Type")
+	if !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("expected Prometheus text format, got '%s'", contentType)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`http_requests_total{route="/hello",method="GET",code="200"} 2`,
+		`http_requests_total{route="/hello",method="POST",code="415"} 1`,
+		`http_requests_total{route="/ping",method="GET",code="200"} 1`,
+		`http_requests_total{route="/ping",method="other",code="405"} 1`,
+		`http_request_duration_seconds_count{route="/hello",method="GET",code="200"} 2`,
+		`http_requests_in_flight{route="/metrics",method="GET"} 1`,
+		`http_requests_in_flight{route="/hello",method="GET"} 0`,
+		"go_goroutines ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics output to contain %q", want)
+		}
+	}
+	if strings.Contains(body, "FOOXXX") {
+		t.Error("expected unknown methods not to be used as labels")
 	}
 }
 
 func BenchmarkPingHandler(b *testing.B) {
 	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
 
+	app := testApp()
+
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		rec := httptest.NewRecorder()
-		pingHandler(rec, req)
+		app.pingHandler(rec, req)
 	}
 }
 
@@ -499,9 +3293,11 @@ func BenchmarkInfoHandler(b *testing.B) {
 	req.Host = "benchmark.test"
 	req.RemoteAddr = "127.0.0.1:8080"
 
+	app := testApp()
+
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		rec := httptest.NewRecorder()
-		infoHandler(rec, req)
+		app.infoHandler(rec, req)
 	}
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"hello-api/api"
+	"hello-api/internal/openapi"
+)
+
+// spec is the parsed form of the embedded OpenAPI document, which
+// TestOpenAPISpec keeps valid.
+var spec = openapi.MustParse(api.OpenAPI)
+
+// openAPIETag identifies the embedded document, which only changes with
+// the binary.
+var openAPIETag = fmt.Sprintf(`"%x"`, sha256.Sum256(api.OpenAPI))
+
+// openAPIHandler serves the embedded OpenAPI document. Conditional
+// requests carrying its ETag are answered with 304 Not Modified.
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", openAPIETag)
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, "openapi.json", time.Time{}, bytes.NewReader(api.OpenAPI))
+}
+
+// validationMiddleware checks requests against the operations item
+// documents before next runs. Documented query and header parameters must
+// match their schemas, request bodies must have a documented Content-Type,
+// and JSON bodies, read up to maxBodyBytes, must match the schema of their
+// media type. Violations are reported as an ErrorResponse listing every
+// offending field.
+//
+// JSON array bodies are left to next, which validates one element at a time
+// as it reads them, so a large batch is never held in memory twice.
+func validationMiddleware(item *openapi.PathItem, maxBodyBytes int64, next http.Handler) http.Handler {
+	if item == nil {
+		return next
+	}
+	ops := item.Operations()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := r.Method
+		if method == http.MethodHead {
+			method = http.MethodGet
+		}
+		op, ok := ops[method]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if errs := op.ValidateParameters(r); len(errs) > 0 {
+			respondWithErrorDetails(w, r, http.StatusBadRequest, "Invalid request", "VALIDATION_FAILED", errs)
+			return
+		}
+		if op.RequestBody == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		contentType := r.Header.Get("Content-Type")
+		mt, ok := op.RequestBody.MediaType(contentType)
+		if !ok {
+			respondWithError(w, r, http.StatusUnsupportedMediaType,
+				"Content-Type must be "+orList(op.RequestBody.MediaTypes()), "INVALID_CONTENT_TYPE")
+			return
+		}
+		if !openapi.IsJSON(contentType) || mt.Schema == nil || slices.Contains(mt.Schema.Type, "array") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !decodeRequestBody(w, r, maxBodyBytes) {
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			if !respondBodyTooLarge(w, r, err) {
+				respondWithError(w, r, http.StatusBadRequest, "Invalid JSON", "INVALID_JSON")
+			}
+			return
+		}
+
+		errs, err := mt.ValidateJSON("body", data)
+		switch {
+		case err != nil:
+			respondWithErrorDetails(w, r, http.StatusBadRequest, "Invalid JSON", "INVALID_JSON", errs)
+		case len(errs) > 0:
+			respondWithErrorDetails(w, r, http.StatusBadRequest, "Invalid request", "VALIDATION_FAILED", errs)
+		default:
+			// The handler reads the body again; it has already been decoded.
+			r.Body = io.NopCloser(bytes.NewReader(data))
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// maxBodyBytes is the request body limit of route.
+func (a *app) maxBodyBytes(route string) int64 {
+	if route == "/hello/batch" {
+		return a.cfg.Hello.Batch.MaxBodyBytes
+	}
+	return a.cfg.Hello.MaxBodyBytes
+}
+
+// orList joins items like "a, b or c".
+func orList(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " or " + items[len(items)-1]
+}
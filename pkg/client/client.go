@@ -0,0 +1,341 @@
+// Package client is a Go client for the hello-api HTTP API. It sends a
+// request ID with every call, retries transient failures with exponential
+// backoff and reports error responses as *Error values that match the
+// sentinel errors of this package:
+//
+//	c, err := client.New("http://localhost:8080", client.WithRetries(3))
+//	if err != nil {
+//		return err
+//	}
+//	resp, err := c.Hello(ctx, "Ada")
+//	if errors.Is(err, client.ErrValidation) {
+//		// The name was rejected; the *client.Error Details say why.
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"hello-api/internal/requestid"
+)
+
+// Defaults used by New.
+const (
+	DefaultTimeout    = 10 * time.Second
+	DefaultRetries    = 2
+	DefaultMinBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff = 5 * time.Second
+)
+
+// RequestIDHeader carries the request ID of every call.
+const RequestIDHeader = requestid.Header
+
+// Request is the body of POST /hello.
+type Request struct {
+	Name      string `json:"name"`
+	Lang      string `json:"lang,omitempty"`
+	Formality string `json:"formality,omitempty"`
+}
+
+// Response is a greeting.
+type Response struct {
+	Message string `json:"message"`
+	Locale  string `json:"locale,omitempty"`
+}
+
+// HealthResponse is the body of GET /health.
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// PingResponse is the body of GET /ping.
+type PingResponse struct {
+	Pong string `json:"pong"`
+}
+
+// InfoResponse describes a request as the server received it.
+type InfoResponse struct {
+	Method      string            `json:"method"`
+	URL         string            `json:"url"`
+	Host        string            `json:"host"`
+	RemoteAddr  string            `json:"remote_addr"`
+	UserAgent   string            `json:"user_agent"`
+	Headers     map[string]string `json:"headers"`
+	QueryParams map[string]string `json:"query_params"`
+}
+
+// Client calls the hello-api HTTP API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	timeout    time.Duration
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	header     http.Header
+	userAgent  string
+
+	// sleep waits between attempts; tests replace it.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client used for requests. The default is
+// http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithTimeout bounds each attempt, including reading the response. Zero
+// disables the per-attempt timeout, leaving only the context's deadline.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithRetries sets how many times a failed call is retried. Network errors,
+// 429 Too Many Requests and 502, 503 and 504 responses are retried; other
+// errors are returned immediately.
+func WithRetries(n int) Option {
+	return func(c *Client) { c.retries = n }
+}
+
+// WithBackoff sets the bounds of the exponential backoff between retries.
+// Each wait is a random duration between half and all of min doubled once
+// per attempt, capped at max. A Retry-After header takes precedence.
+func WithBackoff(min, max time.Duration) Option {
+	return func(c *Client) { c.minBackoff, c.maxBackoff = min, max }
+}
+
+// WithAPIKey authenticates every call with a static API key.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.header.Set("X-API-Key", key) }
+}
+
+// WithBearerToken authenticates every call with a bearer token.
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.header.Set("Authorization", "Bearer "+token) }
+}
+
+// WithUserAgent sets the User-Agent header of every call.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// New returns a Client for the API at baseURL, for example
+// "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("client: invalid base URL %q: must be an absolute http or https URL", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		timeout:    DefaultTimeout,
+		retries:    DefaultRetries,
+		minBackoff: DefaultMinBackoff,
+		maxBackoff: DefaultMaxBackoff,
+		header:     make(http.Header),
+		userAgent:  "hello-api-client",
+		sleep:      sleep,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retries < 0 {
+		return nil, fmt.Errorf("client: retries must not be negative, got %d", c.retries)
+	}
+	if c.minBackoff <= 0 || c.maxBackoff < c.minBackoff {
+		return nil, fmt.Errorf("client: invalid backoff bounds %s and %s", c.minBackoff, c.maxBackoff)
+	}
+	return c, nil
+}
+
+// WithRequestID returns a context whose calls send id as their request ID.
+// Without one, each call generates its own. Retries of a call reuse its ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return requestid.WithID(ctx, id)
+}
+
+// Hello greets name with GET /hello. An empty name greets the server's
+// default.
+func (c *Client) Hello(ctx context.Context, name string) (*Response, error) {
+	query := url.Values{}
+	if name != "" {
+		query.Set("name", name)
+	}
+	var resp Response
+	if err := c.do(ctx, http.MethodGet, "/hello", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// HelloPOST greets req.Name with POST /hello, in req.Lang and
+// req.Formality when set.
+func (c *Client) HelloPOST(ctx context.Context, req Request) (*Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+	var resp Response
+	if err := c.do(ctx, http.MethodPost, "/hello", nil, body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Health calls GET /health.
+func (c *Client) Health(ctx context.Context) (*HealthResponse, error) {
+	var resp HealthResponse
+	if err := c.do(ctx, http.MethodGet, "/health", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Ping calls GET /ping.
+func (c *Client) Ping(ctx context.Context) (*PingResponse, error) {
+	var resp PingResponse
+	if err := c.do(ctx, http.MethodGet, "/ping", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Info calls GET /info.
+func (c *Client) Info(ctx context.Context) (*InfoResponse, error) {
+	var resp InfoResponse
+	if err := c.do(ctx, http.MethodGet, "/info", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// do sends a request, retrying transient failures, and decodes a successful
+// response into out.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body []byte, out interface{}) error {
+	id := requestid.FromContext(ctx)
+	if id == "" {
+		id = requestid.New()
+	}
+
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		retry, retryAfter, err := c.attempt(ctx, method, u.String(), id, body, out)
+		if err == nil || !retry || attempt == c.retries || ctx.Err() != nil {
+			return err
+		}
+
+		wait := c.backoff(attempt)
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		if err := c.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// attempt sends one request. When it fails, retry reports whether the
+// failure is transient: a network error, a per-attempt timeout or a 429,
+// 502, 503 or 504 response, whose Retry-After delay is returned as well.
+func (c *Client) attempt(ctx context.Context, method, url, id string, body []byte, out interface{}) (retry bool, retryAfter time.Duration, err error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return false, 0, fmt.Errorf("client: %w", err)
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set(RequestIDHeader, id)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return true, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := decodeError(resp)
+		if apiErr.RequestID == "" {
+			apiErr.RequestID = id
+		}
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true, apiErr.RetryAfter, apiErr
+		}
+		return false, 0, apiErr
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, 0, fmt.Errorf("client: decoding %s %s response: %w", method, req.URL.Path, err)
+	}
+	return false, 0, nil
+}
+
+// backoff returns the wait before retry attempt+1: between half and all of
+// minBackoff doubled attempt times, capped at maxBackoff.
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.maxBackoff
+	if attempt < 30 {
+		if d := c.minBackoff << attempt; d < ceiling {
+			ceiling = d
+		}
+	}
+	return ceiling/2 + time.Duration(rand.Int63n(int64(ceiling/2)+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds.
+func parseRetryAfter(v string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,417 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a test server that answers each request with the next of its
+// responses, repeating the last, and remembers the requests it received.
+type recorder struct {
+	mu        sync.Mutex
+	requests  []*http.Request
+	bodies    []string
+	responses []func(w http.ResponseWriter)
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	var body json.RawMessage
+	json.NewDecoder(r.Body).Decode(&body)
+	rec.requests = append(rec.requests, r)
+	rec.bodies = append(rec.bodies, string(body))
+
+	i := len(rec.requests) - 1
+	if i >= len(rec.responses) {
+		i = len(rec.responses) - 1
+	}
+	rec.responses[i](w)
+}
+
+func reply(status int, header http.Header, body string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for key, values := range header {
+			w.Header()[key] = values
+		}
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+// newTestClient starts a server answering with responses and returns a
+// client for it whose sleeps are recorded instead of waited.
+func newTestClient(t *testing.T, responses []func(w http.ResponseWriter), opts ...Option) (*Client, *recorder, *[]time.Duration) {
+	t.Helper()
+	rec := &recorder{responses: responses}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	c, err := New(srv.URL, opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var sleeps []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return ctx.Err()
+	}
+	return c, rec, &sleeps
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		baseURL string
+		opts    []Option
+		wantErr bool
+	}{
+		{name: "http", baseURL: "http://localhost:8080"},
+		{name: "https with path", baseURL: "https://api.example.com/v1/"},
+		{name: "relative", baseURL: "/hello", wantErr: true},
+		{name: "unsupported scheme", baseURL: "ftp://example.com", wantErr: true},
+		{name: "unparsable", baseURL: "http://[::1", wantErr: true},
+		{name: "negative retries", baseURL: "http://localhost", opts: []Option{WithRetries(-1)}, wantErr: true},
+		{name: "zero backoff", baseURL: "http://localhost", opts: []Option{WithBackoff(0, time.Second)}, wantErr: true},
+		{name: "inverted backoff", baseURL: "http://localhost", opts: []Option{WithBackoff(time.Second, time.Millisecond)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.baseURL, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		name         string
+		call         func(c *Client) (interface{}, error)
+		body         string
+		expected     interface{}
+		expectedPath string
+		expectedBody string
+	}{
+		{
+			name:         "hello",
+			call:         func(c *Client) (interface{}, error) { return c.Hello(context.Background(), "Ada Lovelace") },
+			body:         `{"message":"Hello, Ada Lovelace!","locale":"en"}`,
+			expected:     &Response{Message: "Hello, Ada Lovelace!", Locale: "en"},
+			expectedPath: "GET /api/hello?name=Ada+Lovelace",
+		},
+		{
+			name:         "hello without name",
+			call:         func(c *Client) (interface{}, error) { return c.Hello(context.Background(), "") },
+			body:         `{"message":"Hello, World!"}`,
+			expected:     &Response{Message: "Hello, World!"},
+			expectedPath: "GET /api/hello?",
+		},
+		{
+			name: "hello POST",
+			call: func(c *Client) (interface{}, error) {
+				return c.HelloPOST(context.Background(), Request{Name: "Ada", Lang: "fr"})
+			},
+			body:         `{"message":"Bonjour, Ada !","locale":"fr"}`,
+			expected:     &Response{Message: "Bonjour, Ada !", Locale: "fr"},
+			expectedPath: "POST /api/hello?",
+			expectedBody: `{"name":"Ada","lang":"fr"}`,
+		},
+		{
+			name:         "health",
+			call:         func(c *Client) (interface{}, error) { return c.Health(context.Background()) },
+			body:         `{"status":"ok"}`,
+			expected:     &HealthResponse{Status: "ok"},
+			expectedPath: "GET /api/health?",
+		},
+		{
+			name:         "ping",
+			call:         func(c *Client) (interface{}, error) { return c.Ping(context.Background()) },
+			body:         `{"pong":"pong"}`,
+			expected:     &PingResponse{Pong: "pong"},
+			expectedPath: "GET /api/ping?",
+		},
+		{
+			name:         "info",
+			call:         func(c *Client) (interface{}, error) { return c.Info(context.Background()) },
+			body:         `{"method":"GET","url":"/info","headers":{"Accept":"application/json"}}`,
+			expected:     &InfoResponse{Method: "GET", URL: "/info", Headers: map[string]string{"Accept": "application/json"}},
+			expectedPath: "GET /api/info?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{responses: []func(w http.ResponseWriter){reply(http.StatusOK, nil, tt.body)}}
+			srv := httptest.NewServer(rec)
+			defer srv.Close()
+
+			c, err := New(srv.URL+"/api/", WithAPIKey("secret"), WithUserAgent("test-agent"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := tt.call(c)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+
+			r := rec.requests[0]
+			if path := r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery; path != tt.expectedPath {
+				t.Errorf("expected request '%s', got '%s'", tt.expectedPath, path)
+			}
+			if rec.bodies[0] != tt.expectedBody {
+				t.Errorf("expected body '%s', got '%s'", tt.expectedBody, rec.bodies[0])
+			}
+			if key := r.Header.Get("X-API-Key"); key != "secret" {
+				t.Errorf("expected X-API-Key 'secret', got '%s'", key)
+			}
+			if ua := r.Header.Get("User-Agent"); ua != "test-agent" {
+				t.Errorf("expected User-Agent 'test-agent', got '%s'", ua)
+			}
+			if r.Header.Get(RequestIDHeader) == "" {
+				t.Error("expected a request ID")
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	unavailable := reply(http.StatusServiceUnavailable, nil, `{"error":"draining","code":"UNAVAILABLE"}`)
+	limited := reply(http.StatusTooManyRequests, http.Header{"Retry-After": {"3"}}, `{"error":"Too many requests","code":"RATE_LIMITED"}`)
+	ok := reply(http.StatusOK, nil, `{"pong":"pong"}`)
+
+	tests := []struct {
+		name             string
+		responses        []func(w http.ResponseWriter)
+		retries          int
+		expectedAttempts int
+		expectedErr      error
+		expectedSleeps   []time.Duration
+	}{
+		{
+			name:             "success after unavailable",
+			responses:        []func(w http.ResponseWriter){unavailable, unavailable, ok},
+			retries:          2,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "retry after is honored",
+			responses:        []func(w http.ResponseWriter){limited, ok},
+			retries:          2,
+			expectedAttempts: 2,
+			expectedSleeps:   []time.Duration{3 * time.Second},
+		},
+		{
+			name:             "gives up after retries",
+			responses:        []func(w http.ResponseWriter){limited},
+			retries:          1,
+			expectedAttempts: 2,
+			expectedErr:      ErrRateLimited,
+			expectedSleeps:   []time.Duration{3 * time.Second},
+		},
+		{
+			name:             "no retries",
+			responses:        []func(w http.ResponseWriter){unavailable},
+			retries:          0,
+			expectedAttempts: 1,
+			expectedErr:      ErrServer,
+		},
+		{
+			name:             "client errors are not retried",
+			responses:        []func(w http.ResponseWriter){reply(http.StatusBadRequest, nil, `{"error":"bad","code":"VALIDATION_FAILED"}`), ok},
+			retries:          2,
+			expectedAttempts: 1,
+			expectedErr:      ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec, sleeps := newTestClient(t, tt.responses, WithRetries(tt.retries))
+
+			_, err := c.Ping(context.Background())
+			if tt.expectedErr == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if len(rec.requests) != tt.expectedAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.expectedAttempts, len(rec.requests))
+			}
+			if tt.expectedSleeps != nil && !reflect.DeepEqual(*sleeps, tt.expectedSleeps) {
+				t.Errorf("expected sleeps %v, got %v", tt.expectedSleeps, *sleeps)
+			}
+
+			id := rec.requests[0].Header.Get(RequestIDHeader)
+			for i, r := range rec.requests {
+				if got := r.Header.Get(RequestIDHeader); got != id {
+					t.Errorf("attempt %d: expected request ID '%s', got '%s'", i, id, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	c, err := New("http://localhost", WithBackoff(100*time.Millisecond, time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{4, time.Second},
+		{60, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := c.backoff(tt.attempt); d < tt.ceiling/2 || d > tt.ceiling {
+				t.Fatalf("attempt %d: expected backoff between %s and %s, got %s", tt.attempt, tt.ceiling/2, tt.ceiling, d)
+			}
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	c, rec, _ := newTestClient(t, []func(w http.ResponseWriter){reply(http.StatusOK, nil, `{"status":"ok"}`)})
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	if _, err := c.Health(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Health(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if id := rec.requests[0].Header.Get(RequestIDHeader); id != "req-123" {
+		t.Errorf("expected request ID 'req-123', got '%s'", id)
+	}
+	if id := rec.requests[1].Header.Get(RequestIDHeader); id == "" || id == "req-123" {
+		t.Errorf("expected a generated request ID, got '%s'", id)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    func(w http.ResponseWriter)
+		expected    *Error
+		expectedErr error
+	}{
+		{
+			name: "error response",
+			response: reply(http.StatusBadRequest, nil,
+				`{"error":"Validation failed","code":"VALIDATION_FAILED","request_id":"abc","details":[{"field":"name","code":"too_long","message":"is too long"}]}`),
+			expected: &Error{
+				StatusCode: http.StatusBadRequest,
+				Code:       "VALIDATION_FAILED",
+				Message:    "Validation failed",
+				RequestID:  "abc",
+				Details:    []FieldError{{Field: "name", Code: "too_long", Message: "is too long"}},
+			},
+			expectedErr: ErrValidation,
+		},
+		{
+			name: "problem",
+			response: reply(http.StatusUnprocessableEntity, http.Header{"Content-Type": {"application/problem+json"}},
+				`{"type":"urn:hello-api:problem:validation-failed","title":"Unprocessable Entity","status":422,"detail":"Validation failed","code":"VALIDATION_FAILED","request_id":"abc","errors":[{"field":"name","code":"required","message":"is required"}]}`),
+			expected: &Error{
+				StatusCode: http.StatusUnprocessableEntity,
+				Code:       "VALIDATION_FAILED",
+				Message:    "Validation failed",
+				RequestID:  "abc",
+				Details:    []FieldError{{Field: "name", Code: "required", Message: "is required"}},
+			},
+			expectedErr: ErrValidation,
+		},
+		{
+			name:     "invalid API key",
+			response: reply(http.StatusUnauthorized, nil, `{"error":"Invalid API key","code":"INVALID_API_KEY","request_id":"abc"}`),
+			expected: &Error{
+				StatusCode: http.StatusUnauthorized,
+				Code:       "INVALID_API_KEY",
+				Message:    "Invalid API key",
+				RequestID:  "abc",
+			},
+			expectedErr: ErrUnauthorized,
+		},
+		{
+			name:     "not JSON",
+			response: reply(http.StatusInternalServerError, http.Header{"Content-Type": {"text/plain"}, RequestIDHeader: {"abc"}}, "oops"),
+			expected: &Error{
+				StatusCode: http.StatusInternalServerError,
+				RequestID:  "abc",
+			},
+			expectedErr: ErrServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _, _ := newTestClient(t, []func(w http.ResponseWriter){tt.response}, WithRetries(0))
+
+			_, err := c.Hello(context.Background(), "Ada")
+			var apiErr *Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *Error, got %v", err)
+			}
+			if !reflect.DeepEqual(apiErr, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, apiErr)
+			}
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected error to match %v", tt.expectedErr)
+			}
+			if errors.Is(err, ErrRateLimited) {
+				t.Error("expected error not to match ErrRateLimited")
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c, err := New(srv.URL, WithTimeout(20*time.Millisecond), WithRetries(0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := c.Ping(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Ping(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
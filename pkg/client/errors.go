@@ -0,0 +1,136 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors matched by *Error values through errors.Is.
+var (
+	// ErrValidation matches VALIDATION_FAILED: the request was well formed
+	// but a field was rejected. Error.Details lists the fields.
+	ErrValidation = errors.New("client: validation failed")
+	// ErrInvalidRequest matches INVALID_JSON, INVALID_FORM,
+	// INVALID_CONTENT_TYPE, INVALID_ENCODING and UNSUPPORTED_ENCODING.
+	ErrInvalidRequest = errors.New("client: invalid request")
+	// ErrUnauthorized matches UNAUTHORIZED, INVALID_API_KEY and INVALID_TOKEN.
+	ErrUnauthorized = errors.New("client: unauthorized")
+	// ErrForbidden matches INSUFFICIENT_SCOPE.
+	ErrForbidden = errors.New("client: forbidden")
+	// ErrRateLimited matches RATE_LIMITED. Error.RetryAfter says when to
+	// try again.
+	ErrRateLimited = errors.New("client: rate limited")
+	// ErrBodyTooLarge matches BODY_TOO_LARGE and BATCH_TOO_LARGE.
+	ErrBodyTooLarge = errors.New("client: body too large")
+	// ErrNotAcceptable matches NOT_ACCEPTABLE.
+	ErrNotAcceptable = errors.New("client: not acceptable")
+	// ErrMethodNotAllowed matches METHOD_NOT_ALLOWED.
+	ErrMethodNotAllowed = errors.New("client: method not allowed")
+	// ErrServer matches any 5xx response.
+	ErrServer = errors.New("client: server error")
+)
+
+var codeErrors = map[string]error{
+	"VALIDATION_FAILED":    ErrValidation,
+	"INVALID_JSON":         ErrInvalidRequest,
+	"INVALID_FORM":         ErrInvalidRequest,
+	"INVALID_CONTENT_TYPE": ErrInvalidRequest,
+	"INVALID_ENCODING":     ErrInvalidRequest,
+	"UNSUPPORTED_ENCODING": ErrInvalidRequest,
+	"UNAUTHORIZED":         ErrUnauthorized,
+	"INVALID_API_KEY":      ErrUnauthorized,
+	"INVALID_TOKEN":        ErrUnauthorized,
+	"INSUFFICIENT_SCOPE":   ErrForbidden,
+	"RATE_LIMITED":         ErrRateLimited,
+	"BODY_TOO_LARGE":       ErrBodyTooLarge,
+	"BATCH_TOO_LARGE":      ErrBodyTooLarge,
+	"NOT_ACCEPTABLE":       ErrNotAcceptable,
+	"METHOD_NOT_ALLOWED":   ErrMethodNotAllowed,
+}
+
+// FieldError describes a rejected field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is an error response from the API, decoded from either the default
+// ErrorResponse body or an RFC 9457 problem.
+type Error struct {
+	StatusCode int
+	// Code is the API's error code, such as VALIDATION_FAILED. It is empty
+	// when the body was not an API error, as from a proxy.
+	Code       string
+	Message    string
+	RequestID  string
+	Details    []FieldError
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("client: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Is reports whether target is the sentinel error for e's code, or ErrServer
+// for a 5xx status.
+func (e *Error) Is(target error) bool {
+	if target == ErrServer {
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	sentinel, ok := codeErrors[e.Code]
+	return ok && target == sentinel
+}
+
+// errorBody holds the fields of both error formats; the message is in
+// "error" or "detail" and the field errors in "details" or "errors".
+type errorBody struct {
+	Error     string       `json:"error"`
+	Detail    string       `json:"detail"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id"`
+	Details   []FieldError `json:"details"`
+	Errors    []FieldError `json:"errors"`
+}
+
+// maxErrorBody bounds how much of an error response is read.
+const maxErrorBody = 64 << 10
+
+// decodeError builds an Error from an error response. Bodies that are not
+// JSON leave Code and Message empty.
+func decodeError(resp *http.Response) *Error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get(RequestIDHeader),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	var body errorBody
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxErrorBody)).Decode(&body); err != nil {
+		return apiErr
+	}
+	apiErr.Code = body.Code
+	apiErr.Message = body.Error
+	if apiErr.Message == "" {
+		apiErr.Message = body.Detail
+	}
+	apiErr.Details = body.Details
+	if apiErr.Details == nil {
+		apiErr.Details = body.Errors
+	}
+	if body.RequestID != "" {
+		apiErr.RequestID = body.RequestID
+	}
+	return apiErr
+}
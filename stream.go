@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"hello-api/internal/logging"
+	"hello-api/internal/sse"
+	"hello-api/internal/validation"
+)
+
+// helloStreamHandler sends the greeting for the request's name, lang and
+// formality query parameters as a "greeting" event every
+// hello.stream.interval until the client disconnects or the server shuts
+// down. Event IDs count up from one, and a reconnecting client's
+// Last-Event-ID resumes the count where it left off.
+func (a *app) helloStreamHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	resp, err := a.greet(r.Context(), r.Header.Get("Accept-Language"), query["name"], query.Get("lang"), query.Get("formality"))
+	if err != nil {
+		var fieldErrs validation.Errors
+		errors.As(err, &fieldErrs)
+		respondWithErrorDetails(w, r, http.StatusBadRequest, "Invalid name", "VALIDATION_FAILED", fieldErrs)
+		return
+	}
+	// Response holds only strings, which always marshal.
+	data, _ := json.Marshal(resp)
+
+	// An unparsable Last-Event-ID is not ours; start a fresh count.
+	id, _ := strconv.ParseUint(sse.LastEventID(r), 10, 64)
+
+	w.Header().Set("Content-Language", resp.Locale)
+	w.Header().Add("Vary", "Accept-Language")
+	stream := sse.NewWriter(w, a.cfg.Server.WriteTimeout)
+	if err := stream.Start(); err != nil || r.Method == http.MethodHead {
+		return
+	}
+
+	cfg := a.cfg.Hello.Stream
+	interval := time.NewTicker(cfg.Interval)
+	defer interval.Stop()
+	heartbeat := time.NewTicker(cfg.Heartbeat)
+	defer heartbeat.Stop()
+
+	logger := logging.FromContext(r.Context())
+	event := sse.Event{Event: "greeting", Data: string(data), Retry: cfg.Retry}
+	send := func() error {
+		id++
+		event.ID = strconv.FormatUint(id, 10)
+		err := stream.Send(event)
+		// The retry hint only needs to reach the client once.
+		event.Retry = 0
+		return err
+	}
+
+	err = send()
+	for err == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.done:
+			return
+		case <-heartbeat.C:
+			err = stream.Comment("heartbeat")
+		case <-interval.C:
+			err = send()
+		}
+	}
+	logger.Debug("stream closed", slog.Any("error", err))
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"hello-api/internal/logging"
+	"hello-api/internal/requestid"
+	"hello-api/internal/validation"
+	"hello-api/internal/websocket"
+)
+
+// wsHandler upgrades /ws to a WebSocket connection. Each text message is a
+// Request answered with a Response, or with an ErrorResponse when it is not
+// a valid request. Binary messages are echoed unchanged.
+func (a *app) wsHandler(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsUpgrade(r) {
+		w.Header().Set("Upgrade", "websocket")
+		respondWithError(w, r, http.StatusUpgradeRequired, "Expected a WebSocket handshake", "UPGRADE_REQUIRED")
+		return
+	}
+	if !a.allowsWebSocketOrigin(r) {
+		respondWithError(w, r, http.StatusForbidden, "Origin not allowed", "ORIGIN_NOT_ALLOWED")
+		return
+	}
+
+	conn, err := websocket.Upgrade(w, r, websocket.Options{
+		MaxMessageSize: a.cfg.WebSocket.MaxMessageBytes,
+		IdleTimeout:    2 * a.cfg.WebSocket.PingInterval,
+		WriteTimeout:   a.cfg.Server.WriteTimeout,
+	})
+	if err != nil {
+		var handshakeErr *websocket.HandshakeError
+		if errors.As(err, &handshakeErr) {
+			respondWithError(w, r, handshakeErr.Status, "Invalid WebSocket handshake: "+handshakeErr.Message, "INVALID_HANDSHAKE")
+		} else {
+			respondWithError(w, r, http.StatusInternalServerError, "WebSocket upgrade failed", "UPGRADE_FAILED")
+		}
+		return
+	}
+	defer conn.Close()
+
+	if !a.websockets.Add(conn) {
+		conn.WriteClose(websocket.CloseGoingAway, "server shutting down")
+		return
+	}
+	defer a.websockets.Remove(conn)
+
+	stopPings := make(chan struct{})
+	defer close(stopPings)
+	go keepAlive(conn, a.cfg.WebSocket.PingInterval, stopPings)
+
+	logger := logging.FromContext(r.Context())
+	for {
+		op, msg, err := conn.ReadMessage()
+		if err != nil {
+			logger.Debug("websocket closed", slog.Any("error", err))
+			return
+		}
+
+		reply := msg
+		if op == websocket.OpText {
+			reply = a.wsGreet(r, msg)
+		}
+		if err := conn.WriteMessage(op, reply); err != nil {
+			logger.Debug("websocket closed", slog.Any("error", err))
+			return
+		}
+	}
+}
+
+// wsGreet answers a Request message with an encoded Response or
+// ErrorResponse. Names are greeted in the locale negotiated from the
+// message's lang field and the handshake's Accept-Language header.
+func (a *app) wsGreet(r *http.Request, msg []byte) []byte {
+	var req Request
+	decoder := json.NewDecoder(bytes.NewReader(msg))
+	decoder.DisallowUnknownFields()
+
+	var v interface{}
+	if err := decoder.Decode(&req); err != nil || decoder.More() {
+		v = ErrorResponse{Error: "Invalid JSON", Code: "INVALID_JSON", RequestID: requestid.FromContext(r.Context())}
+	} else if resp, err := a.greet(r.Context(), r.Header.Get("Accept-Language"), []string{req.Name}, req.Lang, req.Formality); err != nil {
+		var fieldErrs validation.Errors
+		errors.As(err, &fieldErrs)
+		v = ErrorResponse{Error: "Invalid name", Code: "VALIDATION_FAILED", RequestID: requestid.FromContext(r.Context()), Details: fieldErrs}
+	} else {
+		v = resp
+	}
+
+	// Response and ErrorResponse hold only strings, which always marshal.
+	b, _ := json.Marshal(v)
+	return b
+}
+
+// keepAlive pings conn every interval until stop is closed. Each pong resets
+// the connection's idle timeout.
+func keepAlive(conn *websocket.Conn, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := conn.Ping(nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// allowsWebSocketOrigin guards against cross-site WebSocket hijacking:
+// browsers send Origin with every handshake, but CORS does not apply to
+// WebSockets. Same-origin pages and origins allowed by the CORS policy are
+// accepted, as are non-browser clients that send no Origin.
+func (a *app) allowsWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if a.cors != nil && a.cors.AllowsOrigin(origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}